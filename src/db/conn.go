@@ -1,8 +1,10 @@
 package db
 
 import (
+	"encoding/json"
 	"fmt"
 	"graph"
+	"reflect"
 	"sync"
 	"time"
 
@@ -17,17 +19,44 @@ func resultErr(result *graphql.Result) error {
 	return nil
 }
 
+// Conflict reports pending work that was affected by another commit.
+// Either Mutation could no longer be applied and was dropped or Merge
+// describes an edit that was also made by someone else and overwrote it.
 type Conflict struct {
 	Mutation *M
 	Err      error
+	Merge    *graph.MergeConflict
 }
 
+func (c *Conflict) Error() string {
+	if c.Merge != nil {
+		return c.Merge.Error()
+	}
+	return c.Err.Error()
+}
+
+// restore mutations are logged during rebase to put back attrs and
+// edges that were lost while replaying pending mutations
+const (
+	restoreMutation           = `mutation($id:String!,$attrs:[AttrArg],$removeAttrs:[String]){setNode(id:$id,merge:true,attrs:$attrs,removeAttrs:$removeAttrs){id}}`
+	restoreEdgeMutation       = `mutation($from:String!,$to:String!,$name:String!,$attrs:[AttrArg]){setEdge(from:$from,to:$to,name:$name,attrs:$attrs){name}}`
+	restoreDisconnectMutation = `mutation($from:String,$to:String,$name:String){removeEdges(from:$from,to:$to,name:$name){name}}`
+)
+
 type Conn struct {
 	g      *graph.Graph
+	base   *graph.Graph // db graph that the pending log applies to
 	db     *DB
 	claims Claims
 	tokens []*Token
 	log    []*M
+	// resolved holds the values chosen while running the current
+	// mutation and replaying those still to be used when applying one
+	resolved  []json.RawMessage
+	replaying []json.RawMessage
+	// trusted connections skip validation, they are used to replay
+	// the log and to apply mutations that were validated on Commit
+	trusted bool
 	sync.RWMutex
 	OnChange   func()
 	OnConflict func(*Conflict)
@@ -55,6 +84,7 @@ func (c *Conn) Exec(query string) *graphql.Result {
 
 func (c *Conn) ExecWithParams(query string, params map[string]interface{}) *graphql.Result {
 	oldGraph := c.g
+	c.resolved = nil
 	result := c.query(query, params)
 	err := resultErr(result)
 	if err != nil { // if error return graph to last state
@@ -65,6 +95,7 @@ func (c *Conn) ExecWithParams(query string, params map[string]interface{}) *grap
 			Claims:    c.claims,
 			Query:     query,
 			Params:    params,
+			Resolved:  c.resolved,
 		}) // tell connection to update subscriptions
 		if c.OnChange != nil {
 			c.OnChange()
@@ -89,12 +120,27 @@ func (c *Conn) query(query string, params map[string]interface{}) *graphql.Resul
 	})
 }
 
+// PendingChanges returns the difference between the connection's graph
+// and the currently committed graph
+func (c *Conn) PendingChanges() *graph.Changeset {
+	c.db.RLock()
+	base := c.db.g
+	c.db.RUnlock()
+	return graph.Diff(base, c.g)
+}
+
 func (c *Conn) Commit() error {
 	fmt.Println("COMMITTTING")
 	if len(c.log) == 0 {
 		fmt.Println("NOTHING TO COMMIT")
 		return nil
 	}
+	c.db.RLock()
+	base := c.db.g
+	c.db.RUnlock()
+	if err := c.g.ValidateChanges(base); err != nil {
+		return err
+	}
 	log := c.log
 	c.log = nil
 	if err := c.db.commit(log); err != nil {
@@ -107,7 +153,17 @@ func (c *Conn) Commit() error {
 // it resets the base graph and reapplies any pending mutations
 // conflicting mutations are dropped from the connection's pending log
 //
+// replaying a mutation can overwrite attrs that were changed by the
+// other commit even when this connection never touched them, so the
+// result is compared with a three way merge and any attrs the merge
+// kept are restored with an extra mutation
 func (c *Conn) rebase(g *graph.Graph) error {
+	base := c.base
+	c.base = g
+	if len(c.log) == 0 {
+		return c.update(g)
+	}
+	merged, conflicts := graph.Merge(base, c.g, g)
 	if err := c.update(g); err != nil {
 		return err
 	}
@@ -128,9 +184,128 @@ func (c *Conn) rebase(g *graph.Graph) error {
 	if len(log) != len(c.log) {
 		c.log = log
 	}
+	c.restore(merged)
+	for _, conflict := range conflicts {
+		if c.OnConflict != nil {
+			c.OnConflict(&Conflict{
+				Merge: conflict,
+			})
+		}
+	}
 	return nil
 }
 
+// restore logs mutations that make the attrs and edges of the
+// connection's graph match merged. Any that cannot be applied are
+// reported as conflicts. The changes being restored were made by
+// another commit so each mutation carries the claims of the last
+// committed mutation that changed the node rather than this
+// connection's claims.
+func (c *Conn) restore(merged *graph.Graph) {
+	restoring := func(id string, query string, params map[string]interface{}) {
+		m := &M{
+			Timestamp: time.Now(),
+			Claims:    c.changedBy(id),
+			Query:     query,
+			Params:    params,
+		}
+		if err := c.apply(m); err != nil {
+			if c.OnConflict != nil {
+				c.OnConflict(&Conflict{
+					Mutation: m,
+					Err:      err,
+				})
+			}
+			return
+		}
+		c.log = append(c.log, m)
+	}
+	cs := graph.Diff(c.g, merged)
+	for _, change := range cs.Nodes {
+		if change.Kind != graph.Modified || len(change.Attrs) == 0 {
+			continue
+		}
+		attrs := []interface{}{}
+		removed := []interface{}{}
+		for _, ac := range change.Attrs {
+			if ac.New == nil {
+				removed = append(removed, ac.Name)
+				continue
+			}
+			attrs = append(attrs, attrParam(ac.New))
+		}
+		restoring(change.ID, restoreMutation, map[string]interface{}{
+			"id":          change.ID,
+			"attrs":       attrs,
+			"removeAttrs": removed,
+		})
+	}
+	for _, change := range cs.Edges {
+		if change.New == nil {
+			e := change.Old
+			restoring(e.From().ID(), restoreDisconnectMutation, map[string]interface{}{
+				"from": e.From().ID(),
+				"to":   e.To().ID(),
+				"name": e.Name(),
+			})
+			continue
+		}
+		e := change.New
+		if change.Kind == graph.Modified && reflect.DeepEqual(change.Old.Attrs(), e.Attrs()) {
+			// only the rank differs, replaying decides the order
+			continue
+		}
+		attrs := []interface{}{}
+		for _, attr := range e.Attrs() {
+			attrs = append(attrs, attrParam(attr))
+		}
+		restoring(e.From().ID(), restoreEdgeMutation, map[string]interface{}{
+			"from":  e.From().ID(),
+			"to":    e.To().ID(),
+			"name":  e.Name(),
+			"attrs": attrs,
+		})
+	}
+}
+
+// changedBy returns the claims of the last committed mutation that
+// changed the node with the given id. Nodes without history are marked
+// as changed by a rebase.
+func (c *Conn) changedBy(id string) Claims {
+	history := c.db.History(id)
+	if len(history) == 0 {
+		return Claims{"rebase": true}
+	}
+	return history[len(history)-1].Claims
+}
+
+// attrParam returns attr as an AttrArg mutation param
+func attrParam(attr *graph.Attr) map[string]interface{} {
+	enc := attr.Enc
+	if enc == "" {
+		enc = "UTF8"
+	}
+	return map[string]interface{}{
+		"name":  attr.Name,
+		"value": attr.String(),
+		"enc":   enc,
+	}
+}
+
+// validate checks the changes that g would make to the connection's
+// graph. Required fields are not enforced until Commit so that nodes
+// can be built up over several mutations.
+func (c *Conn) validate(g *graph.Graph) error {
+	if c.trusted {
+		return nil
+	}
+	err := g.ValidateChanges(c.g)
+	if verr, ok := err.(*graph.ValidationError); ok {
+		return verr.Except(graph.RuleRequired)
+	}
+	return err
+}
+
 func (c *Conn) update(g *graph.Graph) error {
 	c.Lock()
 	c.g = g
@@ -139,6 +314,8 @@ func (c *Conn) update(g *graph.Graph) error {
 }
 
 func (c *Conn) apply(m *M) error {
+	c.replaying = m.Resolved
+	defer func() { c.replaying = nil }()
 	result := c.query(m.Query, m.Params)
 	if len(result.Errors) > 0 {
 		for _, err := range result.Errors {
@@ -148,6 +325,28 @@ func (c *Conn) apply(m *M) error {
 	return nil
 }
 
+// resolve sets dst to a value that must be the same every time the
+// current mutation is run. When applying a logged mutation the next
+// value recorded by its first run is used, otherwise choose is called
+// and its result recorded in the logged mutation.
+func (c *Conn) resolve(dst interface{}, choose func() (interface{}, error)) error {
+	if len(c.replaying) > 0 {
+		b := c.replaying[0]
+		c.replaying = c.replaying[1:]
+		return json.Unmarshal(b, dst)
+	}
+	v, err := choose()
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.resolved = append(c.resolved, b)
+	return json.Unmarshal(b, dst)
+}
+
 func (c *Conn) Close() error {
 	return c.db.closeConnection(c)
 }
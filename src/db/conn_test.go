@@ -0,0 +1,152 @@
+package db
+
+import (
+	"encoding/json"
+	"testing"
+
+	"testutil"
+)
+
+func TestRebaseRestoresMerge(t *testing.T) {
+	expect := testutil.Expect(t)
+	db, done := openTestDB(t)
+	defer done()
+	c := connect(t, db)
+	exec(t, c, `mutation{setType(id:"doc",name:"Doc",fields:[
+		{name:"title",type:"Text"},{name:"note",type:"Text"},
+		{name:"links",type:"Edge",edgeName:"link",edgeDirection:"Out"}
+	]){id}}`)
+	exec(t, c, `mutation{setNode(id:"x",type:"Doc",attrs:[{name:"title",value:"x",enc:"UTF8"},{name:"note",value:"n",enc:"UTF8"}]){id}}`)
+	exec(t, c, `mutation{setNode(id:"a",type:"Doc"){id}}`)
+	exec(t, c, `mutation{setNode(id:"b",type:"Doc"){id}}`)
+	commit(t, c)
+
+	ours, err := db.NewConnection(Claims{"role": "admin", "uid": "ours"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	theirs, err := db.NewConnection(Claims{"role": "admin", "uid": "theirs"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conflicts := []*Conflict{}
+	ours.OnConflict = func(c *Conflict) {
+		conflicts = append(conflicts, c)
+	}
+	// replaying these puts back the note and drops the link made by theirs
+	exec(t, ours, `mutation{setNode(id:"x",type:"Doc",attrs:[{name:"title",value:"ours",enc:"UTF8"},{name:"note",value:"n",enc:"UTF8"}]){id}}`)
+	exec(t, ours, `mutation{removeNodes(id:"b"){id}}`)
+	exec(t, ours, `mutation{setNode(id:"b",type:"Doc"){id}}`)
+	exec(t, theirs, `mutation{setNode(id:"x",type:"Doc",attrs:[{name:"title",value:"x",enc:"UTF8"}]){id}}`)
+	exec(t, theirs, `mutation{setEdge(from:"a",to:"b",name:"link"){name}}`)
+	commit(t, theirs)
+
+	expect(len(conflicts)).ToEqual(0)
+	expect(query(t, ours, `{node(id:"x"){attrs{name value}}}`)).ToEqual(
+		`{"node":{"attrs":[{"name":"title","value":"ours"}]}}`)
+	expect(query(t, ours, `{node(id:"a"){... on Doc{links{node{id}}}}}`)).ToEqual(
+		`{"node":{"links":[{"node":{"id":"b"}}]}}`)
+	commit(t, ours)
+	// the restored note and link are attributed to theirs
+	uids := func(id string) []string {
+		ids := []string{}
+		for _, r := range db.History(id) {
+			ids = append(ids, r.UID())
+		}
+		return ids
+	}
+	expect(uids("a")).ToEqual([]string{"", "theirs", "ours", "theirs"})
+	expect(uids("x")).ToEqual([]string{"", "theirs", "ours", "theirs"})
+	db = reopen(t, db)
+	c = connect(t, db)
+	expect(query(t, c, `{node(id:"x"){attrs{name value}}}`)).ToEqual(
+		`{"node":{"attrs":[{"name":"title","value":"ours"}]}}`)
+	expect(query(t, c, `{node(id:"a"){... on Doc{links{node{id}}}}}`)).ToEqual(
+		`{"node":{"links":[{"node":{"id":"b"}}]}}`)
+}
+
+func TestImportReplay(t *testing.T) {
+	expect := testutil.Expect(t)
+	db, done := openTestDB(t)
+	defer done()
+	c := connect(t, db)
+	exec(t, c, `mutation{setType(id:"doc",name:"Doc",fields:[
+		{name:"title",type:"Text"},
+		{name:"links",type:"Edge",edgeName:"link",edgeDirection:"Out"}
+	]){id}}`)
+	exec(t, c, `mutation{setNode(id:"a",type:"Doc",attrs:[{name:"title",value:"A",enc:"UTF8"}]){id}}`)
+	exec(t, c, `mutation{setNode(id:"b",type:"Doc",attrs:[{name:"title",value:"B",enc:"UTF8"}]){id}}`)
+	exec(t, c, `mutation{setEdge(from:"a",to:"b",name:"link"){name}}`)
+	commit(t, c)
+	var sub struct{ Subgraph string }
+	json.Unmarshal([]byte(query(t, c, `{subgraph(id:["a"])}`)), &sub)
+
+	ours, theirs := connect(t, db), connect(t, db)
+	importDoc := func() []string {
+		result := ours.ExecWithParams(`mutation($data:String!){importSubgraph(data:$data){id}}`, map[string]interface{}{
+			"data": sub.Subgraph,
+		})
+		if err := resultErr(result); err != nil {
+			t.Fatal(err)
+		}
+		ids := []string{}
+		for _, n := range result.Data.(map[string]interface{})["importSubgraph"].([]interface{}) {
+			ids = append(ids, n.(map[string]interface{})["id"].(string))
+		}
+		return ids
+	}
+	first, second := importDoc(), importDoc()
+	expect(len(first)).ToEqual(2)
+	expect(first[0] == second[0] || first[1] == second[1]).ToEqual(false)
+	exec(t, ours, `mutation{setNode(id:"`+first[0]+`",merge:true,attrs:[{name:"title",value:"copy",enc:"UTF8"}]){id}}`)
+
+	// another commit changes the node count before ours is replayed
+	exec(t, theirs, `mutation{setNode(id:"c",type:"Doc"){id}}`)
+	exec(t, theirs, `mutation{removeNodes(id:"b"){id}}`)
+	commit(t, theirs)
+	conflicts := []*Conflict{}
+	ours.OnConflict = func(c *Conflict) {
+		conflicts = append(conflicts, c)
+	}
+	commit(t, ours)
+	expect(len(conflicts)).ToEqual(0)
+
+	check := func(c *Conn) {
+		expect(query(t, c, `{node(id:"`+first[0]+`"){... on Doc{title links{node{id}}}}}`)).ToEqual(
+			`{"node":{"links":[{"node":{"id":"` + first[1] + `"}}],"title":"copy"}}`)
+		expect(query(t, c, `{node(id:"`+second[1]+`"){... on Doc{title}}}`)).ToEqual(
+			`{"node":{"title":"B"}}`)
+	}
+	check(connect(t, db))
+	db = reopen(t, db)
+	check(connect(t, db))
+}
+
+func TestImportValidatesTypes(t *testing.T) {
+	expect := testutil.Expect(t)
+	db, done := openTestDB(t)
+	defer done()
+	c := connect(t, db)
+	exec(t, c, `mutation{setType(id:"doc",name:"Doc",fields:[{name:"title",type:"Text"}]){id}}`)
+	importDoc := func(types string) error {
+		return resultErr(c.ExecWithParams(`mutation($data:String!){importSubgraph(data:$data){id}}`, map[string]interface{}{
+			"data": `{"types":` + types + `,"nodes":[],"edges":[]}`,
+		}))
+	}
+	for _, types := range []string{
+		`[{"id":"x","name":"WhereArg"}]`,
+		`[{"id":"x","name":"Thing","fields":[{"name":"size","type":"Bogus"}]}]`,
+		`[{"id":"x","name":"Thing","fields":[{"name":"type","type":"Text"}]}]`,
+		`[{"id":"x","name":"Doc"}]`,
+		`[{"id":"x","name":"Thing"},{"id":"y","name":"Thing"}]`,
+	} {
+		if err := importDoc(types); err == nil {
+			t.Errorf("expected importing %s to fail", types)
+		}
+	}
+	if err := importDoc(`[{"id":"doc","name":"Doc"},{"id":"x","name":"Thing"}]`); err != nil {
+		t.Fatal(err)
+	}
+	expect(query(t, c, `{doc:type(id:"doc"){name} thing:type(id:"x"){name}}`)).ToEqual(
+		`{"doc":{"name":"Doc"},"thing":{"name":"Thing"}}`)
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"search"
 	"sync"
 	"time"
 )
@@ -18,6 +19,10 @@ type M struct {
 	Claims    Claims                 `json:"c,omitempty"`
 	Query     string                 `json:"q,omitempty"`
 	Params    map[string]interface{} `json:"p,omitempty"`
+	// Resolved holds values chosen by resolvers when the mutation first
+	// ran, in the order they were chosen, so that replays get the same
+	// values. See Conn.resolve.
+	Resolved []json.RawMessage `json:"r,omitempty"`
 }
 
 type Config struct {
@@ -26,7 +31,24 @@ type Config struct {
 }
 
 type DB struct {
-	g *graph.Graph
+	g     *graph.Graph
+	index *search.Index // full text index of the committed graph
+	geo   *geoIndex     // spatial index of the committed graph
+	// indexLock guards the indexes separately so that searches made while
+	// notifying connections of a commit do not wait on the db lock
+	indexLock sync.RWMutex
+	// history holds the revisions of each node by id. restoreNode reads
+	// it while connections are rebased inside commit, so it cannot use
+	// the db lock.
+	history     map[string][]*Revision
+	historyLock sync.RWMutex
+	// failures holds values in the log that could not be converted to
+	// the type of their field when replayed and were left out of the
+	// graph. Committed values are coerced before they are logged so in
+	// practice it only grows while Open replays an old log, the lock
+	// keeps reads by the migrationFailures query safe regardless.
+	failures     []*graph.MigrationFailure
+	failuresLock sync.RWMutex
 	sync.RWMutex
 	conns []*Conn
 	log   io.ReadWriter
@@ -37,16 +59,20 @@ type DB struct {
 func (db *DB) commit(mutations []*M) error {
 	db.Lock()
 	defer db.Unlock()
+	before := db.g
 	enc := json.NewEncoder(db.log)
 	for _, m := range mutations {
 		fmt.Println("calling db.apply", m)
-		if err := db.apply(m); err != nil {
-			return err
+		err := db.apply(m)
+		if err == nil {
+			err = enc.Encode(m)
 		}
-		if err := enc.Encode(m); err != nil {
+		if err != nil {
+			db.updateIndex(before)
 			return err
 		}
 	}
+	db.updateIndex(before)
 	// rebase graph on all connections
 	for _, c := range db.conns {
 		err := c.rebase(db.g)
@@ -90,6 +116,7 @@ func (db *DB) newConnection(claims Claims, tokens []*Token) (*Conn, error) {
 	c := &Conn{
 		db:     db,
 		g:      db.g,
+		base:   db.g,
 		claims: claims,
 		tokens: tokens,
 	}
@@ -103,13 +130,16 @@ func (db *DB) apply(m *M) error {
 		return err
 	}
 	defer c.close()
+	c.trusted = true
 	fmt.Println("calling c.apply")
 	if err := c.apply(m); err != nil {
 		fmt.Println("done c.apply (fail)")
 		return err
 	}
 	fmt.Println("done c.apply")
+	before := db.g
 	db.g = c.g
+	db.record(before, m)
 	return nil
 }
 
@@ -132,7 +162,6 @@ func (db *DB) decode(log io.Reader, apply func(m *M) error) error {
 			return err
 		}
 	}
-	return nil
 }
 
 func (db *DB) GetMutations(before time.Time, after time.Time) ([]*M, error) {
@@ -151,6 +180,20 @@ func (db *DB) GetMutations(before time.Time, after time.Time) ([]*M, error) {
 	return muts, nil
 }
 
+// MigrationFailures returns the values that were left out of the graph
+// because they could not be converted to the type of their field
+func (db *DB) MigrationFailures() []*graph.MigrationFailure {
+	db.failuresLock.RLock()
+	defer db.failuresLock.RUnlock()
+	return append([]*graph.MigrationFailure{}, db.failures...)
+}
+
+func (db *DB) reportFailure(f *graph.MigrationFailure) {
+	db.failuresLock.Lock()
+	defer db.failuresLock.Unlock()
+	db.failures = append(db.failures, f)
+}
+
 func (db *DB) GetNode(id string) *graph.Node {
 	return db.g.Get(id)
 }
@@ -198,5 +241,6 @@ func Open(cfg Config) (*DB, error) {
 	if err := db.replay(); err != nil {
 		return nil, err
 	}
+	db.indexAll()
 	return db, nil
 }
@@ -1,290 +1,152 @@
 package db
 
 import (
-	"bytes"
 	"encoding/json"
-	"fmt"
+	"io/ioutil"
 	"os"
 	"testing"
 
-	"github.com/graphql-go/graphql"
+	"testutil"
 )
 
-func dump(r *graphql.Result) {
-	if len(r.Errors) > 0 {
-		fmt.Println("FAIL", r.Errors)
-		os.Exit(1)
-	}
-	b, _ := json.MarshalIndent(r.Data, "", "  ")
-	fmt.Println(string(b))
-}
-
-func TestConnection(t *testing.T) {
-	var buf bytes.Buffer
-	db, err := New(&buf)
+// openTestDB opens a db logging to a new temp file which is removed
+// by the returned func
+func openTestDB(t *testing.T) (*DB, func()) {
+	f, err := ioutil.TempFile("", "grapht-db-test")
 	if err != nil {
 		t.Fatal(err)
 	}
-	c := db.NewConnection("")
-	defer c.Close()
-	dump(c.Exec(`
-		mutation {
-			defineType(name: "User", fields:[
-				{name:"username",type:"Text"},
-				{name:"friends",type:"HasMany",edge:"friend"}
-			]) {
-				name
-			}
-		}
-	`))
-	dump(c.Exec(`
-		mutation {
-			alice:set(id:"alice",type:"User",attrs:[{name:"username",value:"alice1"}]) {
-				id
-				type {
-					name
-					fields {
-						name
-						type
-						edge
-					}
-				}
-				attrs {
-					name
-					value
-				}
-			}
-		}
-	`))
-	dump(c.Exec(`
-		mutation {
-			bob:set(id:"bob",type:"User",attrs:[{name:"username",value:"bob1"}]) {
-				id
-			}
-		}
-	`))
-	dump(c.Exec(`
-		mutation {
-			jeff:set(id:"jeff",type:"User",attrs:[{name:"username",value:"jeff1"}]) {
-				id
-			}
-		}
-	`))
-	dump(c.Exec(`
-		mutation {
-			connect(from:"alice",to:"bob",name:"friend"){
-				from {
-					id
-				}
-				to {
-					id
-				}
-			}
-		}
-	`))
-	dump(c.Exec(`
-		mutation {
-			connect(from:"alice",to:"jeff",name:"friend"){
-				from {
-					id
-				}
-				to {
-					id
-				}
-			}
-		}
-	`))
-	dump(c.Exec(`
-		mutation {
-			connect(from:"alice",to:"jeff",name:"like"){
-				name
-				from {
-					id
-				}
-				to {
-					id
-				}
-			}
-		}
-	`))
-	dump(c.Query(`
-		query {
-			aliceWithFriends:node(id:"alice") {
-				...on User {
-					username
-					friendsViaOut:out(name:"friend") {
-						id
-						friendsViaIn:in(name:"friend") {
-							id
-						}
-					}
-				}
-			}
-		}
-	`))
-	dump(c.Exec(`
-		mutation {
-			disconnect(from:"alice",to:"bob",name:"friend"){
-				from {
-					id
-				}
-				to {
-					id
-				}
-			}
-		}
-	`))
-	dump(c.Query(`
-		query {
-			aliceFewerFriends:node(id:"alice") {
-				id
-				edges(name:"friend",dir:"out") {
-					name
-					node {
-						id
-						edges(name:"friend",dir:"in") {
-							node {
-								id
-							}
-						}
-					}
-				}
-			}
-		}
-	`))
-	dump(c.Exec(`
-		mutation {
-			remove(id:"jeff") {
-				id
-			}
-		}
-	`))
-	dump(c.Query(`
-		query {
-			aliceNoFriends:node(id:"alice") {
-				...on User {
-					friends {
-						id
-					}
-				}
-			}
-		}
-	`))
-	dump(c.Query(`
-		query {
-			users:nodes(type:[User]) {
-				...on User {
-					username
-				}
-			}
-		}
-	`))
-	c2 := db.NewConnection("")
-	defer c2.Close()
-	dump(c2.Query(`
-		query {
-			c2NodeShouldBeNull:node(id:"alice") {
-				id
-			}
-		}
-	`))
-	if err := c.Commit(); err != nil {
+	path := f.Name()
+	f.Close()
+	db, err := Open(Config{Path: path})
+	if err != nil {
 		t.Fatal(err)
 	}
-	c2 = db.NewConnection("")
-	defer c2.Close()
-	dump(c2.Query(`
-		query {
-			c2NodeShouldNowBeOK:node(id:"alice") {
-				id
-			}
-		}
-	`))
-	db2, err := New(&buf)
+	return db, func() {
+		db.Close()
+		os.Remove(path)
+	}
+}
+
+// reopen closes db and opens a new db replaying the same log
+func reopen(t *testing.T, db *DB) *DB {
+	db.Close()
+	db2, err := Open(db.cfg)
 	if err != nil {
 		t.Fatal(err)
 	}
-	c2 = db2.NewConnection("")
-	dump(c2.Query(`
-		query {
-			replayedUser:node(id:"alice") {
-				...on User {
-					id
-					friends {
-						id
-					}
-				}
-			}
-		}
-	`))
+	return db2
 }
 
-func TestOpen(t *testing.T) {
-	fmt.Println("-----------------")
-	testFile := "test.db"
-	db, err := Open(testFile)
+func connect(t *testing.T, db *DB) *Conn {
+	c, err := db.NewConnection(Claims{"role": "admin"}, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	c := db.NewConnection("")
-	dump(c.Exec(`
-		mutation mutantA {
-			defineType(name: "User", fields:[
-				{name:"username",type:"Text"}
-			]) {
-				name
-			}
-		}
-	`))
-	dump(c.Exec(`
-		mutation mutantB {
-			alice:set(id:"alice",type:"User",attrs:[{name:"username",value:"alice1"}]) {
-				id
-			}
-		}
-	`))
-	if err := c.Commit(); err != nil {
-		t.Fatal(err)
+	return c
+}
+
+// exec runs a mutation and returns the result data as JSON, failing
+// the test on any error
+func exec(t *testing.T, c *Conn, query string) string {
+	t.Helper()
+	result := c.Exec(query)
+	if err := resultErr(result); err != nil {
+		t.Fatalf("%s\n%s", query, err)
 	}
-	db.Close()
-	db, err = Open(testFile)
-	if err != nil {
-		t.Fatal(err)
+	b, _ := json.Marshal(result.Data)
+	return string(b)
+}
+
+// execErr runs a mutation that is expected to fail and returns the error
+func execErr(t *testing.T, c *Conn, query string) error {
+	t.Helper()
+	err := resultErr(c.Exec(query))
+	if err == nil {
+		t.Fatalf("expected error from %s", query)
+	}
+	return err
+}
+
+// query runs a query and returns the result data as JSON, failing the
+// test on any error
+func query(t *testing.T, c *Conn, q string) string {
+	t.Helper()
+	result := c.Query(q)
+	if err := resultErr(result); err != nil {
+		t.Fatalf("%s\n%s", q, err)
 	}
-	c = db.NewConnection("")
-	dump(c.Query(`
-		query {
-			users:nodes(type:[User]) {
-				...on User {
-					username
-				}
-			}
-		}
-	`))
-	dump(c.Exec(`
-		mutation {
-			bob:set(id:"bob",type:"User",attrs:[{name:"username",value:"bob1"}]) {
-				id
-			}
-		}
-	`))
+	b, _ := json.Marshal(result.Data)
+	return string(b)
+}
+
+func commit(t *testing.T, c *Conn) {
+	t.Helper()
 	if err := c.Commit(); err != nil {
 		t.Fatal(err)
 	}
-	db.Close()
-	db, err = Open(testFile)
-	if err != nil {
-		t.Fatal(err)
+}
+
+const userType = `mutation {
+	setType(id:"u",name:"User",fields:[
+		{name:"username",type:"Text"},
+		{name:"friends",type:"Edge",edgeName:"friend",edgeDirection:"Out"}
+	]){id}
+}`
+
+func TestConnection(t *testing.T) {
+	expect := testutil.Expect(t)
+	db, done := openTestDB(t)
+	defer done()
+	c := connect(t, db)
+	defer c.Close()
+	exec(t, c, userType)
+	for _, id := range []string{"alice", "bob", "jeff"} {
+		exec(t, c, `mutation{setNode(id:"`+id+`",type:"User",attrs:[{name:"username",value:"`+id+`1",enc:"UTF8"}]){id}}`)
 	}
-	c = db.NewConnection("")
-	dump(c.Query(`
-		query {
-			users:nodes(type:[User]) {
-				...on User {
-					username
-				}
-			}
-		}
-	`))
-	os.Remove(testFile)
+	exec(t, c, `mutation{setEdge(from:"alice",to:"bob",name:"friend"){name}}`)
+	exec(t, c, `mutation{setEdge(from:"alice",to:"jeff",name:"friend"){name}}`)
+	exec(t, c, `mutation{setEdge(from:"alice",to:"jeff",name:"like"){name}}`)
+	expect(query(t, c, `{node(id:"alice"){... on User{username friends{node{id}}}}}`)).ToEqual(
+		`{"node":{"friends":[{"node":{"id":"bob"}},{"node":{"id":"jeff"}}],"username":"alice1"}}`)
+	exec(t, c, `mutation{removeEdges(from:"alice",to:"bob",name:"friend"){name}}`)
+	exec(t, c, `mutation{removeNodes(id:"jeff"){id}}`)
+	expect(query(t, c, `{node(id:"alice"){... on User{friends{node{id}}}}}`)).ToEqual(
+		`{"node":{"friends":[]}}`)
+
+	// other connections only see committed changes
+	c2 := connect(t, db)
+	defer c2.Close()
+	expect(query(t, c2, `{node(id:"alice"){id}}`)).ToEqual(`{"node":null}`)
+	commit(t, c)
+	c2 = connect(t, db)
+	defer c2.Close()
+	expect(query(t, c2, `{node(id:"alice"){id}}`)).ToEqual(`{"node":{"id":"alice"}}`)
+
+	// replaying the log gives the same graph
+	db = reopen(t, db)
+	c = connect(t, db)
+	defer c.Close()
+	expect(query(t, c, `{nodes(type:[User]){id ... on User{username}}}`)).ToEqual(
+		`{"nodes":[{"id":"alice","username":"alice1"},{"id":"bob","username":"bob1"}]}`)
+}
+
+func TestOpen(t *testing.T) {
+	expect := testutil.Expect(t)
+	db, done := openTestDB(t)
+	defer done()
+	c := connect(t, db)
+	exec(t, c, userType)
+	exec(t, c, `mutation{setNode(id:"alice",type:"User",attrs:[{name:"username",value:"alice1",enc:"UTF8"}]){id}}`)
+	commit(t, c)
+	db = reopen(t, db)
+	c = connect(t, db)
+	expect(query(t, c, `{nodes(type:[User]){... on User{username}}}`)).ToEqual(
+		`{"nodes":[{"username":"alice1"}]}`)
+	exec(t, c, `mutation{setNode(id:"bob",type:"User",attrs:[{name:"username",value:"bob1",enc:"UTF8"}]){id}}`)
+	commit(t, c)
+	db = reopen(t, db)
+	c = connect(t, db)
+	expect(query(t, c, `{nodes(type:[User]){... on User{username}}}`)).ToEqual(
+		`{"nodes":[{"username":"alice1"},{"username":"bob1"}]}`)
 }
@@ -0,0 +1,220 @@
+package db
+
+import (
+	"graph"
+	"math"
+	"sort"
+)
+
+// geoCellSize is the width in degrees of the cells of the spatial index
+const geoCellSize = 0.5
+
+type geoCell struct {
+	lat, lng int
+}
+
+func cellOf(p graph.GeoPoint) geoCell {
+	return geoCell{
+		lat: int(math.Floor(p.Lat / geoCellSize)),
+		lng: int(math.Floor(p.Lng / geoCellSize)),
+	}
+}
+
+// geoIndex is a grid of the nodes with a GeoPoint attr by the cells
+// their points fall in
+type geoIndex struct {
+	g     *graph.Graph // graph the index was last updated to
+	cells map[geoCell]map[string]bool
+	nodes map[string][]geoCell
+}
+
+func newGeoIndex() *geoIndex {
+	return &geoIndex{
+		cells: map[geoCell]map[string]bool{},
+		nodes: map[string][]geoCell{},
+	}
+}
+
+// geoPoints returns the points held by the GeoPoint fields of n by
+// field name
+func geoPoints(n *graph.Node) map[string]graph.GeoPoint {
+	ps := map[string]graph.GeoPoint{}
+	t := n.Type()
+	if t == nil {
+		return ps
+	}
+	for _, f := range t.AllFields() {
+		if f.Type != GeoPoint {
+			continue
+		}
+		attr := n.Attr(f.Name)
+		if attr == nil || attr.Empty() {
+			continue
+		}
+		if p, err := graph.ParseGeoPoint(attr.Value); err == nil {
+			ps[f.Name] = p
+		}
+	}
+	return ps
+}
+
+func (ix *geoIndex) put(n *graph.Node) {
+	ix.remove(n.ID())
+	for _, p := range geoPoints(n) {
+		c := cellOf(p)
+		ids, ok := ix.cells[c]
+		if !ok {
+			ids = map[string]bool{}
+			ix.cells[c] = ids
+		}
+		ids[n.ID()] = true
+		ix.nodes[n.ID()] = append(ix.nodes[n.ID()], c)
+	}
+}
+
+func (ix *geoIndex) remove(id string) {
+	for _, c := range ix.nodes[id] {
+		delete(ix.cells[c], id)
+		if len(ix.cells[c]) == 0 {
+			delete(ix.cells, c)
+		}
+	}
+	delete(ix.nodes, id)
+}
+
+// candidates returns the ids of nodes with a point in a cell that
+// overlaps b. Points still need checking against b itself.
+func (ix *geoIndex) candidates(b graph.GeoBounds) []string {
+	south, north := cellOf(graph.GeoPoint{Lat: b.South}).lat, cellOf(graph.GeoPoint{Lat: b.North}).lat
+	lngs := [][2]float64{{b.West, b.East}}
+	if b.West > b.East {
+		lngs = [][2]float64{{b.West, 180}, {-180, b.East}}
+	}
+	ncells := 0
+	for _, r := range lngs {
+		ncells += (north - south + 1) * (cellOf(graph.GeoPoint{Lng: r[1]}).lng - cellOf(graph.GeoPoint{Lng: r[0]}).lng + 1)
+	}
+	seen := map[string]bool{}
+	ids := []string{}
+	add := func(set map[string]bool) {
+		for id := range set {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	if ncells > len(ix.cells) {
+		// cheaper to look at every occupied cell
+		for c, set := range ix.cells {
+			if c.lat >= south && c.lat <= north {
+				add(set)
+			}
+		}
+		return ids
+	}
+	for _, r := range lngs {
+		west, east := cellOf(graph.GeoPoint{Lng: r[0]}).lng, cellOf(graph.GeoPoint{Lng: r[1]}).lng
+		for lat := south; lat <= north; lat++ {
+			for lng := west; lng <= east; lng++ {
+				add(ix.cells[geoCell{lat, lng}])
+			}
+		}
+	}
+	return ids
+}
+
+// GeoFilter selects nodes with a point within Radius metres of Near,
+// inside Bounds or both. Field limits the test to one GeoPoint field,
+// otherwise any GeoPoint field of the node may match.
+type GeoFilter struct {
+	Field  string
+	Near   *graph.GeoPoint
+	Radius float64
+	Bounds *graph.GeoBounds
+}
+
+// area returns the bounds that every match must fall inside
+func (gf *GeoFilter) area() graph.GeoBounds {
+	switch {
+	case gf.Near != nil:
+		return gf.Near.Around(gf.Radius)
+	case gf.Bounds != nil:
+		return *gf.Bounds
+	}
+	return graph.GeoBounds{North: 90, South: -90, West: -180, East: 180}
+}
+
+// match reports whether n passes the filter, the GeoPoint field that
+// passed and the distance from Near to its point. Where several fields
+// pass the closest is used.
+func (gf *GeoFilter) match(n *graph.Node) (string, float64, bool) {
+	field, best, found := "", math.Inf(1), false
+	for name, p := range geoPoints(n) {
+		if gf.Field != "" && name != gf.Field {
+			continue
+		}
+		if gf.Bounds != nil && !gf.Bounds.Contains(p) {
+			continue
+		}
+		d := 0.0
+		if gf.Near != nil {
+			if d = gf.Near.Distance(p); d > gf.Radius {
+				continue
+			}
+		}
+		if d < best || (d == best && name < field) {
+			field, best = name, d
+		}
+		found = true
+	}
+	return field, best, found
+}
+
+// GeoMatch is a node found by FindNear with the GeoPoint field that
+// passed the filter and its distance in metres from the Near point, the
+// distance is 0 when the filter has no Near point.
+type GeoMatch struct {
+	Node     *graph.Node
+	Field    string
+	Distance float64
+}
+
+// FindNear returns matches for the nodes of g that are of any of the
+// given types, pass every condition and pass gf. If gf has a Near point
+// they are ordered by distance from it, otherwise by id. Candidates come
+// from the spatial index when g is the graph it was last updated to, so
+// that connections without pending changes do not scan every node.
+func (db *DB) FindNear(g *graph.Graph, types []*graph.Type, cs []*graph.Condition, gf *GeoFilter) ([]*GeoMatch, error) {
+	var ns graph.Nodes
+	db.indexLock.RLock()
+	if db.geo != nil && db.geo.g == g {
+		ns = graph.Nodes{}
+		for _, id := range db.geo.candidates(gf.area()) {
+			if n := g.Get(id); n != nil {
+				ns = append(ns, n)
+			}
+		}
+	}
+	db.indexLock.RUnlock()
+	if ns == nil {
+		ns = g.Nodes()
+	}
+	sort.Sort(ns)
+	ns, err := ns.FilterType(types...).Filter(cs)
+	if err != nil {
+		return nil, err
+	}
+	found := []*GeoMatch{}
+	for _, n := range ns {
+		if field, d, ok := gf.match(n); ok {
+			found = append(found, &GeoMatch{Node: n, Field: field, Distance: d})
+		}
+	}
+	if gf.Near != nil {
+		sort.SliceStable(found, func(i, j int) bool {
+			return found[i].Distance < found[j].Distance
+		})
+	}
+	return found, nil
+}
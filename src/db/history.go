@@ -0,0 +1,88 @@
+package db
+
+import (
+	"fmt"
+	"graph"
+	"time"
+)
+
+// Revision is the state of a node after a mutation changed it. The
+// graph as it was after the mutation is kept so that the node and its
+// edges can be read back. Graphs share structure so this is cheap.
+type Revision struct {
+	Timestamp time.Time
+	Claims    Claims
+	id        string
+	g         *graph.Graph
+}
+
+// UID returns the uid claim of the user that made the mutation
+func (r *Revision) UID() string {
+	uid, _ := r.Claims["uid"].(string)
+	return uid
+}
+
+// Node returns the node as it was after the mutation or nil if the
+// mutation removed it
+func (r *Revision) Node() *graph.Node {
+	return r.g.Get(r.id)
+}
+
+// record adds a revision for every node that m changed, including the
+// nodes at either end of any edge it connected or disconnected
+func (db *DB) record(before *graph.Graph, m *M) {
+	cs := graph.Diff(before, db.g)
+	ids := []string{}
+	seen := map[string]bool{}
+	add := func(id string) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for _, c := range cs.Nodes {
+		add(c.ID)
+	}
+	for _, c := range cs.Edges {
+		for _, e := range []*graph.Edge{c.Old, c.New} {
+			if e != nil {
+				add(e.From().ID())
+				add(e.To().ID())
+			}
+		}
+	}
+	db.historyLock.Lock()
+	defer db.historyLock.Unlock()
+	if db.history == nil {
+		db.history = map[string][]*Revision{}
+	}
+	for _, id := range ids {
+		db.history[id] = append(db.history[id], &Revision{
+			Timestamp: m.Timestamp,
+			Claims:    m.Claims,
+			id:        id,
+			g:         db.g,
+		})
+	}
+}
+
+// History returns the committed revisions of a node, oldest first
+func (db *DB) History(id string) []*Revision {
+	db.historyLock.RLock()
+	defer db.historyLock.RUnlock()
+	return append([]*Revision{}, db.history[id]...)
+}
+
+// RevisionAt returns the last revision of a node made at or before t
+func (db *DB) RevisionAt(id string, t time.Time) (*Revision, error) {
+	var found *Revision
+	for _, r := range db.History(id) {
+		if !r.Timestamp.After(t) {
+			found = r
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("node '%s' has no history at %s", id, t.Format(time.RFC3339))
+	}
+	return found, nil
+}
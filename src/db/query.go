@@ -7,11 +7,13 @@ import (
 	"net/url"
 	"reflect"
 	"regexp"
-	"sort"
+	"search"
 	"strings"
 	"time"
+	"uuid"
 
 	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
 )
 
 // Field Types
@@ -25,6 +27,12 @@ const (
 	DataTable = "DataTable"
 	File      = "File"
 	Image     = "Image"
+	Computed  = "Computed"
+	Enum      = "Enum"
+	Date      = "Date"
+	DateTime  = "DateTime"
+	Time      = "Time"
+	GeoPoint  = "GeoPoint"
 )
 
 // Field EdgeDirection
@@ -34,7 +42,7 @@ const (
 )
 
 func castError(name string, src interface{}, dst string) error {
-	return fmt.Errorf("failed to cast '%s' arg '%v' to %s", name, reflect.ValueOf(src).Type().Name, dst)
+	return fmt.Errorf("failed to cast '%s' arg '%v' to %s", name, reflect.ValueOf(src).Type().Name(), dst)
 }
 func invalidArg(args map[string]interface{}, name string, reason string) error {
 	return fmt.Errorf("argument '%s' (%v) invalid: %s", name, args[name], reason)
@@ -44,8 +52,11 @@ func nilSourceError(fieldName, typeName string) error {
 }
 
 var validIdent = regexp.MustCompile(`^[_a-zA-Z][_a-zA-Z0-9]*$`)
-var validFieldType = regexp.MustCompile(`^(Text|RichText|Int|Float|Boolean|Edge|File|Image)$`)
+var validFieldType = regexp.MustCompile(`^(Text|RichText|Int|Float|Boolean|Edge|File|Image|Computed|Enum|Date|DateTime|Time|GeoPoint|DataTable)$`)
 var validEdgeDirection = regexp.MustCompile(`^(In|Out)$`)
+var validOnDelete = regexp.MustCompile(`^(Disconnect|Cascade|Restrict)$`)
+var validEdgeCardinality = regexp.MustCompile(`^(One|Many)$`)
+var validEdgeOverflow = regexp.MustCompile(`^(Reject|Replace)$`)
 var validEncType = regexp.MustCompile(`^(UTF8|DataURI|JSON)$`)
 
 var reservedWords = []string{
@@ -65,6 +76,32 @@ var reservedWords = []string{
 	"id",
 }
 
+// schemaTypeNames are the names of the GraphQL types the schema defines
+// itself, which node types and generated enums must not reuse
+var schemaTypeNames = map[string]bool{
+	"Attr": true, "AttrArg": true, "AttrChange": true, "Boolean": true,
+	"BoundsArg": true, "ChangeKindEnum": true, "Changeset": true,
+	"Connection": true, "DataColumn": true, "DataColumnArg": true,
+	"DataTable": true, "Date": true, "DateTime": true, "Edge": true,
+	"EdgeChange": true, "Field": true, "FieldArg": true, "FieldMigration": true,
+	"FieldNameEnum": true, "Float": true, "GeoPoint": true, "ID": true,
+	"Img": true, "Int": true, "MigrationFailure": true, "Mutation": true,
+	"NearArg": true, "NearResult": true, "NodeChange": true,
+	"NodeInterface": true, "Removal": true, "Revision": true,
+	"RootMutation": true, "RootQuery": true,
+	"SchemeEnum": true, "SearchResult": true, "Step": true, "String": true,
+	"Time": true, "Token": true, "Type": true, "TypeChange": true,
+	"TypeEnum": true, "ValueTypeEnum": true, "WhereArg": true,
+}
+
+// reservedEnumValues cannot be used as enum values in GraphQL
+var reservedEnumValues = map[string]bool{"true": true, "false": true, "null": true}
+
+// validEnumValue reports whether v can be used as an enum value
+func validEnumValue(v string) bool {
+	return validIdent.MatchString(v) && !reservedEnumValues[v]
+}
+
 // TODO: this is insanely lazy FIXME
 func fill(dst interface{}, src interface{}) error {
 	b, err := json.Marshal(src)
@@ -90,10 +127,11 @@ type ImageKey struct {
 
 func NewGraphqlContext(c *Conn) *GraphqlContext {
 	cxt := &GraphqlContext{
-		conn:      c,
-		types:     map[string]*graphql.Object{},
-		fields:    graphql.Fields{},
-		mutations: graphql.Fields{},
+		conn:       c,
+		types:      map[string]*graphql.Object{},
+		interfaces: map[string]*graphql.Interface{},
+		fields:     graphql.Fields{},
+		mutations:  graphql.Fields{},
 	}
 	return cxt
 }
@@ -106,6 +144,7 @@ type Connection struct {
 type GraphqlContext struct {
 	conn                  *Conn
 	types                 map[string]*graphql.Object
+	interfaces            map[string]*graphql.Interface
 	fields                graphql.Fields
 	mutations             graphql.Fields
 	fieldDefinitionObject *graphql.Object
@@ -117,7 +156,23 @@ type GraphqlContext struct {
 	tokenObject           *graphql.Object
 	mutationObject        *graphql.Object
 	connectionObject      *graphql.Object
+	stepObject            *graphql.Object
+	changesetObject       *graphql.Object
+	removalObject         *graphql.Object
+	migrationObject       *graphql.Object
+	failureObject         *graphql.Object
+	whereInputObject      *graphql.InputObject
+	nearInputObject       *graphql.InputObject
+	boundsInputObject     *graphql.InputObject
+	geoPointObject        *graphql.Object
+	dataTableObject       *graphql.Object
+	dataColumnObject      *graphql.Object
+	dataColumnInput       *graphql.InputObject
+	searchResultObject    *graphql.Object
+	nearResultObject      *graphql.Object
+	revisionObject        *graphql.Object
 	nodeInterface         *graphql.Interface
+	enums                 map[string]*graphql.Enum
 	typeEnum              *graphql.Enum
 	fieldNameEnum         *graphql.Enum
 }
@@ -144,11 +199,31 @@ func (cxt *GraphqlContext) TypeObject() *graphql.Object {
 		Description: "field descriptions for this type",
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 			if t, ok := p.Source.(*graph.Type); ok {
-				return t.Fields, nil
+				return t.AllFields(), nil
+			}
+			return []interface{}{}, nil
+		},
+	})
+	cxt.typeDefinitionObject.AddFieldConfig("extends", &graphql.Field{
+		Type:        graphql.NewList(cxt.TypeObject()),
+		Description: "parent types that this type inherits fields from",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if t, ok := p.Source.(*graph.Type); ok {
+				return cxt.conn.g.Parents(t), nil
 			}
 			return []interface{}{}, nil
 		},
 	})
+	cxt.typeDefinitionObject.AddFieldConfig("abstract", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.Boolean),
+		Description: "abstract types can only be extended and cannot hold nodes",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if t, ok := p.Source.(*graph.Type); ok {
+				return t.Abstract, nil
+			}
+			return false, nil
+		},
+	})
 	return cxt.typeDefinitionObject
 }
 
@@ -178,6 +253,27 @@ func (cxt *GraphqlContext) ValueTypeEnum() *graphql.Enum {
 			string(Image): &graphql.EnumValueConfig{
 				Description: "Image data field",
 			},
+			string(Enum): &graphql.EnumValueConfig{
+				Description: "One or more of a set of allowed values",
+			},
+			string(Date): &graphql.EnumValueConfig{
+				Description: "Calendar date",
+			},
+			string(DateTime): &graphql.EnumValueConfig{
+				Description: "Instant in time stored as RFC3339 in UTC",
+			},
+			string(Time): &graphql.EnumValueConfig{
+				Description: "Time of day",
+			},
+			string(GeoPoint): &graphql.EnumValueConfig{
+				Description: "Position on the earth as lat and lng",
+			},
+			string(DataTable): &graphql.EnumValueConfig{
+				Description: "Table of rows with typed columns",
+			},
+			string(Computed): &graphql.EnumValueConfig{
+				Description: "Value derived from an expression at query time",
+			},
 			string(File): &graphql.EnumValueConfig{
 				Description: "File attachment data field",
 			},
@@ -319,7 +415,7 @@ func (cxt *GraphqlContext) AttrObject() *graphql.Object {
 					if attr.Enc == "DataURI" {
 						return "<data>", nil
 					}
-					return attr.Value, nil
+					return attr.String(), nil
 				},
 			},
 			"enc": &graphql.Field{
@@ -363,6 +459,60 @@ func (cxt *GraphqlContext) FieldDefinitionObject() *graphql.Object {
 				Type:        graphql.NewNonNull(graphql.Boolean),
 				Description: "is field required",
 			},
+			"unique": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Boolean),
+				Description: "must the value be unique among nodes of the type",
+			},
+			"indexed": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Boolean),
+				Description: "are values indexed for where filters",
+			},
+			"enumValues": &graphql.Field{
+				Type:        graphql.NewList(graphql.String),
+				Description: "values allowed by an Enum field",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					fd, ok := p.Source.(*graph.Field)
+					if !ok {
+						return nil, nil
+					}
+					if fd.Type != Enum {
+						return nil, nil
+					}
+					return fd.EnumValues, nil
+				},
+			},
+			"enumMulti": &graphql.Field{
+				Type:        graphql.Boolean,
+				Description: "may an Enum field hold more than one value",
+			},
+			"dataColumns": &graphql.Field{
+				Type:        graphql.NewList(cxt.DataColumnObject()),
+				Description: "columns of a DataTable field",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					fd, ok := p.Source.(*graph.Field)
+					if !ok {
+						return nil, nil
+					}
+					if fd.Type != DataTable {
+						return nil, nil
+					}
+					return fd.DataColumns, nil
+				},
+			},
+			"default": &graphql.Field{
+				Type:        graphql.String,
+				Description: "value given to new nodes and read by nodes without one",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					fd, ok := p.Source.(*graph.Field)
+					if !ok {
+						return nil, nil
+					}
+					if fd.Default == "" {
+						return nil, nil
+					}
+					return fd.Default, nil
+				},
+			},
 			"unit": &graphql.Field{
 				Type:        graphql.String,
 				Description: "SI unit of field value",
@@ -442,6 +592,59 @@ func (cxt *GraphqlContext) FieldDefinitionObject() *graphql.Object {
 					return fd.EdgeDirection, nil
 				},
 			},
+			"onDelete": &graphql.Field{
+				Type:        graphql.String,
+				Description: "what happens to connected nodes when a node is removed Disconnect/Cascade/Restrict",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					fd, ok := p.Source.(*graph.Field)
+					if !ok {
+						return nil, nil
+					}
+					if fd.OnDelete == "" {
+						return graph.OnDeleteDisconnect, nil
+					}
+					return fd.OnDelete, nil
+				},
+			},
+			"edgeCardinality": &graphql.Field{
+				Type:        graphql.String,
+				Description: "how many connections the field may hold One/Many",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					fd, ok := p.Source.(*graph.Field)
+					if !ok {
+						return nil, nil
+					}
+					if fd.EdgeCardinality == "" {
+						return graph.CardinalityMany, nil
+					}
+					return fd.EdgeCardinality, nil
+				},
+			},
+			"edgeLimit": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "most connections a Many field may hold 0=nolimit",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					fd, ok := p.Source.(*graph.Field)
+					if !ok {
+						return nil, nil
+					}
+					return fd.EdgeLimit, nil
+				},
+			},
+			"edgeOverflow": &graphql.Field{
+				Type:        graphql.String,
+				Description: "what happens when a connection would exceed the limit Reject/Replace",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					fd, ok := p.Source.(*graph.Field)
+					if !ok {
+						return nil, nil
+					}
+					if fd.EdgeOverflow == "" {
+						return graph.OverflowReject, nil
+					}
+					return fd.EdgeOverflow, nil
+				},
+			},
 			"textMarkup": &graphql.Field{
 				Type:        graphql.String,
 				Description: "is this field marked up with special formating",
@@ -490,11 +693,93 @@ func (cxt *GraphqlContext) FieldDefinitionObject() *graphql.Object {
 					return fd.TextCharLimit, nil
 				},
 			},
+			"expression": &graphql.Field{
+				Type:        graphql.String,
+				Description: "expression a Computed field is derived from",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					fd, ok := p.Source.(*graph.Field)
+					if !ok {
+						return nil, nil
+					}
+					if fd.Expression == "" {
+						return nil, nil
+					}
+					return fd.Expression, nil
+				},
+			},
+			"computedType": &graphql.Field{
+				Type:        graphql.String,
+				Description: "type of value a Computed field gives Text/Int/Float/Boolean",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					fd, ok := p.Source.(*graph.Field)
+					if !ok {
+						return nil, nil
+					}
+					if fd.Type != Computed {
+						return nil, nil
+					}
+					if fd.ComputedType == "" {
+						return Text, nil
+					}
+					return fd.ComputedType, nil
+				},
+			},
 		},
 	})
 	return cxt.fieldDefinitionObject
 }
 
+func (cxt *GraphqlContext) nodeInterfaceFields() graphql.Fields {
+	return graphql.Fields{
+		"id": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.ID),
+			Description: "The id of the node",
+		},
+		"name": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Name attr if available or ID if not",
+		},
+		"type": &graphql.Field{
+			Type:        graphql.NewNonNull(cxt.TypeObject()),
+			Description: "type definition of node",
+		},
+		"attrs": &graphql.Field{
+			Type:        graphql.NewList(cxt.AttrObject()),
+			Description: "list of node attributes as key/value pairs",
+		},
+		"connections": &graphql.Field{
+			Type: graphql.NewList(cxt.ConnectionObject()),
+			Args: graphql.FieldConfigArgument{
+				"name": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+				"direction": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+			},
+			Description: "list inbound/outbound edges",
+		},
+		"history": &graphql.Field{
+			Type:        graphql.NewList(cxt.RevisionObject()),
+			Description: "committed revisions of the node, oldest first",
+		},
+	}
+}
+
+func (cxt *GraphqlContext) resolveNodeType(p graphql.ResolveTypeParams) *graphql.Object {
+	n, ok := p.Value.(*graph.Node)
+	if !ok {
+		return nil
+	}
+	if n == nil {
+		return nil
+	}
+	if n.Type() == nil {
+		return nil
+	}
+	return cxt.NodeType(n.Type())
+}
+
 func (cxt *GraphqlContext) NodeInterface() *graphql.Interface {
 	if cxt.nodeInterface != nil {
 		return cxt.nodeInterface
@@ -503,48 +788,11 @@ func (cxt *GraphqlContext) NodeInterface() *graphql.Interface {
 		Name:        "NodeInterface",
 		Description: "Generic node interface",
 		Fields:      graphql.Fields{},
-		ResolveType: func(p graphql.ResolveTypeParams) *graphql.Object {
-			n, ok := p.Value.(*graph.Node)
-			if !ok {
-				return nil
-			}
-			if n == nil {
-				return nil
-			}
-			if n.Type() == nil {
-				return nil
-			}
-			return cxt.NodeType(n.Type())
-		},
-	})
-	cxt.nodeInterface.AddFieldConfig("id", &graphql.Field{
-		Type:        graphql.NewNonNull(graphql.ID),
-		Description: "The id of the node",
-	})
-	cxt.nodeInterface.AddFieldConfig("name", &graphql.Field{
-		Type:        graphql.String,
-		Description: "Name attr if available or ID if not",
-	})
-	cxt.nodeInterface.AddFieldConfig("type", &graphql.Field{
-		Type:        graphql.NewNonNull(cxt.TypeObject()),
-		Description: "type definition of node",
-	})
-	cxt.nodeInterface.AddFieldConfig("attrs", &graphql.Field{
-		Type:        graphql.NewList(cxt.AttrObject()),
-		Description: "list of node attributes as key/value pairs",
-	})
-	cxt.nodeInterface.AddFieldConfig("connections", &graphql.Field{
-		Type: graphql.NewList(cxt.ConnectionObject()),
-		Args: graphql.FieldConfigArgument{
-			"name": &graphql.ArgumentConfig{
-				Type: graphql.String,
-			},
-			"direction": &graphql.ArgumentConfig{
-				Type: graphql.String,
-			},
-		},
-		Description: "list inbound/outbound edges",
+		ResolveType: cxt.resolveNodeType,
 	})
+	for name, f := range cxt.nodeInterfaceFields() {
+		cxt.nodeInterface.AddFieldConfig(name, f)
+	}
 	return cxt.nodeInterface
 }
 
@@ -593,6 +841,28 @@ func (cxt *GraphqlContext) ConnectionObject() *graphql.Object {
 			return c.Edge.Name(), nil
 		},
 	})
+	cxt.connectionObject.AddFieldConfig("attrs", &graphql.Field{
+		Type:        graphql.NewList(cxt.AttrObject()),
+		Description: "key/value attrs of connecting edge",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			c, ok := p.Source.(*Connection)
+			if !ok {
+				return nil, castError("attrs", p.Source, "*Connection")
+			}
+			return c.Edge.Attrs(), nil
+		},
+	})
+	cxt.connectionObject.AddFieldConfig("rank", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.Int),
+		Description: "sort key of connecting edge, connections are listed in rank order",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			c, ok := p.Source.(*Connection)
+			if !ok {
+				return nil, castError("rank", p.Source, "*Connection")
+			}
+			return int(c.Edge.Rank()), nil
+		},
+	})
 	return cxt.connectionObject
 }
 func (cxt *GraphqlContext) EdgeType() *graphql.Object {
@@ -636,13 +906,83 @@ func (cxt *GraphqlContext) EdgeType() *graphql.Object {
 			return e.Name(), nil
 		},
 	})
+	cxt.edgeObject.AddFieldConfig("attrs", &graphql.Field{
+		Type:        graphql.NewList(cxt.AttrObject()),
+		Description: "key/value attrs of the connection",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			e, ok := p.Source.(*graph.Edge)
+			if !ok {
+				return nil, castError("attrs", p.Source, "Edge")
+			}
+			return e.Attrs(), nil
+		},
+	})
+	cxt.edgeObject.AddFieldConfig("rank", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.Int),
+		Description: "sort key of the connection, edges are listed in rank order",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			e, ok := p.Source.(*graph.Edge)
+			if !ok {
+				return nil, castError("rank", p.Source, "Edge")
+			}
+			return int(e.Rank()), nil
+		},
+	})
 	return cxt.edgeObject
 }
 
+// TypeInterface returns the interface implemented by every node of t
+// or any of its subtypes so that queries can use `... on t` fragments
+func (cxt *GraphqlContext) TypeInterface(t *graph.Type) *graphql.Interface {
+	if i, exists := cxt.interfaces[t.Name]; exists {
+		return i
+	}
+	i := graphql.NewInterface(graphql.InterfaceConfig{
+		Name:        interfaceName(t),
+		Description: t.Description,
+		Fields:      graphql.Fields{},
+		ResolveType: cxt.resolveNodeType,
+	})
+	cxt.interfaces[t.Name] = i
+	for name, f := range cxt.nodeInterfaceFields() {
+		i.AddFieldConfig(name, f)
+	}
+	for _, f := range t.AllFields() {
+		i.AddFieldConfig(f.Name, cxt.Field(f))
+	}
+	return i
+}
+
+// hasInterface reports whether t is emitted as an interface, which is
+// the case for abstract types and any type that has been extended
+func (cxt *GraphqlContext) hasInterface(t *graph.Type) bool {
+	return t.Abstract || len(cxt.conn.g.Subtypes(t)) > 0
+}
+
+// interfaceName returns the name of the interface emitted for t. An
+// abstract type has no object so its interface takes the type name,
+// otherwise the object keeps the name and the interface is suffixed.
+func interfaceName(t *graph.Type) string {
+	if t.Abstract {
+		return t.Name
+	}
+	return t.Name + "Interface"
+}
+
+// NodeType returns the object for nodes of type t
 func (cxt *GraphqlContext) NodeType(t *graph.Type) *graphql.Object {
 	if _, exists := cxt.types[t.Name]; exists {
 		return cxt.types[t.Name]
 	}
+	interfaces := []*graphql.Interface{
+		cxt.NodeInterface(),
+	}
+	if cxt.hasInterface(t) {
+		interfaces = append(interfaces, cxt.TypeInterface(t))
+	}
+	for _, a := range cxt.conn.g.Ancestors(t) {
+		interfaces = append(interfaces, cxt.TypeInterface(a))
+	}
 	o := graphql.NewObject(graphql.ObjectConfig{
 		Name: t.Name,
 		Fields: graphql.Fields{
@@ -672,8 +1012,8 @@ func (cxt *GraphqlContext) NodeType(t *graph.Type) *graphql.Object {
 						return "", nilSourceError("id", t.Name)
 					}
 					nameAttr := n.Attr("name")
-					if nameAttr != nil && nameAttr.Value != "" {
-						return nameAttr.Value, nil
+					if nameAttr != nil && !nameAttr.Empty() {
+						return nameAttr.String(), nil
 					}
 					return n.ID(), nil
 				},
@@ -755,14 +1095,26 @@ func (cxt *GraphqlContext) NodeType(t *graph.Type) *graphql.Object {
 					return connections, nil
 				},
 			},
+			"history": &graphql.Field{
+				Type:        graphql.NewList(cxt.RevisionObject()),
+				Description: "committed revisions of the node, oldest first",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					n, ok := p.Source.(*graph.Node)
+					if !ok {
+						return nil, castError("history", p.Source, "Node")
+					}
+					if n == nil {
+						return nil, nilSourceError("history", t.Name)
+					}
+					return cxt.conn.db.History(n.ID()), nil
+				},
+			},
 		},
-		Interfaces: []*graphql.Interface{
-			cxt.NodeInterface(),
-		},
+		Interfaces: interfaces,
 	})
 	cxt.types[t.Name] = o
 	// add fields to describe the type fields (each field is like a "column")
-	for _, f := range t.Fields {
+	for _, f := range t.AllFields() {
 		o.AddFieldConfig(f.Name, cxt.Field(f))
 	}
 	return o
@@ -781,9 +1133,34 @@ func (cxt *GraphqlContext) ValueType(fd *graph.Field) graphql.Output {
 	case Image:
 		return cxt.ImageObject()
 	case Edge:
+		if fd.EdgeCardinality == graph.CardinalityOne {
+			return cxt.ConnectionObject()
+		}
 		return graphql.NewList(cxt.ConnectionObject())
 	case RichText:
 		return graphql.String
+	case Date, DateTime, Time:
+		return timeScalars[fd.Type]
+	case GeoPoint:
+		return cxt.GeoPointObject()
+	case DataTable:
+		return cxt.DataTableObject()
+	case Enum:
+		if fd.EnumMulti {
+			return graphql.NewList(cxt.EnumType(fd))
+		}
+		return cxt.EnumType(fd)
+	case Computed:
+		switch fd.ComputedType {
+		case Int:
+			return graphql.Int
+		case Float:
+			return graphql.Float
+		case Boolean:
+			return graphql.Boolean
+		default:
+			return graphql.String
+		}
 	default:
 		panic(fmt.Sprintf("unknown ValueType '%s'", fd.Type))
 	}
@@ -833,7 +1210,7 @@ func (cxt *GraphqlContext) ImageField(f *graph.Field) *graphql.Field {
 				DBName:   cxt.conn.db.Name,
 				NodeID:   n.ID(),
 				AttrName: f.Name,
-				Data:     attr.Value,
+				Data:     attr.String(),
 				Cfg:      &cfg,
 			}
 			return key, nil
@@ -841,10 +1218,112 @@ func (cxt *GraphqlContext) ImageField(f *graph.Field) *graphql.Field {
 	}
 }
 
+// timeLayouts are the default layouts for showing each temporal type
+var timeLayouts = map[string]string{
+	Date:     graph.DateLayout,
+	DateTime: graph.DateTimeLayout,
+	Time:     graph.TimeLayout,
+}
+
+// timeScalars are the GraphQL types of Date, DateTime and Time fields.
+// Values are passed through as formatted strings.
+var timeScalars = map[string]*graphql.Scalar{
+	Date:     timeScalar(Date, "A calendar date, formatted as 2006-01-02 unless another format is given"),
+	DateTime: timeScalar(DateTime, "An instant in time, formatted as RFC3339 unless another format is given"),
+	Time:     timeScalar(Time, "A time of day, formatted as 15:04:05 unless another format is given"),
+}
+
+func timeScalar(name string, description string) *graphql.Scalar {
+	identity := func(v interface{}) interface{} {
+		if s, ok := v.(string); ok {
+			return s
+		}
+		return nil
+	}
+	return graphql.NewScalar(graphql.ScalarConfig{
+		Name:        name,
+		Description: description,
+		Serialize:   identity,
+		ParseValue:  identity,
+		ParseLiteral: func(valueAST ast.Value) interface{} {
+			if v, ok := valueAST.(*ast.StringValue); ok {
+				return v.Value
+			}
+			return nil
+		},
+	})
+}
+
+// TimeField returns a field for a Date, DateTime or Time field f that
+// can be formatted with a Go time layout and DateTimes shown in a zone
+func (cxt *GraphqlContext) TimeField(f *graph.Field) *graphql.Field {
+	return &graphql.Field{
+		Args: graphql.FieldConfigArgument{
+			"format": &graphql.ArgumentConfig{
+				Type:        graphql.String,
+				Description: "Go time layout eg. \"Mon 2 Jan 2006 15:04\"",
+			},
+			"tz": &graphql.ArgumentConfig{
+				Type:        graphql.String,
+				Description: "zone name eg. \"Europe/London\" or offset eg. \"+02:00\" to show a DateTime in",
+			},
+		},
+		Type:        timeScalars[f.Type],
+		Description: f.Description,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			n, ok := p.Source.(*graph.Node)
+			if !ok {
+				return nil, fmt.Errorf("failed to get field %s invalid node source: %v", f.Name, p.Source)
+			}
+			if n == nil {
+				return nil, nilSourceError(f.Name, "<unknown>")
+			}
+			v := n.Value(f)
+			if v == nil {
+				return nil, nil
+			}
+			t, err := graph.ParseTime(f.Type, graph.FormatValue(v))
+			if err != nil {
+				return nil, err
+			}
+			format, _ := p.Args["format"].(string)
+			tz, _ := p.Args["tz"].(string)
+			if tz != "" && f.Type == DateTime {
+				loc, err := loadZone(tz)
+				if err != nil {
+					return nil, err
+				}
+				t = t.In(loc)
+			}
+			if format == "" {
+				format = timeLayouts[f.Type]
+			}
+			return t.Format(format), nil
+		},
+	}
+}
+
+// loadZone returns the location named by tz which may be a zone name
+// or a fixed offset such as +02:00
+func loadZone(tz string) (*time.Location, error) {
+	if t, err := time.Parse("-07:00", tz); err == nil {
+		_, offset := t.Zone()
+		return time.FixedZone(tz, offset), nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("'%s' is not a valid time zone", tz)
+	}
+	return loc, nil
+}
+
 func (cxt *GraphqlContext) Field(f *graph.Field) *graphql.Field {
 	if f.Type == Image {
 		return cxt.ImageField(f)
 	}
+	if f.Temporal() {
+		return cxt.TimeField(f)
+	}
 	return &graphql.Field{
 		Type:        cxt.ValueType(f),
 		Description: f.Description,
@@ -858,11 +1337,7 @@ func (cxt *GraphqlContext) Field(f *graph.Field) *graphql.Field {
 			}
 			switch f.Type {
 			case Edge:
-				var edgeNames []string
-				if f.EdgeName != "" {
-					edgeNames = append(edgeNames, f.EdgeName)
-				}
-				edges := n.Edges(edgeNames, f.EdgeDirection)
+				edges := n.FieldEdges(f)
 				connections := []*Connection{}
 				for _, e := range edges {
 					c := &Connection{
@@ -875,18 +1350,97 @@ func (cxt *GraphqlContext) Field(f *graph.Field) *graphql.Field {
 					}
 					connections = append(connections, c)
 				}
+				if f.EdgeCardinality == graph.CardinalityOne {
+					if len(connections) == 0 {
+						return nil, nil
+					}
+					return connections[len(connections)-1], nil
+				}
 				return connections, nil
-			default:
-				attr := n.Attr(f.Name)
-				if attr == nil {
+			case Computed:
+				return n.Compute(f)
+			case GeoPoint:
+				v := n.Value(f)
+				if v == nil {
 					return nil, nil
 				}
-				return string(attr.Value), nil
-			}
+				return graph.ParseGeoPoint(v)
+			case DataTable:
+				v := n.Value(f)
+				if v == nil {
+					return nil, nil
+				}
+				return f.ParseDataTable(v)
+			case Enum:
+				v := n.Value(f)
+				if v == nil {
+					return nil, nil
+				}
+				sel, err := f.EnumSelection(v)
+				if err != nil {
+					return nil, err
+				}
+				if f.EnumMulti {
+					return sel, nil
+				}
+				return sel[0], nil
+			default:
+				attr := n.Attr(f.Name)
+				if attr == nil || attr.Empty() {
+					return f.DefaultValue(), nil
+				}
+				if attr.Type() == graph.StringValue || attr.Type() == graph.JSONValue {
+					return attr.String(), nil
+				}
+				return attr.Value, nil
+			}
 		},
 	}
 }
 
+// EnumType returns the enum of the values allowed by the Enum field f.
+// Enums are named after the field and shared by fields with the same
+// name and values.
+func (cxt *GraphqlContext) EnumType(f *graph.Field) *graphql.Enum {
+	if cxt.enums == nil {
+		cxt.enums = map[string]*graphql.Enum{}
+	}
+	key := f.Name + ":" + strings.Join(f.EnumValues, "|")
+	if e, ok := cxt.enums[key]; ok {
+		return e
+	}
+	name := strings.ToUpper(f.Name[:1]) + f.Name[1:] + "Enum"
+	for i := 2; cxt.enumNamed(name); i++ {
+		name = fmt.Sprintf("%s%sEnum%d", strings.ToUpper(f.Name[:1]), f.Name[1:], i)
+	}
+	values := graphql.EnumValueConfigMap{}
+	for _, v := range f.EnumValues {
+		values[v] = &graphql.EnumValueConfig{
+			Value: v,
+		}
+	}
+	cxt.enums[key] = graphql.NewEnum(graphql.EnumConfig{
+		Name:        name,
+		Description: fmt.Sprintf("allowed values of %s fields", f.Name),
+		Values:      values,
+	})
+	return cxt.enums[key]
+}
+
+// enumNamed reports whether name is used by another enum, a type the
+// schema defines or a node type
+func (cxt *GraphqlContext) enumNamed(name string) bool {
+	if schemaTypeNames[name] || cxt.conn.g.TypeByName(name) != nil {
+		return true
+	}
+	for _, e := range cxt.enums {
+		if e.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
 func (cxt *GraphqlContext) TypeEnum() *graphql.Enum {
 	if cxt.typeEnum != nil {
 		return cxt.typeEnum
@@ -911,7 +1465,7 @@ func (cxt *GraphqlContext) FieldNameEnum() *graphql.Enum {
 	}
 	values := graphql.EnumValueConfigMap{}
 	for _, t := range cxt.conn.g.Types() {
-		for _, fd := range t.Fields {
+		for _, fd := range t.AllFields() {
 			if _, exists := values[fd.Name]; !exists {
 				values[fd.Name] = &graphql.EnumValueConfig{
 					Value: fd.Name,
@@ -927,6 +1481,79 @@ func (cxt *GraphqlContext) FieldNameEnum() *graphql.Enum {
 	return cxt.fieldNameEnum
 }
 
+// reservedFieldNames are the node interface fields that a type's own
+// fields cannot replace, "name" is left out as a Text field named name
+// is what the interface reports.
+var reservedFieldNames = map[string]bool{
+	"id":          true,
+	"type":        true,
+	"attrs":       true,
+	"connections": true,
+	"history":     true,
+}
+
+// checkType validates a type definition before it is defined in g. It
+// is used by setType and for each new type brought in by importSubgraph.
+func checkType(g *graph.Graph, t *graph.Type) error {
+	if !validIdent.MatchString(t.Name) {
+		return fmt.Errorf("cannot define type '%s': not a valid type name", t.Name)
+	}
+	if schemaTypeNames[t.Name] || schemaTypeNames[t.Name+"Interface"] {
+		return fmt.Errorf("cannot define type '%s': the name is used by the schema", t.Name)
+	}
+	for _, other := range g.Types() {
+		if other.ID == t.ID {
+			continue
+		}
+		if other.Name == t.Name {
+			return fmt.Errorf("cannot define type '%s': the name is used by type '%s'", t.Name, other.ID)
+		}
+		if other.Name+"Interface" == t.Name || t.Name+"Interface" == other.Name {
+			return fmt.Errorf("cannot define type '%s': the name clashes with the interface of type '%s'", t.Name, other.Name)
+		}
+	}
+	for _, fa := range t.Fields {
+		if !validIdent.MatchString(fa.Name) {
+			return fmt.Errorf("'%s' is not a valid field name", fa.Name)
+		}
+		if reservedFieldNames[fa.Name] || (fa.Name == "name" && fa.Type != "Text") {
+			return fmt.Errorf("'%s' is a reserved field name", fa.Name)
+		}
+		if !validFieldType.MatchString(fa.Type) {
+			return fmt.Errorf("'%s' is not a valid field type", fa.Type)
+		}
+		if fa.EdgeDirection != "" {
+			if !validEdgeDirection.MatchString(fa.EdgeDirection) {
+				return fmt.Errorf("'%s' is not a valid field edgeDirection", fa.Type)
+			}
+		}
+		if fa.OnDelete != "" {
+			if !validOnDelete.MatchString(fa.OnDelete) {
+				return fmt.Errorf("'%s' is not a valid field onDelete policy", fa.OnDelete)
+			}
+		}
+		if fa.EdgeCardinality != "" {
+			if !validEdgeCardinality.MatchString(fa.EdgeCardinality) {
+				return fmt.Errorf("'%s' is not a valid field edgeCardinality", fa.EdgeCardinality)
+			}
+		}
+		if fa.EdgeOverflow != "" {
+			if !validEdgeOverflow.MatchString(fa.EdgeOverflow) {
+				return fmt.Errorf("'%s' is not a valid field edgeOverflow policy", fa.EdgeOverflow)
+			}
+		}
+		if fa.EdgeLimit < 0 {
+			return fmt.Errorf("field edgeLimit cannot be negative")
+		}
+		for _, v := range fa.EnumValues {
+			if !validEnumValue(v) {
+				return fmt.Errorf("'%s' is not a valid field enum value", v)
+			}
+		}
+	}
+	return g.ValidateType(t)
+}
+
 func (cxt *GraphqlContext) SetTypeMutation() *graphql.Field {
 	return &graphql.Field{
 		Description: "Create a new type",
@@ -938,6 +1565,14 @@ func (cxt *GraphqlContext) SetTypeMutation() *graphql.Field {
 			"name": &graphql.ArgumentConfig{
 				Type: graphql.NewNonNull(graphql.String),
 			},
+			"extends": &graphql.ArgumentConfig{
+				Type:        graphql.NewList(graphql.String),
+				Description: "ids of parent types to inherit fields from",
+			},
+			"abstract": &graphql.ArgumentConfig{
+				Type:        graphql.Boolean,
+				Description: "abstract types can only be extended and cannot hold nodes",
+			},
 			"fields": &graphql.ArgumentConfig{
 				Type: graphql.NewList(graphql.NewInputObject(graphql.InputObjectConfig{
 					Name: "FieldArg",
@@ -951,6 +1586,24 @@ func (cxt *GraphqlContext) SetTypeMutation() *graphql.Field {
 						"required": &graphql.InputObjectFieldConfig{
 							Type: graphql.Boolean,
 						},
+						"unique": &graphql.InputObjectFieldConfig{
+							Type: graphql.Boolean,
+						},
+						"indexed": &graphql.InputObjectFieldConfig{
+							Type: graphql.Boolean,
+						},
+						"default": &graphql.InputObjectFieldConfig{
+							Type: graphql.String,
+						},
+						"enumValues": &graphql.InputObjectFieldConfig{
+							Type: graphql.NewList(graphql.String),
+						},
+						"enumMulti": &graphql.InputObjectFieldConfig{
+							Type: graphql.Boolean,
+						},
+						"dataColumns": &graphql.InputObjectFieldConfig{
+							Type: graphql.NewList(cxt.DataColumnInputObject()),
+						},
 						"unit": &graphql.InputObjectFieldConfig{
 							Type: graphql.String,
 						},
@@ -969,6 +1622,18 @@ func (cxt *GraphqlContext) SetTypeMutation() *graphql.Field {
 						"edgeToTypeID": &graphql.InputObjectFieldConfig{
 							Type: graphql.String,
 						},
+						"onDelete": &graphql.InputObjectFieldConfig{
+							Type: graphql.String,
+						},
+						"edgeCardinality": &graphql.InputObjectFieldConfig{
+							Type: graphql.String,
+						},
+						"edgeLimit": &graphql.InputObjectFieldConfig{
+							Type: graphql.Int,
+						},
+						"edgeOverflow": &graphql.InputObjectFieldConfig{
+							Type: graphql.String,
+						},
 						"textMarkup": &graphql.InputObjectFieldConfig{
 							Type: graphql.String,
 						},
@@ -981,42 +1646,51 @@ func (cxt *GraphqlContext) SetTypeMutation() *graphql.Field {
 						"textCharLimit": &graphql.InputObjectFieldConfig{
 							Type: graphql.Int,
 						},
+						"expression": &graphql.InputObjectFieldConfig{
+							Type: graphql.String,
+						},
+						"computedType": &graphql.InputObjectFieldConfig{
+							Type: graphql.String,
+						},
 					},
 				})),
 			},
 		},
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 			args := struct {
-				ID     string
-				Name   string
-				Fields []*graph.Field
+				ID       string
+				Name     string
+				Extends  []string
+				Abstract bool
+				Fields   []*graph.Field
 			}{}
 			if err := fill(&args, p.Args); err != nil {
 				return nil, err
 			}
-			if !validIdent.MatchString(args.Name) {
-				return nil, fmt.Errorf("cannot define type '%': not a valid type name")
-			}
 			t := &graph.Type{
-				ID:   args.ID,
-				Name: args.Name,
+				ID:       args.ID,
+				Name:     args.Name,
+				Extends:  args.Extends,
+				Abstract: args.Abstract,
+				Fields:   args.Fields,
 			}
-			for _, fa := range args.Fields {
-				if !validIdent.MatchString(fa.Name) {
-					return nil, fmt.Errorf("'%s' is not a valid field name", fa.Name)
-				}
-				if !validFieldType.MatchString(fa.Type) {
-					return nil, fmt.Errorf("'%s' is not a valid field type", fa.Type)
-				}
-				if fa.EdgeDirection != "" {
-					if !validEdgeDirection.MatchString(fa.EdgeDirection) {
-						return nil, fmt.Errorf("'%s' is not a valid field edgeDirection", fa.Type)
+			g := cxt.conn.g
+			if err := checkType(g, t); err != nil {
+				return nil, err
+			}
+			if t.Abstract {
+				for _, n := range g.Nodes() {
+					if n.Type() != nil && n.Type().ID == t.ID {
+						return nil, fmt.Errorf("cannot make type '%s' abstract while it has nodes", t.Name)
 					}
 				}
-				t.Fields = append(t.Fields, fa)
 			}
-			g := cxt.conn.g
 			g = g.DefineType(*t)
+			// existing nodes must meet any new rules, such as a field
+			// becoming unique while values are shared
+			if err := cxt.conn.validate(g); err != nil {
+				return nil, fmt.Errorf("cannot define type '%s': %s", args.Name, err)
+			}
 			t = g.TypeByID(args.ID)
 			if t == nil {
 				return nil, fmt.Errorf("failed to create type")
@@ -1027,220 +1701,1653 @@ func (cxt *GraphqlContext) SetTypeMutation() *graphql.Field {
 	}
 }
 
-func (cxt *GraphqlContext) NodeListField(t *graph.Type) *graphql.Field {
-	var gqlType graphql.Type
-	if t == nil {
-		gqlType = cxt.NodeInterface()
-	} else {
-		gqlType = cxt.NodeType(t)
-	}
+func (cxt *GraphqlContext) RenameFieldMutation() *graphql.Field {
 	return &graphql.Field{
+		Description: "rename a field and the matching attr on every node of the type",
+		Type:        cxt.TypeObject(),
 		Args: graphql.FieldConfigArgument{
-			"type": &graphql.ArgumentConfig{
-				Type: graphql.NewList(cxt.TypeEnum()),
-			},
 			"typeID": &graphql.ArgumentConfig{
-				Type: graphql.NewList(graphql.String),
+				Type: graphql.NewNonNull(graphql.String),
 			},
-			"sort": &graphql.ArgumentConfig{
-				Type: graphql.NewList(cxt.FieldNameEnum()),
+			"from": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"to": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
 			},
 		},
-		Description: "list all nodes",
-		Type:        graphql.NewList(gqlType),
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 			args := struct {
-				Type   []string
-				TypeID []string
-				Sort   []string
+				TypeID string
+				From   string
+				To     string
 			}{}
 			if err := fill(&args, p.Args); err != nil {
 				return nil, err
 			}
-			ns := cxt.conn.g.Nodes()
-			ts := []*graph.Type{}
-			for _, typeName := range args.Type {
-				t := cxt.conn.g.TypeByName(typeName)
-				if t == nil {
-					return nil, fmt.Errorf("'%s' is not a valid type name")
-				}
-				ts = append(ts, t)
+			if !validIdent.MatchString(args.To) {
+				return nil, fmt.Errorf("'%s' is not a valid field name", args.To)
 			}
-			for _, typeID := range args.TypeID {
-				t := cxt.conn.g.TypeByID(typeID)
-				if t == nil {
-					return nil, fmt.Errorf("'%s' is not a valid type id")
-				}
-				ts = append(ts, t)
+			g, err := cxt.conn.g.RenameField(args.TypeID, args.From, args.To)
+			if err != nil {
+				return nil, err
 			}
-			ns = ns.FilterType(ts...)
-			sort.Sort(ns) // sort by id
-			return ns, nil
+			cxt.conn.update(g)
+			return g.TypeByID(args.TypeID), nil
 		},
 	}
 }
 
-func (cxt *GraphqlContext) NodeField(t *graph.Type) *graphql.Field {
-	var gqlType graphql.Type
-	if t == nil {
-		gqlType = cxt.NodeInterface()
-	} else {
-		gqlType = cxt.NodeType(t)
+type fieldMigration struct {
+	Type     *graph.Type
+	Failures []*graph.MigrationFailure
+}
+
+func (cxt *GraphqlContext) MigrationFailureObject() *graphql.Object {
+	if cxt.failureObject != nil {
+		return cxt.failureObject
 	}
-	return &graphql.Field{
-		Args: graphql.FieldConfigArgument{
-			"id": &graphql.ArgumentConfig{
-				Type: graphql.NewNonNull(graphql.ID),
+	cxt.failureObject = graphql.NewObject(graphql.ObjectConfig{
+		Name: "MigrationFailure",
+		Fields: graphql.Fields{
+			"nodeID": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "id of the node that held the value",
+			},
+			"field": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "name of the field the value was set on",
+			},
+			"value": &graphql.Field{
+				Type:        graphql.String,
+				Description: "the value that was removed",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					f, ok := p.Source.(*graph.MigrationFailure)
+					if !ok {
+						return nil, castError("value", p.Source, "*MigrationFailure")
+					}
+					return graph.FormatValue(f.Value), nil
+				},
+			},
+			"reason": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "why the value could not be converted",
 			},
 		},
-		Description: "fetch any node by id",
-		Type:        gqlType,
-		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-			id, ok := p.Args["id"].(string)
-			if !ok || id == "" {
-				return nil, fmt.Errorf("invalid id")
-			}
-			return cxt.conn.g.Get(id), nil
-		},
+	})
+	return cxt.failureObject
+}
+
+func (cxt *GraphqlContext) MigrationObject() *graphql.Object {
+	if cxt.migrationObject != nil {
+		return cxt.migrationObject
 	}
+	cxt.migrationObject = graphql.NewObject(graphql.ObjectConfig{
+		Name: "FieldMigration",
+		Fields: graphql.Fields{
+			"type": &graphql.Field{
+				Type:        cxt.TypeObject(),
+				Description: "the migrated type",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					m, ok := p.Source.(*fieldMigration)
+					if !ok {
+						return nil, castError("type", p.Source, "*fieldMigration")
+					}
+					return m.Type, nil
+				},
+			},
+			"failures": &graphql.Field{
+				Type:        graphql.NewList(cxt.MigrationFailureObject()),
+				Description: "values that could not be converted and were removed",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					m, ok := p.Source.(*fieldMigration)
+					if !ok {
+						return nil, castError("failures", p.Source, "*fieldMigration")
+					}
+					return m.Failures, nil
+				},
+			},
+		},
+	})
+	return cxt.migrationObject
 }
 
-func (cxt *GraphqlContext) GetType() *graphql.Field {
+func (cxt *GraphqlContext) ChangeFieldTypeMutation() *graphql.Field {
 	return &graphql.Field{
+		Description: "change the type of a field and convert the matching attr on every node of the type",
+		Type:        cxt.MigrationObject(),
 		Args: graphql.FieldConfigArgument{
-			"id": &graphql.ArgumentConfig{
+			"typeID": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"name": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"type": &graphql.ArgumentConfig{
 				Type: graphql.NewNonNull(graphql.String),
 			},
+			"force": &graphql.ArgumentConfig{
+				Type:         graphql.Boolean,
+				DefaultValue: false,
+				Description:  "remove values that cannot be converted instead of failing",
+			},
+			"enumValues": &graphql.ArgumentConfig{
+				Type:        graphql.NewList(graphql.String),
+				Description: "values allowed when converting to Enum",
+			},
+			"enumMulti": &graphql.ArgumentConfig{
+				Type:        graphql.Boolean,
+				Description: "may the Enum hold more than one value",
+			},
+			"dataColumns": &graphql.ArgumentConfig{
+				Type:        graphql.NewList(cxt.DataColumnInputObject()),
+				Description: "columns when converting to DataTable",
+			},
 		},
-		Description: "fetch single type by id",
-		Type:        cxt.TypeObject(),
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-			name, ok := p.Args["id"].(string)
-			if !ok || name == "" {
-				return nil, fmt.Errorf("invalid id arg")
+			args := struct {
+				TypeID      string
+				Name        string
+				Type        string
+				Force       bool
+				EnumValues  []string
+				EnumMulti   bool
+				DataColumns []*graph.DataColumn
+			}{}
+			if err := fill(&args, p.Args); err != nil {
+				return nil, err
 			}
-			t := cxt.conn.g.TypeByID(name)
-			if t == nil {
-				return nil, nil
+			if !validFieldType.MatchString(args.Type) {
+				return nil, fmt.Errorf("'%s' is not a valid field type", args.Type)
 			}
-			return t, nil
-		},
-	}
-}
-func (cxt *GraphqlContext) GetTypes() *graphql.Field {
-	return &graphql.Field{
-		Description: "list all type definition",
-		Type:        graphql.NewList(cxt.TypeObject()),
-		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-			return cxt.conn.g.Types(), nil
+			for _, v := range args.EnumValues {
+				if !validEnumValue(v) {
+					return nil, fmt.Errorf("'%s' is not a valid field enum value", v)
+				}
+			}
+			g, failures, err := cxt.conn.g.ChangeFieldType(args.TypeID, args.Name, graph.FieldConversion{
+				Type:        args.Type,
+				EnumValues:  args.EnumValues,
+				EnumMulti:   args.EnumMulti,
+				DataColumns: args.DataColumns,
+			})
+			if err != nil {
+				return nil, err
+			}
+			if len(failures) > 0 && !args.Force {
+				msgs := []string{}
+				for _, f := range failures {
+					msgs = append(msgs, fmt.Sprintf("node '%s': %s", f.NodeID, f.Reason))
+				}
+				return nil, fmt.Errorf("cannot change type of field '%s': %d values cannot be converted (%s)", args.Name, len(failures), strings.Join(msgs, ", "))
+			}
+			cxt.conn.update(g)
+			return &fieldMigration{
+				Type:     g.TypeByID(args.TypeID),
+				Failures: failures,
+			}, nil
 		},
 	}
 }
 
-func (cxt *GraphqlContext) GetEdges() *graphql.Field {
+func (cxt *GraphqlContext) DropFieldMutation() *graphql.Field {
 	return &graphql.Field{
-		Description: "fetch edges",
-		Type:        graphql.NewList(cxt.EdgeType()),
+		Description: "remove a field and purge the matching attr from every node of the type",
+		Type:        cxt.TypeObject(),
 		Args: graphql.FieldConfigArgument{
-			"name": &graphql.ArgumentConfig{
-				Type: graphql.String,
-			},
-			"from": &graphql.ArgumentConfig{
-				Type: graphql.String,
+			"typeID": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
 			},
-			"to": &graphql.ArgumentConfig{
-				Type: graphql.String,
+			"name": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
 			},
 		},
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-			match := graph.EdgeMatch{}
-			err := fill(&match, p.Args)
+			args := struct {
+				TypeID string
+				Name   string
+			}{}
+			if err := fill(&args, p.Args); err != nil {
+				return nil, err
+			}
+			g, err := cxt.conn.g.DropField(args.TypeID, args.Name)
 			if err != nil {
 				return nil, err
 			}
-			g := cxt.conn.g
-			edges := g.Edges(match)
-			return edges, nil
+			cxt.conn.update(g)
+			return g.TypeByID(args.TypeID), nil
 		},
 	}
 }
 
-func (cxt *GraphqlContext) DisconnectMutation() *graphql.Field {
-	return &graphql.Field{
-		Description: "set node data",
-		Type:        graphql.NewList(cxt.EdgeType()),
-		Args: graphql.FieldConfigArgument{
-			"name": &graphql.ArgumentConfig{
+func (cxt *GraphqlContext) WhereInputObject() *graphql.InputObject {
+	if cxt.whereInputObject != nil {
+		return cxt.whereInputObject
+	}
+	cxt.whereInputObject = graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "WhereArg",
+		Description: "condition on an attr value (field, op, value) or on the existence of an edge (edge, direction, exists)",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"field": &graphql.InputObjectFieldConfig{
 				Type: graphql.String,
 			},
-			"from": &graphql.ArgumentConfig{
+			"op": &graphql.InputObjectFieldConfig{
+				Type:        graphql.String,
+				Description: "eq, ne, lt, gt, lte, gte, in or contains (default eq)",
+			},
+			"value": &graphql.InputObjectFieldConfig{
 				Type: graphql.String,
 			},
-			"to": &graphql.ArgumentConfig{
+			"values": &graphql.InputObjectFieldConfig{
+				Type:        graphql.NewList(graphql.String),
+				Description: "values for the in operator",
+			},
+			"edge": &graphql.InputObjectFieldConfig{
+				Type: graphql.String,
+			},
+			"direction": &graphql.InputObjectFieldConfig{
 				Type: graphql.String,
 			},
+			"exists": &graphql.InputObjectFieldConfig{
+				Type:        graphql.Boolean,
+				Description: "false to match nodes without the edge (default true)",
+			},
+		},
+	})
+	return cxt.whereInputObject
+}
+
+func (cxt *GraphqlContext) GeoPointObject() *graphql.Object {
+	if cxt.geoPointObject != nil {
+		return cxt.geoPointObject
+	}
+	coord := func(name string, get func(p graph.GeoPoint) float64) *graphql.Field {
+		return &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Float),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				pt, ok := p.Source.(graph.GeoPoint)
+				if !ok {
+					return nil, castError(name, p.Source, "GeoPoint")
+				}
+				return get(pt), nil
+			},
+		}
+	}
+	cxt.geoPointObject = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "GeoPoint",
+		Description: "position on the earth in degrees",
+		Fields: graphql.Fields{
+			"lat": coord("lat", func(p graph.GeoPoint) float64 { return p.Lat }),
+			"lng": coord("lng", func(p graph.GeoPoint) float64 { return p.Lng }),
+			"distance": &graphql.Field{
+				Type:        graphql.Float,
+				Description: "distance in metres to the given point",
+				Args: graphql.FieldConfigArgument{
+					"lat": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.Float),
+					},
+					"lng": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.Float),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					pt, ok := p.Source.(graph.GeoPoint)
+					if !ok {
+						return nil, castError("distance", p.Source, "GeoPoint")
+					}
+					to := graph.GeoPoint{}
+					if err := fill(&to, p.Args); err != nil {
+						return nil, err
+					}
+					if err := to.Validate(); err != nil {
+						return nil, err
+					}
+					return pt.Distance(to), nil
+				},
+			},
+		},
+	})
+	return cxt.geoPointObject
+}
+
+func (cxt *GraphqlContext) DataColumnObject() *graphql.Object {
+	if cxt.dataColumnObject != nil {
+		return cxt.dataColumnObject
+	}
+	cxt.dataColumnObject = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "DataColumn",
+		Description: "column of a DataTable field",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"type": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Text, Int, Float or Boolean",
+			},
+			"unit": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					c, ok := p.Source.(*graph.DataColumn)
+					if !ok {
+						return nil, castError("unit", p.Source, "DataColumn")
+					}
+					if c.Unit == "" {
+						return nil, nil
+					}
+					return c.Unit, nil
+				},
+			},
+		},
+	})
+	return cxt.dataColumnObject
+}
+
+func (cxt *GraphqlContext) DataColumnInputObject() *graphql.InputObject {
+	if cxt.dataColumnInput != nil {
+		return cxt.dataColumnInput
+	}
+	cxt.dataColumnInput = graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "DataColumnArg",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"type": &graphql.InputObjectFieldConfig{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Text, Int, Float or Boolean",
+			},
+			"unit": &graphql.InputObjectFieldConfig{
+				Type: graphql.String,
+			},
+		},
+	})
+	return cxt.dataColumnInput
+}
+
+func (cxt *GraphqlContext) DataTableObject() *graphql.Object {
+	if cxt.dataTableObject != nil {
+		return cxt.dataTableObject
+	}
+	cxt.dataTableObject = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "DataTable",
+		Description: "rows of a DataTable field",
+		Fields: graphql.Fields{
+			"columns": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(cxt.DataColumnObject())),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					dt, ok := p.Source.(*graph.DataTable)
+					if !ok {
+						return nil, castError("columns", p.Source, "DataTable")
+					}
+					return dt.Columns, nil
+				},
+			},
+			"rows": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewList(graphql.String))),
+				Description: "cells of each row in column order, null where empty",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					dt, ok := p.Source.(*graph.DataTable)
+					if !ok {
+						return nil, castError("rows", p.Source, "DataTable")
+					}
+					rows := [][]interface{}{}
+					for _, row := range dt.Rows {
+						cells := []interface{}{}
+						for _, cell := range row {
+							if cell == nil {
+								cells = append(cells, nil)
+								continue
+							}
+							cells = append(cells, graph.FormatValue(cell))
+						}
+						rows = append(rows, cells)
+					}
+					return rows, nil
+				},
+			},
+			"csv": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "the table as CSV with a header of column names",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					dt, ok := p.Source.(*graph.DataTable)
+					if !ok {
+						return nil, castError("csv", p.Source, "DataTable")
+					}
+					return dt.CSV(), nil
+				},
+			},
+		},
+	})
+	return cxt.dataTableObject
+}
+
+func (cxt *GraphqlContext) NearInputObject() *graphql.InputObject {
+	if cxt.nearInputObject != nil {
+		return cxt.nearInputObject
+	}
+	cxt.nearInputObject = graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "NearArg",
+		Description: "circle around a point that a GeoPoint field must fall inside",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"field": &graphql.InputObjectFieldConfig{
+				Type:        graphql.String,
+				Description: "GeoPoint field to test (default any)",
+			},
+			"lat": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.Float),
+			},
+			"lng": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.Float),
+			},
+			"radius": &graphql.InputObjectFieldConfig{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "radius in metres",
+			},
+		},
+	})
+	return cxt.nearInputObject
+}
+
+func (cxt *GraphqlContext) BoundsInputObject() *graphql.InputObject {
+	if cxt.boundsInputObject != nil {
+		return cxt.boundsInputObject
+	}
+	cxt.boundsInputObject = graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "BoundsArg",
+		Description: "box that a GeoPoint field must fall inside, west may be greater than east to cross the antimeridian",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"field": &graphql.InputObjectFieldConfig{
+				Type:        graphql.String,
+				Description: "GeoPoint field to test (default any)",
+			},
+			"north": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.Float),
+			},
+			"south": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.Float),
+			},
+			"east": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.Float),
+			},
+			"west": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.Float),
+			},
+		},
+	})
+	return cxt.boundsInputObject
+}
+
+func (cxt *GraphqlContext) NodeListField(t *graph.Type) *graphql.Field {
+	var gqlType graphql.Type
+	if t == nil {
+		gqlType = cxt.NodeInterface()
+	} else {
+		gqlType = cxt.NodeType(t)
+	}
+	return &graphql.Field{
+		Args: graphql.FieldConfigArgument{
+			"type": &graphql.ArgumentConfig{
+				Type: graphql.NewList(cxt.TypeEnum()),
+			},
+			"typeID": &graphql.ArgumentConfig{
+				Type: graphql.NewList(graphql.String),
+			},
+			"sort": &graphql.ArgumentConfig{
+				Type:        graphql.NewList(cxt.FieldNameEnum()),
+				Description: "fields to order by, nodes are ordered by id when not given",
+			},
+			"desc": &graphql.ArgumentConfig{
+				Type:        graphql.Boolean,
+				Description: "reverse the order",
+			},
+			"where": &graphql.ArgumentConfig{
+				Type:        graphql.NewList(cxt.WhereInputObject()),
+				Description: "only list nodes that match every condition",
+			},
+			"near": &graphql.ArgumentConfig{
+				Type:        cxt.NearInputObject(),
+				Description: "only list nodes near a point, nearest first, use nearby for their distances",
+			},
+			"within": &graphql.ArgumentConfig{
+				Type:        cxt.BoundsInputObject(),
+				Description: "only list nodes inside a box",
+			},
+		},
+		Description: "list all nodes",
+		Type:        graphql.NewList(gqlType),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			args := &nodeListArgs{}
+			if err := fill(args, p.Args); err != nil {
+				return nil, err
+			}
+			ts, conds, err := cxt.nodeFilters(args)
+			if err != nil {
+				return nil, err
+			}
+			if args.Near == nil && args.Within == nil {
+				ns, err := cxt.conn.g.Find(ts, conds) // sorted by id
+				if err != nil {
+					return nil, err
+				}
+				if len(args.Sort) > 0 || args.Desc {
+					ns.SortBy(args.Sort, args.Desc)
+				}
+				return ns, nil
+			}
+			gf, err := args.geoFilter()
+			if err != nil {
+				return nil, err
+			}
+			matches, err := cxt.conn.db.FindNear(cxt.conn.g, ts, conds, gf) // nearest first
+			if err != nil {
+				return nil, err
+			}
+			ns := graph.Nodes{}
+			for _, m := range matches {
+				ns = append(ns, m.Node)
+			}
+			switch {
+			case len(args.Sort) > 0 || (args.Desc && gf.Near == nil):
+				ns.SortBy(args.Sort, args.Desc)
+			case args.Desc:
+				for i, j := 0, len(ns)-1; i < j; i, j = i+1, j-1 {
+					ns[i], ns[j] = ns[j], ns[i]
+				}
+			}
+			return ns, nil
+		},
+	}
+}
+
+// nodeListArgs are the arguments of the nodes and nearby queries
+type nodeListArgs struct {
+	Type   []string
+	TypeID []string
+	Sort   []string
+	Desc   bool
+	Near   *struct {
+		Field  string
+		Lat    float64
+		Lng    float64
+		Radius float64
+	}
+	Within *struct {
+		Field string
+		graph.GeoBounds
+	}
+	Where []struct {
+		Field     string
+		Op        string
+		Value     string
+		Values    []string
+		Edge      string
+		Direction string
+		Exists    *bool
+	}
+}
+
+// nodeFilters returns the types and conditions selected by args
+func (cxt *GraphqlContext) nodeFilters(args *nodeListArgs) ([]*graph.Type, []*graph.Condition, error) {
+	ts := []*graph.Type{}
+	for _, typeName := range args.Type {
+		t := cxt.conn.g.TypeByName(typeName)
+		if t == nil {
+			return nil, nil, fmt.Errorf("'%s' is not a valid type name", typeName)
+		}
+		ts = append(ts, t)
+	}
+	for _, typeID := range args.TypeID {
+		t := cxt.conn.g.TypeByID(typeID)
+		if t == nil {
+			return nil, nil, fmt.Errorf("'%s' is not a valid type id", typeID)
+		}
+		ts = append(ts, t)
+	}
+	conds := []*graph.Condition{}
+	for _, w := range args.Where {
+		c := &graph.Condition{
+			Field:     w.Field,
+			Op:        w.Op,
+			Value:     w.Value,
+			Edge:      w.Edge,
+			Direction: w.Direction,
+			Exists:    w.Exists == nil || *w.Exists,
+		}
+		if c.Op == "" {
+			c.Op = graph.OpEq
+		}
+		for _, v := range w.Values {
+			c.Values = append(c.Values, v)
+		}
+		conds = append(conds, c)
+	}
+	return ts, conds, nil
+}
+
+// geoFilter returns the GeoFilter for the near and within args
+func (args *nodeListArgs) geoFilter() (*GeoFilter, error) {
+	gf := &GeoFilter{}
+	if args.Near != nil {
+		p := graph.GeoPoint{Lat: args.Near.Lat, Lng: args.Near.Lng}
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("near: %s", err)
+		}
+		if args.Near.Radius < 0 {
+			return nil, fmt.Errorf("near: radius cannot be negative")
+		}
+		gf.Near, gf.Radius, gf.Field = &p, args.Near.Radius, args.Near.Field
+	}
+	if args.Within != nil {
+		if err := args.Within.Validate(); err != nil {
+			return nil, fmt.Errorf("within: %s", err)
+		}
+		if gf.Field != "" && args.Within.Field != "" && gf.Field != args.Within.Field {
+			return nil, fmt.Errorf("near and within must test the same field")
+		}
+		gf.Bounds = &args.Within.GeoBounds
+		if args.Within.Field != "" {
+			gf.Field = args.Within.Field
+		}
+	}
+	return gf, nil
+}
+
+func (cxt *GraphqlContext) NearResultObject() *graphql.Object {
+	if cxt.nearResultObject != nil {
+		return cxt.nearResultObject
+	}
+	cxt.nearResultObject = graphql.NewObject(graphql.ObjectConfig{
+		Name: "NearResult",
+		Fields: graphql.Fields{
+			"node": &graphql.Field{
+				Type:        cxt.NodeInterface(),
+				Description: "the matching node",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					m, ok := p.Source.(*GeoMatch)
+					if !ok {
+						return nil, castError("node", p.Source, "*GeoMatch")
+					}
+					return m.Node, nil
+				},
+			},
+			"distance": &graphql.Field{
+				Type:        graphql.Float,
+				Description: "distance in metres from the near point to the matching GeoPoint",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					m, ok := p.Source.(*GeoMatch)
+					if !ok {
+						return nil, castError("distance", p.Source, "*GeoMatch")
+					}
+					return m.Distance, nil
+				},
+			},
+			"field": &graphql.Field{
+				Type:        graphql.String,
+				Description: "name of the GeoPoint field that matched",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					m, ok := p.Source.(*GeoMatch)
+					if !ok {
+						return nil, castError("field", p.Source, "*GeoMatch")
+					}
+					return m.Field, nil
+				},
+			},
+		},
+	})
+	return cxt.nearResultObject
+}
+
+func (cxt *GraphqlContext) NearbyField() *graphql.Field {
+	return &graphql.Field{
+		Description: "nodes near a point with their distance from it, nearest first",
+		Type:        graphql.NewList(cxt.NearResultObject()),
+		Args: graphql.FieldConfigArgument{
+			"type": &graphql.ArgumentConfig{
+				Type: graphql.NewList(cxt.TypeEnum()),
+			},
+			"typeID": &graphql.ArgumentConfig{
+				Type: graphql.NewList(graphql.String),
+			},
+			"where": &graphql.ArgumentConfig{
+				Type:        graphql.NewList(cxt.WhereInputObject()),
+				Description: "only list nodes that match every condition",
+			},
+			"near": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(cxt.NearInputObject()),
+			},
+			"within": &graphql.ArgumentConfig{
+				Type:        cxt.BoundsInputObject(),
+				Description: "only list nodes inside a box",
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			args := &nodeListArgs{}
+			if err := fill(args, p.Args); err != nil {
+				return nil, err
+			}
+			ts, conds, err := cxt.nodeFilters(args)
+			if err != nil {
+				return nil, err
+			}
+			gf, err := args.geoFilter()
+			if err != nil {
+				return nil, err
+			}
+			return cxt.conn.db.FindNear(cxt.conn.g, ts, conds, gf)
+		},
+	}
+}
+
+func (cxt *GraphqlContext) NodeField(t *graph.Type) *graphql.Field {
+	var gqlType graphql.Type
+	if t == nil {
+		gqlType = cxt.NodeInterface()
+	} else {
+		gqlType = cxt.NodeType(t)
+	}
+	return &graphql.Field{
+		Args: graphql.FieldConfigArgument{
+			"id": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.ID),
+			},
+		},
+		Description: "fetch any node by id",
+		Type:        gqlType,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id, ok := p.Args["id"].(string)
+			if !ok || id == "" {
+				return nil, fmt.Errorf("invalid id")
+			}
+			return cxt.conn.g.Get(id), nil
+		},
+	}
+}
+
+func (cxt *GraphqlContext) GetType() *graphql.Field {
+	return &graphql.Field{
+		Args: graphql.FieldConfigArgument{
+			"id": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+		},
+		Description: "fetch single type by id",
+		Type:        cxt.TypeObject(),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			name, ok := p.Args["id"].(string)
+			if !ok || name == "" {
+				return nil, fmt.Errorf("invalid id arg")
+			}
+			t := cxt.conn.g.TypeByID(name)
+			if t == nil {
+				return nil, nil
+			}
+			return t, nil
+		},
+	}
+}
+func (cxt *GraphqlContext) GetTypes() *graphql.Field {
+	return &graphql.Field{
+		Description: "list all type definition",
+		Type:        graphql.NewList(cxt.TypeObject()),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return cxt.conn.g.Types(), nil
+		},
+	}
+}
+
+func (cxt *GraphqlContext) GetEdges() *graphql.Field {
+	return &graphql.Field{
+		Description: "fetch edges",
+		Type:        graphql.NewList(cxt.EdgeType()),
+		Args: graphql.FieldConfigArgument{
+			"name": &graphql.ArgumentConfig{
+				Type: graphql.String,
+			},
+			"from": &graphql.ArgumentConfig{
+				Type: graphql.String,
+			},
+			"to": &graphql.ArgumentConfig{
+				Type: graphql.String,
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			match := graph.EdgeMatch{}
+			err := fill(&match, p.Args)
+			if err != nil {
+				return nil, err
+			}
+			g := cxt.conn.g
+			edges := g.Edges(match)
+			return edges, nil
+		},
+	}
+}
+
+func (cxt *GraphqlContext) StepObject() *graphql.Object {
+	if cxt.stepObject != nil {
+		return cxt.stepObject
+	}
+	cxt.stepObject = graphql.NewObject(graphql.ObjectConfig{
+		Name:   "Step",
+		Fields: graphql.Fields{},
+	})
+	cxt.stepObject.AddFieldConfig("node", &graphql.Field{
+		Type:        graphql.NewNonNull(cxt.NodeInterface()),
+		Description: "node reached by this step",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			s, ok := p.Source.(*graph.Step)
+			if !ok {
+				return nil, castError("node", p.Source, "*Step")
+			}
+			return s.Node, nil
+		},
+	})
+	cxt.stepObject.AddFieldConfig("depth", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.Int),
+		Description: "number of hops from the starting node",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			s, ok := p.Source.(*graph.Step)
+			if !ok {
+				return nil, castError("depth", p.Source, "*Step")
+			}
+			return s.Depth, nil
+		},
+	})
+	cxt.stepObject.AddFieldConfig("connection", &graphql.Field{
+		Type:        cxt.ConnectionObject(),
+		Description: "connection followed to reach the node (null for the starting node)",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			s, ok := p.Source.(*graph.Step)
+			if !ok {
+				return nil, castError("connection", p.Source, "*Step")
+			}
+			if s.Edge == nil {
+				return nil, nil
+			}
+			return &Connection{
+				Edge:      s.Edge,
+				Direction: s.Direction(),
+			}, nil
+		},
+	})
+	return cxt.stepObject
+}
+
+func traversalArgs(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	args["edges"] = &graphql.ArgumentConfig{
+		Type:        graphql.NewList(graphql.String),
+		Description: "names of edges to follow (default all)",
+	}
+	args["direction"] = &graphql.ArgumentConfig{
+		Type:        graphql.String,
+		Description: "direction of edges to follow In/Out (default both)",
+	}
+	return args
+}
+
+func (cxt *GraphqlContext) PathField() *graphql.Field {
+	return &graphql.Field{
+		Description: "shortest path between two nodes",
+		Type:        graphql.NewList(cxt.StepObject()),
+		Args: traversalArgs(graphql.FieldConfigArgument{
+			"from": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"to": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"maxDepth": &graphql.ArgumentConfig{
+				Type: graphql.Int,
+			},
+		}),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			args := struct {
+				From      string
+				To        string
+				Edges     []string
+				Direction string
+				MaxDepth  int
+			}{}
+			if err := fill(&args, p.Args); err != nil {
+				return nil, err
+			}
+			if args.Direction != "" && !validEdgeDirection.MatchString(args.Direction) {
+				return nil, invalidArg(p.Args, "direction", "must be In or Out")
+			}
+			return cxt.conn.g.ShortestPath(args.From, args.To, graph.Traversal{
+				EdgeNames: args.Edges,
+				Direction: args.Direction,
+				MaxDepth:  args.MaxDepth,
+			}), nil
+		},
+	}
+}
+
+func (cxt *GraphqlContext) NeighbourhoodField() *graphql.Field {
+	return &graphql.Field{
+		Description: "nodes reachable from a node within depth hops",
+		Type:        graphql.NewList(cxt.StepObject()),
+		Args: traversalArgs(graphql.FieldConfigArgument{
+			"id": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"depth": &graphql.ArgumentConfig{
+				Type:         graphql.Int,
+				DefaultValue: 1,
+			},
+		}),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			args := struct {
+				ID        string
+				Edges     []string
+				Direction string
+				Depth     int
+			}{}
+			if err := fill(&args, p.Args); err != nil {
+				return nil, err
+			}
+			if args.Direction != "" && !validEdgeDirection.MatchString(args.Direction) {
+				return nil, invalidArg(p.Args, "direction", "must be In or Out")
+			}
+			if args.Depth < 1 {
+				return nil, invalidArg(p.Args, "depth", "must be at least 1")
+			}
+			steps := []*graph.Step{}
+			cxt.conn.g.BFS(args.ID, graph.Traversal{
+				EdgeNames: args.Edges,
+				Direction: args.Direction,
+				MaxDepth:  args.Depth,
+			}, func(s *graph.Step) bool {
+				if s.Depth > 0 {
+					steps = append(steps, s)
+				}
+				return true
+			})
+			return steps, nil
+		},
+	}
+}
+
+func (cxt *GraphqlContext) SubgraphField() *graphql.Field {
+	return &graphql.Field{
+		Description: "JSON document holding the nodes reachable from the given nodes, the edges between them and the types they need",
+		Type:        graphql.String,
+		Args: traversalArgs(graphql.FieldConfigArgument{
+			"id": &graphql.ArgumentConfig{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.String)),
+				Description: "ids of the nodes to start from",
+			},
+			"maxDepth": &graphql.ArgumentConfig{
+				Type: graphql.Int,
+			},
+		}),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			args := struct {
+				ID        []string
+				Edges     []string
+				Direction string
+				MaxDepth  int
+			}{}
+			if err := fill(&args, p.Args); err != nil {
+				return nil, err
+			}
+			if args.Direction != "" && !validEdgeDirection.MatchString(args.Direction) {
+				return nil, invalidArg(p.Args, "direction", "must be In or Out")
+			}
+			for _, id := range args.ID {
+				if cxt.conn.g.Get(id) == nil {
+					return nil, fmt.Errorf("cannot export subgraph: node '%s' does not exist", id)
+				}
+			}
+			sub := cxt.conn.g.Subgraph(args.ID, graph.Traversal{
+				EdgeNames: args.Edges,
+				Direction: args.Direction,
+				MaxDepth:  args.MaxDepth,
+			})
+			b, err := json.Marshal(sub.Document())
+			if err != nil {
+				return nil, err
+			}
+			return string(b), nil
+		},
+	}
+}
+
+func (cxt *GraphqlContext) ImportMutation() *graphql.Field {
+	return &graphql.Field{
+		Description: "add the contents of a subgraph document, returns the imported nodes in document order",
+		Type:        graphql.NewList(cxt.NodeInterface()),
+		Args: graphql.FieldConfigArgument{
+			"data": &graphql.ArgumentConfig{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "JSON document as returned by the subgraph query",
+			},
+			"keepIDs": &graphql.ArgumentConfig{
+				Type:         graphql.Boolean,
+				DefaultValue: false,
+				Description:  "keep the node ids from the document instead of assigning new ones",
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			args := struct {
+				Data    string
+				KeepIDs bool
+			}{}
+			if err := fill(&args, p.Args); err != nil {
+				return nil, err
+			}
+			doc := &graph.Document{}
+			if err := json.Unmarshal([]byte(args.Data), doc); err != nil {
+				return nil, fmt.Errorf("cannot import subgraph: %s", err)
+			}
+			g := cxt.conn.g
+			// new types get the same checks as setType, types the graph
+			// already has are left as they are
+			checked := g
+			for _, t := range doc.Types {
+				if t == nil {
+					return nil, fmt.Errorf("cannot import subgraph: empty type definition")
+				}
+				if checked.TypeByID(t.ID) != nil {
+					continue
+				}
+				if err := checkType(checked, t); err != nil {
+					return nil, fmt.Errorf("cannot import subgraph: %s", err)
+				}
+				checked = checked.DefineType(*t)
+			}
+			var remap func(id string) string
+			if !args.KeepIDs {
+				// new ids are chosen once and kept in the log so that
+				// replays import the same nodes
+				ids := map[string]string{}
+				err := cxt.conn.resolve(&ids, func() (interface{}, error) {
+					chosen := map[string]string{}
+					for _, dn := range doc.Nodes {
+						id, err := uuid.RandomUUID()
+						if err != nil {
+							return nil, err
+						}
+						chosen[dn.ID] = id.String()
+					}
+					return chosen, nil
+				})
+				if err != nil {
+					return nil, fmt.Errorf("cannot import subgraph: %s", err)
+				}
+				remap = func(id string) string {
+					return ids[id]
+				}
+			}
+			g, err := g.Import(doc, remap)
+			if err != nil {
+				return nil, err
+			}
+			if err := cxt.conn.validate(g); err != nil {
+				return nil, err
+			}
+			cxt.conn.update(g)
+			imported := graph.Nodes{}
+			for _, dn := range doc.Nodes {
+				id := dn.ID
+				if remap != nil {
+					id = remap(id)
+				}
+				imported = append(imported, g.Get(id))
+			}
+			return imported, nil
+		},
+	}
+}
+
+type searchResult struct {
+	*search.Result
+	Node *graph.Node
+}
+
+func (cxt *GraphqlContext) SearchResultObject() *graphql.Object {
+	if cxt.searchResultObject != nil {
+		return cxt.searchResultObject
+	}
+	cxt.searchResultObject = graphql.NewObject(graphql.ObjectConfig{
+		Name: "SearchResult",
+		Fields: graphql.Fields{
+			"node": &graphql.Field{
+				Type:        cxt.NodeInterface(),
+				Description: "the matching node",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					r, ok := p.Source.(*searchResult)
+					if !ok {
+						return nil, castError("node", p.Source, "*searchResult")
+					}
+					return r.Node, nil
+				},
+			},
+			"score": &graphql.Field{
+				Type:        graphql.Float,
+				Description: "relevance of the node to the query, higher is better",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					r, ok := p.Source.(*searchResult)
+					if !ok {
+						return nil, castError("score", p.Source, "*searchResult")
+					}
+					return r.Score, nil
+				},
+			},
+			"field": &graphql.Field{
+				Type:        graphql.String,
+				Description: "name of the field the snippet was taken from",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					r, ok := p.Source.(*searchResult)
+					if !ok {
+						return nil, castError("field", p.Source, "*searchResult")
+					}
+					return r.Field, nil
+				},
+			},
+			"snippet": &graphql.Field{
+				Type:        graphql.String,
+				Description: "html escaped text around the first match with matching words wrapped in <em>",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					r, ok := p.Source.(*searchResult)
+					if !ok {
+						return nil, castError("snippet", p.Source, "*searchResult")
+					}
+					return r.Snippet, nil
+				},
+			},
+		},
+	})
+	return cxt.searchResultObject
+}
+
+func (cxt *GraphqlContext) SearchField() *graphql.Field {
+	return &graphql.Field{
+		Description: "committed nodes whose Text and RichText fields match the query, best matches first",
+		Type:        graphql.NewList(cxt.SearchResultObject()),
+		Args: graphql.FieldConfigArgument{
+			"query": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"types": &graphql.ArgumentConfig{
+				Type:        graphql.NewList(cxt.TypeEnum()),
+				Description: "only return nodes of these types or their subtypes",
+			},
+			"first": &graphql.ArgumentConfig{
+				Type:         graphql.Int,
+				DefaultValue: 10,
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			args := struct {
+				Query string
+				Types []string
+				First int
+			}{}
+			if err := fill(&args, p.Args); err != nil {
+				return nil, err
+			}
+			if args.First < 1 {
+				return nil, invalidArg(p.Args, "first", "must be at least 1")
+			}
+			g := cxt.conn.g
+			ts := []*graph.Type{}
+			for _, name := range args.Types {
+				t := g.TypeByName(name)
+				if t == nil {
+					return nil, fmt.Errorf("'%s' is not a valid type name", name)
+				}
+				ts = append(ts, t)
+			}
+			// the index holds committed nodes so skip any that have
+			// since been removed on this connection
+			keep := func(id string) bool {
+				n := g.Get(id)
+				return n != nil && len(graph.Nodes{n}.FilterType(ts...)) > 0
+			}
+			results := []*searchResult{}
+			for _, r := range cxt.conn.db.Search(args.Query, args.First, keep) {
+				results = append(results, &searchResult{
+					Result: r,
+					Node:   g.Get(r.ID),
+				})
+			}
+			return results, nil
+		},
+	}
+}
+
+func (cxt *GraphqlContext) DisconnectMutation() *graphql.Field {
+	return &graphql.Field{
+		Description: "set node data",
+		Type:        graphql.NewList(cxt.EdgeType()),
+		Args: graphql.FieldConfigArgument{
+			"name": &graphql.ArgumentConfig{
+				Type: graphql.String,
+			},
+			"from": &graphql.ArgumentConfig{
+				Type: graphql.String,
+			},
+			"to": &graphql.ArgumentConfig{
+				Type: graphql.String,
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			match := graph.EdgeMatch{}
+			err := fill(&match, p.Args)
+			if err != nil {
+				return nil, err
+			}
+			g := cxt.conn.g
+			edges := g.Edges(match)
+			g = g.Disconnect(match)
+			cxt.conn.update(g)
+			return edges, nil
+		},
+	}
+}
+func (cxt *GraphqlContext) ConnectMutation() *graphql.Field {
+	return &graphql.Field{
+		Description: "set node data",
+		Type:        cxt.EdgeType(),
+		Args: graphql.FieldConfigArgument{
+			"name": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"from": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"to": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"attrs": &graphql.ArgumentConfig{
+				Type: graphql.NewList(cxt.AttrInputObject()),
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			cfg := graph.EdgeConfig{}
+			err := fill(&cfg, p.Args)
+			if err != nil {
+				return nil, err
+			}
+			for _, attr := range cfg.Attrs {
+				if !validEncType.MatchString(attr.Enc) {
+					return nil, fmt.Errorf("cannot set edge attr: '%s' is not a valid enc type", attr.Enc)
+				}
+				if !validIdent.MatchString(attr.Name) {
+					return nil, fmt.Errorf("cannot set edge attr: '%s' is not a valid attr name", attr.Name)
+				}
+			}
+			g := cxt.conn.g
+			from := g.Get(cfg.From)
+			if from == nil {
+				return nil, fmt.Errorf("from node '%s' did not exist", cfg.From)
+			}
+			to := g.Get(cfg.To)
+			if to == nil {
+				return nil, fmt.Errorf("to node '%s' did not exist", cfg.To)
+			}
+			if cfg.Name == "" {
+				return nil, fmt.Errorf("connection name cannot be blank")
+			}
+			g = g.Link(cfg)
+			if err := cxt.conn.validate(g); err != nil {
+				return nil, err
+			}
+			edge := g.Edges(graph.EdgeMatch{
+				From: cfg.From,
+				To:   cfg.To,
+				Name: cfg.Name,
+			}).First()
+			if edge == nil {
+				return nil, fmt.Errorf("failed to create edge")
+			}
+			cxt.conn.update(g)
+			return edge, nil
+		},
+	}
+}
+func (cxt *GraphqlContext) MoveEdgeMutation() *graphql.Field {
+	return &graphql.Field{
+		Description: "move an edge to a position among the edges with the same name and source node",
+		Type:        graphql.NewList(cxt.EdgeType()),
+		Args: graphql.FieldConfigArgument{
+			"name": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"from": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"to": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"position": &graphql.ArgumentConfig{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "zero based index to move the edge to",
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			args := struct {
+				graph.EdgeMatch
+				Position int
+			}{}
+			if err := fill(&args, p.Args); err != nil {
+				return nil, err
+			}
+			g := cxt.conn.g
+			if g.Edges(args.EdgeMatch).First() == nil {
+				return nil, fmt.Errorf("cannot move edge '%s' from '%s' to '%s': not connected", args.Name, args.From, args.To)
+			}
+			g = g.MoveEdge(args.EdgeMatch, args.Position)
+			cxt.conn.update(g)
+			return g.Edges(graph.EdgeMatch{From: args.From, Name: args.Name}), nil
+		},
+	}
+}
+
+func (cxt *GraphqlContext) ReorderEdgesMutation() *graphql.Field {
+	return &graphql.Field{
+		Description: "arrange the edges with the same name and source node, listed targets come first in the order given followed by any others",
+		Type:        graphql.NewList(cxt.EdgeType()),
+		Args: graphql.FieldConfigArgument{
+			"name": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"from": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"to": &graphql.ArgumentConfig{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.String)),
+				Description: "ids of the target nodes in their new order",
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			args := struct {
+				Name string
+				From string
+				To   []string
+			}{}
+			if err := fill(&args, p.Args); err != nil {
+				return nil, err
+			}
+			g := cxt.conn.g
+			current := g.Edges(graph.EdgeMatch{From: args.From, Name: args.Name})
+			order := graph.Edges{}
+			listed := map[string]bool{}
+			for _, to := range args.To {
+				if listed[to] {
+					return nil, fmt.Errorf("cannot reorder edges: '%s' is listed more than once", to)
+				}
+				listed[to] = true
+				e := g.Edges(graph.EdgeMatch{From: args.From, Name: args.Name, To: to}).First()
+				if e == nil {
+					return nil, fmt.Errorf("cannot reorder edges: '%s' is not connected to '%s' by '%s'", to, args.From, args.Name)
+				}
+				order = append(order, e)
+			}
+			for _, e := range current {
+				if !listed[e.To().ID()] {
+					order = append(order, e)
+				}
+			}
+			g = g.ReorderEdges(order)
+			cxt.conn.update(g)
+			return g.Edges(graph.EdgeMatch{From: args.From, Name: args.Name}), nil
+		},
+	}
+}
+
+func (cxt *GraphqlContext) RevisionObject() *graphql.Object {
+	if cxt.revisionObject != nil {
+		return cxt.revisionObject
+	}
+	revision := func(name string, p graphql.ResolveParams) (*Revision, error) {
+		r, ok := p.Source.(*Revision)
+		if !ok {
+			return nil, castError(name, p.Source, "*Revision")
+		}
+		return r, nil
+	}
+	cxt.revisionObject = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Revision",
+		Fields: graphql.Fields{
+			"timestamp": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "time of the mutation that made the revision",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					r, err := revision("timestamp", p)
+					if err != nil {
+						return nil, err
+					}
+					return r.Timestamp.Format(time.RFC3339Nano), nil
+				},
+			},
+			"uid": &graphql.Field{
+				Type:        graphql.String,
+				Description: "uid of the user that made the mutation",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					r, err := revision("uid", p)
+					if err != nil {
+						return nil, err
+					}
+					return r.UID(), nil
+				},
+			},
+			"removed": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Boolean),
+				Description: "true if the mutation removed the node",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					r, err := revision("removed", p)
+					if err != nil {
+						return nil, err
+					}
+					return r.Node() == nil, nil
+				},
+			},
+			"attrs": &graphql.Field{
+				Type:        graphql.NewList(cxt.AttrObject()),
+				Description: "attrs of the node after the mutation",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					r, err := revision("attrs", p)
+					if err != nil {
+						return nil, err
+					}
+					if n := r.Node(); n != nil {
+						return n.Attrs(), nil
+					}
+					return nil, nil
+				},
+			},
+			"edges": &graphql.Field{
+				Type:        graphql.NewList(cxt.EdgeType()),
+				Description: "inbound and outbound edges of the node after the mutation",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					r, err := revision("edges", p)
+					if err != nil {
+						return nil, err
+					}
+					if n := r.Node(); n != nil {
+						return n.Edges(nil, ""), nil
+					}
+					return nil, nil
+				},
+			},
 		},
-		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-			match := graph.EdgeMatch{}
-			err := fill(&match, p.Args)
-			if err != nil {
-				return nil, err
-			}
-			g := cxt.conn.g
-			edges := g.Edges(match)
-			g = g.Disconnect(match)
-			cxt.conn.update(g)
-			return edges, nil
-		},
+	})
+	return cxt.revisionObject
+}
+
+// restoredNode is the part of a revision that restoreNode puts back.
+// Values are kept in string form so they survive being logged.
+type restoredNode struct {
+	Type  string          `json:"type"`
+	Attrs []*graph.Attr   `json:"attrs"`
+	Edges []*restoredEdge `json:"edges"`
+}
+
+type restoredEdge struct {
+	From  string        `json:"from"`
+	Name  string        `json:"name"`
+	To    string        `json:"to"`
+	Attrs []*graph.Attr `json:"attrs"`
+}
+
+func newRestoredNode(n *graph.Node) (*restoredNode, error) {
+	if n == nil {
+		return nil, fmt.Errorf("it was removed")
+	}
+	t := n.Type()
+	if t == nil {
+		return nil, fmt.Errorf("its type was not defined")
+	}
+	formatAttrs := func(attrs []*graph.Attr) []*graph.Attr {
+		formatted := []*graph.Attr{}
+		for _, attr := range attrs {
+			formatted = append(formatted, &graph.Attr{Name: attr.Name, Value: attr.String(), Enc: attr.Enc})
+		}
+		return formatted
 	}
+	rn := &restoredNode{Type: t.ID, Attrs: formatAttrs(n.Attrs()), Edges: []*restoredEdge{}}
+	for _, e := range n.Edges(nil, "") {
+		rn.Edges = append(rn.Edges, &restoredEdge{
+			From:  e.From().ID(),
+			Name:  e.Name(),
+			To:    e.To().ID(),
+			Attrs: formatAttrs(e.Attrs()),
+		})
+	}
+	return rn, nil
 }
-func (cxt *GraphqlContext) ConnectMutation() *graphql.Field {
+
+func (cxt *GraphqlContext) RestoreNodeMutation() *graphql.Field {
 	return &graphql.Field{
-		Description: "set node data",
-		Type:        cxt.EdgeType(),
+		Description: "put a node's attrs and edges back to how they were at a point in its history",
+		Type:        cxt.NodeInterface(),
 		Args: graphql.FieldConfigArgument{
-			"name": &graphql.ArgumentConfig{
-				Type: graphql.NewNonNull(graphql.String),
-			},
-			"from": &graphql.ArgumentConfig{
+			"id": &graphql.ArgumentConfig{
 				Type: graphql.NewNonNull(graphql.String),
 			},
-			"to": &graphql.ArgumentConfig{
-				Type: graphql.NewNonNull(graphql.String),
+			"at": &graphql.ArgumentConfig{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "RFC3339 time, the last revision made at or before it is restored",
 			},
 		},
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-			cfg := graph.EdgeConfig{}
-			err := fill(&cfg, p.Args)
-			if err != nil {
+			args := struct {
+				ID string
+				At string
+			}{}
+			if err := fill(&args, p.Args); err != nil {
 				return nil, err
 			}
+			at, err := time.Parse(time.RFC3339Nano, args.At)
+			if err != nil {
+				return nil, invalidArg(p.Args, "at", "must be an RFC3339 time")
+			}
+			// the revision is read once and recorded so that replaying
+			// the mutation does not depend on the history at that time
+			old := restoredNode{}
+			err = cxt.conn.resolve(&old, func() (interface{}, error) {
+				rev, err := cxt.conn.db.RevisionAt(args.ID, at)
+				if err != nil {
+					return nil, err
+				}
+				return newRestoredNode(rev.Node())
+			})
+			if err != nil {
+				return nil, fmt.Errorf("cannot restore node '%s': %s", args.ID, err)
+			}
 			g := cxt.conn.g
-			from := g.Get(cfg.From)
-			if from == nil {
-				return nil, fmt.Errorf("from node '%s' did not exist", cfg.From)
+			t := g.TypeByID(old.Type)
+			if t == nil || t.Abstract {
+				return nil, fmt.Errorf("cannot restore node '%s': type '%s' is no longer defined", args.ID, old.Type)
 			}
-			to := g.Get(cfg.To)
-			if to == nil {
-				return nil, fmt.Errorf("to node '%s' did not exist", cfg.To)
+			// attrs for fields that have since been dropped are left
+			// out and the rest are converted to the current field types
+			attrs := []*graph.Attr{}
+			for _, attr := range old.Attrs {
+				f := t.Field(attr.Name)
+				if f == nil {
+					continue
+				}
+				v, err := f.Coerce(attr.Value, attr.Enc)
+				if err != nil {
+					return nil, fmt.Errorf("cannot restore field '%s': %s", attr.Name, err)
+				}
+				attrs = append(attrs, &graph.Attr{Name: attr.Name, Value: v, Enc: attr.Enc})
 			}
-			if cfg.Name == "" {
-				return nil, fmt.Errorf("connection name cannot be blank")
+			g = g.Set(graph.NodeConfig{
+				ID:    args.ID,
+				Type:  t,
+				Attrs: attrs,
+			})
+			// edges that still exist keep their rank, others are
+			// removed or reconnected if the other node still exists
+			key := func(from, name, to string) string {
+				return from + "\x00" + name + "\x00" + to
 			}
-			g = g.Connect(cfg)
-			edge := g.Edges(graph.EdgeMatch{
-				From: cfg.From,
-				To:   cfg.To,
-				Name: cfg.Name,
-			}).First()
-			if edge == nil {
-				return nil, fmt.Errorf("failed to create edge")
+			wanted := map[string]bool{}
+			for _, e := range old.Edges {
+				wanted[key(e.From, e.Name, e.To)] = true
+			}
+			existing := map[string]bool{}
+			for _, e := range g.Get(args.ID).Edges(nil, "") {
+				k := key(e.From().ID(), e.Name(), e.To().ID())
+				existing[k] = true
+				if !wanted[k] {
+					g = g.Disconnect(graph.EdgeMatch{From: e.From().ID(), Name: e.Name(), To: e.To().ID()})
+				}
+			}
+			for _, e := range old.Edges {
+				if existing[key(e.From, e.Name, e.To)] || g.Get(e.From) == nil || g.Get(e.To) == nil {
+					continue
+				}
+				g = g.Connect(graph.EdgeConfig{Name: e.Name, From: e.From, To: e.To, Attrs: e.Attrs})
+			}
+			if err := cxt.conn.validate(g); err != nil {
+				return nil, err
 			}
 			cxt.conn.update(g)
-			return edge, nil
+			return g.Get(args.ID), nil
 		},
 	}
 }
+
+func (cxt *GraphqlContext) RemovalObject() *graphql.Object {
+	if cxt.removalObject != nil {
+		return cxt.removalObject
+	}
+	cxt.removalObject = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Removal",
+		Fields: graphql.Fields{
+			"node": &graphql.Field{
+				Type:        cxt.NodeInterface(),
+				Description: "the removed node",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					removed, ok := p.Source.(graph.Nodes)
+					if !ok {
+						return nil, castError("node", p.Source, "Nodes")
+					}
+					return removed.First(), nil
+				},
+			},
+			"cascaded": &graphql.Field{
+				Type:        graphql.NewList(cxt.NodeInterface()),
+				Description: "other nodes removed by Cascade onDelete policies",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					removed, ok := p.Source.(graph.Nodes)
+					if !ok {
+						return nil, castError("cascaded", p.Source, "Nodes")
+					}
+					return removed[1:], nil
+				},
+			},
+		},
+	})
+	return cxt.removalObject
+}
+
 func (cxt *GraphqlContext) RemoveMutation() *graphql.Field {
 	return &graphql.Field{
 		Description: "delete a node",
@@ -1251,23 +3358,51 @@ func (cxt *GraphqlContext) RemoveMutation() *graphql.Field {
 			},
 		},
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-			cfg := graph.NodeConfig{}
-			err := fill(&cfg, p.Args)
+			removed, err := cxt.removeNode(p)
 			if err != nil {
 				return nil, err
 			}
-			g := cxt.conn.g
-			n := g.Get(cfg.ID)
-			if n == nil {
-				return nil, fmt.Errorf("node already removed")
-			}
-			g = g.Remove(cfg.ID)
-			cxt.conn.update(g)
-			return n, nil
+			return removed.First(), nil
+		},
+	}
+}
+
+func (cxt *GraphqlContext) RemoveWithReportMutation() *graphql.Field {
+	return &graphql.Field{
+		Description: "delete a node and report any other nodes removed by Cascade onDelete policies",
+		Type:        cxt.RemovalObject(),
+		Args: graphql.FieldConfigArgument{
+			"id": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return cxt.removeNode(p)
 		},
 	}
 }
 
+// removeNode deletes the node with the id arg and returns it followed
+// by any nodes removed by cascades
+func (cxt *GraphqlContext) removeNode(p graphql.ResolveParams) (graph.Nodes, error) {
+	cfg := graph.NodeConfig{}
+	err := fill(&cfg, p.Args)
+	if err != nil {
+		return nil, err
+	}
+	g := cxt.conn.g
+	n := g.Get(cfg.ID)
+	if n == nil {
+		return nil, fmt.Errorf("node already removed")
+	}
+	g, removed, err := g.Delete(cfg.ID)
+	if err != nil {
+		return nil, err
+	}
+	cxt.conn.update(g)
+	return removed, nil
+}
+
 func (cxt *GraphqlContext) AttrInputObject() *graphql.InputObject {
 	if cxt.attrInputObject != nil {
 		return cxt.attrInputObject
@@ -1309,14 +3444,19 @@ func (cxt *GraphqlContext) SetNodeMutation() *graphql.Field {
 			"merge": &graphql.ArgumentConfig{
 				Type: graphql.Boolean,
 			},
+			"removeAttrs": &graphql.ArgumentConfig{
+				Type:        graphql.NewList(graphql.String),
+				Description: "names of attrs to remove when merging",
+			},
 		},
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 			cfg := struct {
-				ID     string        `json:"id"`
-				Type   string        `json:"type"`
-				TypeID string        `json:"typeID"`
-				Attrs  []*graph.Attr `json:"attrs"`
-				Merge  bool          `json:"merge"`
+				ID          string        `json:"id"`
+				Type        string        `json:"type"`
+				TypeID      string        `json:"typeID"`
+				Attrs       []*graph.Attr `json:"attrs"`
+				Merge       bool          `json:"merge"`
+				RemoveAttrs []string      `json:"removeAttrs"`
 			}{}
 			err := fill(&cfg, p.Args)
 			if err != nil {
@@ -1343,32 +3483,61 @@ func (cxt *GraphqlContext) SetNodeMutation() *graphql.Field {
 			} else {
 				return nil, fmt.Errorf("type or typeID is required")
 			}
-			getField := func(name string) *graph.Field {
-				for _, field := range t.Fields {
-					if field.Name == name {
-						return field
-					}
-				}
-				return nil
+			if t.Abstract {
+				return nil, fmt.Errorf("cannot set node: type '%s' is abstract", t.Name)
 			}
+			attrs := []*graph.Attr{}
 			for _, attr := range cfg.Attrs {
-				if !validEncType.MatchString(attr.Enc) {
+				if !validEncType.MatchString(attr.Enc) && attr.Enc != "CSV" {
 					return nil, fmt.Errorf("cannot set field: '%s' is not a valid field enc type", attr.Enc)
 				}
 				if !validIdent.MatchString(attr.Name) {
 					return nil, fmt.Errorf("cannot set field: '%s' is not a valid field name", attr.Name)
 				}
-				f := getField(attr.Name)
+				f := t.Field(attr.Name)
 				if f == nil {
 					return nil, fmt.Errorf("cannot set field: type '%s' does not define a field called '%s'", t.Name, attr.Name)
 				}
+				if attr.Enc == "CSV" && f.Type != DataTable {
+					return nil, fmt.Errorf("cannot set field '%s': only DataTable fields accept CSV", attr.Name)
+				}
+				v, err := f.Coerce(attr.Value, attr.Enc)
+				if err != nil {
+					// logs written before values were typed may hold
+					// strings that no longer coerce, leave them out of
+					// the graph and report them instead
+					if cxt.conn.trusted {
+						cxt.conn.db.reportFailure(&graph.MigrationFailure{
+							NodeID: cfg.ID,
+							Field:  attr.Name,
+							Value:  attr.Value,
+							Reason: err.Error(),
+						})
+						continue
+					}
+					return nil, fmt.Errorf("cannot set field '%s': %s", attr.Name, err)
+				}
+				attr.Value = v
+				if f.Type == DataTable {
+					// CSV is only an input format, rows are stored as JSON
+					attr.Enc = "JSON"
+				}
+				attrs = append(attrs, attr)
+			}
+			cfg.Attrs = attrs
+			if g.Get(cfg.ID) == nil {
+				cfg.Attrs = withDefaults(t, cfg.Attrs)
 			}
 			g = g.Set(graph.NodeConfig{
-				ID:    cfg.ID,
-				Type:  t,
-				Attrs: cfg.Attrs,
-				Merge: cfg.Merge,
+				ID:          cfg.ID,
+				Type:        t,
+				Attrs:       cfg.Attrs,
+				Merge:       cfg.Merge,
+				RemoveAttrs: cfg.RemoveAttrs,
 			})
+			if err := cxt.conn.validate(g); err != nil {
+				return nil, err
+			}
 			n := g.Get(cfg.ID)
 			if n == nil {
 				return nil, fmt.Errorf("failed to create node")
@@ -1378,6 +3547,28 @@ func (cxt *GraphqlContext) SetNodeMutation() *graphql.Field {
 		},
 	}
 }
+
+// withDefaults adds an attr holding the default of each field of t
+// that is missing from attrs
+func withDefaults(t *graph.Type, attrs []*graph.Attr) []*graph.Attr {
+	given := map[string]bool{}
+	for _, attr := range attrs {
+		given[attr.Name] = true
+	}
+	for _, f := range t.AllFields() {
+		v := f.DefaultValue()
+		if v == nil || given[f.Name] {
+			continue
+		}
+		enc := "UTF8"
+		if f.Type == DataTable {
+			enc = "JSON"
+		}
+		attrs = append(attrs, &graph.Attr{Name: f.Name, Value: v, Enc: enc})
+	}
+	return attrs
+}
+
 func (cxt *GraphqlContext) TokenObject() *graphql.Object {
 	if cxt.tokenObject != nil {
 		return cxt.tokenObject
@@ -1540,6 +3731,229 @@ func (cxt *GraphqlContext) GetMutations() *graphql.Field {
 
 }
 
+func (cxt *GraphqlContext) ChangesetObject() *graphql.Object {
+	if cxt.changesetObject != nil {
+		return cxt.changesetObject
+	}
+	changeKindEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name:        "ChangeKindEnum",
+		Description: "how something differs from the committed graph",
+		Values: graphql.EnumValueConfigMap{
+			graph.Added: &graphql.EnumValueConfig{
+				Description: "does not exist in the committed graph",
+			},
+			graph.Removed: &graphql.EnumValueConfig{
+				Description: "will be removed from the committed graph",
+			},
+			graph.Modified: &graphql.EnumValueConfig{
+				Description: "exists in the committed graph with different values",
+			},
+		},
+	})
+	attrChangeObject := graphql.NewObject(graphql.ObjectConfig{
+		Name: "AttrChange",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "attr name",
+			},
+			"kind": &graphql.Field{
+				Type:        graphql.NewNonNull(changeKindEnum),
+				Description: "kind of change",
+			},
+			"old": &graphql.Field{
+				Type:        cxt.AttrObject(),
+				Description: "committed attr",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					c, ok := p.Source.(*graph.AttrChange)
+					if !ok {
+						return nil, castError("old", p.Source, "*AttrChange")
+					}
+					if c.Old == nil {
+						return nil, nil
+					}
+					return c.Old, nil
+				},
+			},
+			"new": &graphql.Field{
+				Type:        cxt.AttrObject(),
+				Description: "pending attr",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					c, ok := p.Source.(*graph.AttrChange)
+					if !ok {
+						return nil, castError("new", p.Source, "*AttrChange")
+					}
+					if c.New == nil {
+						return nil, nil
+					}
+					return c.New, nil
+				},
+			},
+		},
+	})
+	nodeChangeObject := graphql.NewObject(graphql.ObjectConfig{
+		Name: "NodeChange",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.ID),
+				Description: "id of changed node",
+			},
+			"kind": &graphql.Field{
+				Type:        graphql.NewNonNull(changeKindEnum),
+				Description: "kind of change",
+			},
+			"old": &graphql.Field{
+				Type:        cxt.NodeInterface(),
+				Description: "committed node",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					c, ok := p.Source.(*graph.NodeChange)
+					if !ok {
+						return nil, castError("old", p.Source, "*NodeChange")
+					}
+					if c.Old == nil {
+						return nil, nil
+					}
+					return c.Old, nil
+				},
+			},
+			"new": &graphql.Field{
+				Type:        cxt.NodeInterface(),
+				Description: "pending node",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					c, ok := p.Source.(*graph.NodeChange)
+					if !ok {
+						return nil, castError("new", p.Source, "*NodeChange")
+					}
+					if c.New == nil {
+						return nil, nil
+					}
+					return c.New, nil
+				},
+			},
+			"attrs": &graphql.Field{
+				Type:        graphql.NewList(attrChangeObject),
+				Description: "changed attrs",
+			},
+		},
+	})
+	edgeChangeObject := graphql.NewObject(graphql.ObjectConfig{
+		Name: "EdgeChange",
+		Fields: graphql.Fields{
+			"kind": &graphql.Field{
+				Type:        graphql.NewNonNull(changeKindEnum),
+				Description: "kind of change",
+			},
+			"old": &graphql.Field{
+				Type:        cxt.EdgeType(),
+				Description: "committed edge",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					c, ok := p.Source.(*graph.EdgeChange)
+					if !ok {
+						return nil, castError("old", p.Source, "*EdgeChange")
+					}
+					if c.Old == nil {
+						return nil, nil
+					}
+					return c.Old, nil
+				},
+			},
+			"new": &graphql.Field{
+				Type:        cxt.EdgeType(),
+				Description: "pending edge",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					c, ok := p.Source.(*graph.EdgeChange)
+					if !ok {
+						return nil, castError("new", p.Source, "*EdgeChange")
+					}
+					if c.New == nil {
+						return nil, nil
+					}
+					return c.New, nil
+				},
+			},
+		},
+	})
+	typeChangeObject := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TypeChange",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "id of changed type",
+			},
+			"kind": &graphql.Field{
+				Type:        graphql.NewNonNull(changeKindEnum),
+				Description: "kind of change",
+			},
+			"old": &graphql.Field{
+				Type:        cxt.TypeObject(),
+				Description: "committed type",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					c, ok := p.Source.(*graph.TypeChange)
+					if !ok {
+						return nil, castError("old", p.Source, "*TypeChange")
+					}
+					if c.Old == nil {
+						return nil, nil
+					}
+					return c.Old, nil
+				},
+			},
+			"new": &graphql.Field{
+				Type:        cxt.TypeObject(),
+				Description: "pending type",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					c, ok := p.Source.(*graph.TypeChange)
+					if !ok {
+						return nil, castError("new", p.Source, "*TypeChange")
+					}
+					if c.New == nil {
+						return nil, nil
+					}
+					return c.New, nil
+				},
+			},
+		},
+	})
+	cxt.changesetObject = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Changeset",
+		Fields: graphql.Fields{
+			"nodes": &graphql.Field{
+				Type:        graphql.NewList(nodeChangeObject),
+				Description: "added, removed and modified nodes",
+			},
+			"edges": &graphql.Field{
+				Type:        graphql.NewList(edgeChangeObject),
+				Description: "connected, disconnected and modified edges",
+			},
+			"types": &graphql.Field{
+				Type:        graphql.NewList(typeChangeObject),
+				Description: "defined and redefined types",
+			},
+		},
+	})
+	return cxt.changesetObject
+}
+
+func (cxt *GraphqlContext) GetMigrationFailures() *graphql.Field {
+	return &graphql.Field{
+		Description: "values in the log that could not be converted to the type of their field when replayed and were left out",
+		Type:        graphql.NewList(cxt.MigrationFailureObject()),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return cxt.conn.db.MigrationFailures(), nil
+		},
+	}
+}
+
+func (cxt *GraphqlContext) GetPendingChanges() *graphql.Field {
+	return &graphql.Field{
+		Description: "changes made on this connection that have not been committed",
+		Type:        cxt.ChangesetObject(),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return cxt.conn.PendingChanges(), nil
+		},
+	}
+}
+
 func (cxt *GraphqlContext) AddQuery(name string, field *graphql.Field) {
 	cxt.fields[name] = field
 }
@@ -1561,7 +3975,12 @@ func (cxt *GraphqlContext) schema() (*graphql.Schema, error) {
 		Types: []graphql.Type{},
 	}
 	for _, t := range cxt.conn.g.Types() {
-		cfg.Types = append(cfg.Types, cxt.NodeType(t))
+		if cxt.hasInterface(t) {
+			cfg.Types = append(cfg.Types, cxt.TypeInterface(t))
+		}
+		if !t.Abstract {
+			cfg.Types = append(cfg.Types, cxt.NodeType(t))
+		}
 	}
 	s, err := graphql.NewSchema(cfg)
 	if err != nil {
@@ -1577,14 +3996,29 @@ func (cxt *GraphqlContext) Schema() (*graphql.Schema, error) {
 	cxt.AddQuery("node", cxt.NodeField(nil))
 	cxt.AddQuery("nodes", cxt.NodeListField(nil))
 	cxt.AddQuery("edges", cxt.GetEdges())
+	cxt.AddQuery("path", cxt.PathField())
+	cxt.AddQuery("neighbourhood", cxt.NeighbourhoodField())
+	cxt.AddQuery("subgraph", cxt.SubgraphField())
+	cxt.AddQuery("search", cxt.SearchField())
+	cxt.AddQuery("nearby", cxt.NearbyField())
 	cxt.AddQuery("type", cxt.GetType())
 	cxt.AddQuery("types", cxt.GetTypes())
 	cxt.AddQuery("mutations", cxt.GetMutations())
 	cxt.AddQuery("tokens", cxt.GetTokens())
+	cxt.AddQuery("pendingChanges", cxt.GetPendingChanges())
+	cxt.AddQuery("migrationFailures", cxt.GetMigrationFailures())
 	cxt.AddMutation("setType", cxt.SetTypeMutation())
+	cxt.AddMutation("renameField", cxt.RenameFieldMutation())
+	cxt.AddMutation("changeFieldType", cxt.ChangeFieldTypeMutation())
+	cxt.AddMutation("dropField", cxt.DropFieldMutation())
 	cxt.AddMutation("setNode", cxt.SetNodeMutation())
 	cxt.AddMutation("removeNodes", cxt.RemoveMutation())
+	cxt.AddMutation("removeNodeWithReport", cxt.RemoveWithReportMutation())
+	cxt.AddMutation("restoreNode", cxt.RestoreNodeMutation())
 	cxt.AddMutation("setEdge", cxt.ConnectMutation())
 	cxt.AddMutation("removeEdges", cxt.DisconnectMutation())
+	cxt.AddMutation("moveEdge", cxt.MoveEdgeMutation())
+	cxt.AddMutation("reorderEdges", cxt.ReorderEdgesMutation())
+	cxt.AddMutation("importSubgraph", cxt.ImportMutation())
 	return cxt.schema()
 }
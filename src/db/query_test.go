@@ -0,0 +1,466 @@
+package db
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"graph"
+	"testutil"
+)
+
+func TestRemoveCascade(t *testing.T) {
+	expect := testutil.Expect(t)
+	db, done := openTestDB(t)
+	defer done()
+	c := connect(t, db)
+	exec(t, c, `mutation{setType(id:"comment",name:"Comment",fields:[{name:"text",type:"Text"}]){id}}`)
+	exec(t, c, `mutation{setType(id:"post",name:"Post",fields:[
+		{name:"comments",type:"Edge",edgeName:"comment",edgeDirection:"Out",onDelete:"Cascade"}
+	]){id}}`)
+	exec(t, c, `mutation{setType(id:"user",name:"User",fields:[
+		{name:"posts",type:"Edge",edgeName:"post",edgeDirection:"Out",onDelete:"Restrict"}
+	]){id}}`)
+	for _, n := range [][2]string{{"p1", "Post"}, {"p2", "Post"}, {"c1", "Comment"}, {"c2", "Comment"}, {"u1", "User"}} {
+		exec(t, c, `mutation{setNode(id:"`+n[0]+`",type:"`+n[1]+`"){id}}`)
+	}
+	exec(t, c, `mutation{setEdge(from:"p1",to:"c1",name:"comment"){name}}`)
+	exec(t, c, `mutation{setEdge(from:"p1",to:"c2",name:"comment"){name}}`)
+	exec(t, c, `mutation{setEdge(from:"u1",to:"p2",name:"post"){name}}`)
+
+	expect(exec(t, c, `mutation{removeNodeWithReport(id:"p1"){node{id} cascaded{id}}}`)).ToEqual(
+		`{"removeNodeWithReport":{"cascaded":[{"id":"c1"},{"id":"c2"}],"node":{"id":"p1"}}}`)
+	expect(query(t, c, `{nodes(type:[Comment]){id}}`)).ToEqual(`{"nodes":[]}`)
+	// removeNodes returns only the removed node
+	expect(execErr(t, c, `mutation{removeNodes(id:"u1"){id}}`)).ToNotBeNil()
+	expect(exec(t, c, `mutation{removeNodes(id:"p2"){id}}`)).ToEqual(`{"removeNodes":{"id":"p2"}}`)
+	expect(exec(t, c, `mutation{removeNodes(id:"u1"){id}}`)).ToEqual(`{"removeNodes":{"id":"u1"}}`)
+}
+
+func TestSetNodeCoercion(t *testing.T) {
+	expect := testutil.Expect(t)
+	db, done := openTestDB(t)
+	defer done()
+	c := connect(t, db)
+	exec(t, c, `mutation{setType(id:"item",name:"Item",fields:[
+		{name:"qty",type:"Int"},{name:"price",type:"Float"},{name:"sold",type:"Boolean"}
+	]){id}}`)
+	expect(exec(t, c, `mutation{setNode(id:"a",type:"Item",attrs:[
+		{name:"qty",value:"12",enc:"UTF8"},{name:"price",value:"1.5",enc:"UTF8"},{name:"sold",value:"true",enc:"UTF8"}
+	]){... on Item{qty price sold}}}`)).ToEqual(`{"setNode":{"price":1.5,"qty":12,"sold":true}}`)
+	expect(execErr(t, c, `mutation{setNode(id:"b",type:"Item",attrs:[{name:"qty",value:"many",enc:"UTF8"}]){id}}`).Error()).ToEqual(
+		`cannot set field 'qty': 'many' is not a valid Int`)
+	commit(t, c)
+	db = reopen(t, db)
+	c = connect(t, db)
+	expect(query(t, c, `{node(id:"a"){... on Item{qty price sold}}}`)).ToEqual(`{"node":{"price":1.5,"qty":12,"sold":true}}`)
+}
+
+func TestReplayUntypedValues(t *testing.T) {
+	expect := testutil.Expect(t)
+	db, done := openTestDB(t)
+	defer done()
+	// a log written before values were typed
+	log := `{"q":"mutation{setType(id:\"item\",name:\"Item\",fields:[{name:\"qty\",type:\"Int\"},{name:\"name\",type:\"Text\"}]){id}}"}
+{"q":"mutation{setNode(id:\"a\",type:\"Item\",attrs:[{name:\"qty\",value:\"lots\",enc:\"UTF8\"},{name:\"name\",value:\"A\",enc:\"UTF8\"}]){id}}"}
+`
+	if err := ioutil.WriteFile(db.cfg.Path, []byte(log), 0600); err != nil {
+		t.Fatal(err)
+	}
+	db = reopen(t, db)
+	c := connect(t, db)
+	expect(query(t, c, `{node(id:"a"){attrs{name value}}}`)).ToEqual(`{"node":{"attrs":[{"name":"name","value":"A"}]}}`)
+	expect(query(t, c, `{migrationFailures{nodeID field value reason}}`)).ToEqual(
+		`{"migrationFailures":[{"field":"qty","nodeID":"a","reason":"'lots' is not a valid Int","value":"lots"}]}`)
+}
+
+func TestChangeFieldTypeMutation(t *testing.T) {
+	expect := testutil.Expect(t)
+	db, done := openTestDB(t)
+	defer done()
+	c := connect(t, db)
+	exec(t, c, `mutation{setType(id:"shirt",name:"Shirt",fields:[{name:"size",type:"Text"},{name:"sizes",type:"Text"}]){id}}`)
+	exec(t, c, `mutation{setNode(id:"a",type:"Shirt",attrs:[
+		{name:"size",value:"M",enc:"UTF8"},{name:"sizes",value:"size,chest\nS,90\nM,100",enc:"UTF8"}
+	]){id}}`)
+	expect(execErr(t, c, `mutation{changeFieldType(typeID:"shirt",name:"size",type:"Enum"){failures{nodeID}}}`).Error()).ToEqual(
+		`cannot change type of field 'size': enumValues are required`)
+	expect(exec(t, c, `mutation{changeFieldType(typeID:"shirt",name:"size",type:"Enum",enumValues:["S","M","L"]){
+		type{fields{name enumValues}} failures{nodeID}
+	}}`)).ToEqual(`{"changeFieldType":{"failures":[],"type":{"fields":[{"enumValues":["S","M","L"],"name":"size"},{"enumValues":null,"name":"sizes"}]}}}`)
+	expect(exec(t, c, `mutation{changeFieldType(typeID:"shirt",name:"sizes",type:"DataTable",dataColumns:[
+		{name:"size",type:"Text"},{name:"chest",type:"Int",unit:"cm"}
+	]){failures{nodeID}}}`)).ToEqual(`{"changeFieldType":{"failures":[]}}`)
+	commit(t, c)
+	db = reopen(t, db)
+	c = connect(t, db)
+	expect(query(t, c, `{node(id:"a"){... on Shirt{size sizes{columns{name unit} rows}}}}`)).ToEqual(
+		`{"node":{"size":"M","sizes":{"columns":[{"name":"size","unit":null},{"name":"chest","unit":"cm"}],"rows":[["S","90"],["M","100"]]}}}`)
+}
+
+func TestSetTypeUnique(t *testing.T) {
+	expect := testutil.Expect(t)
+	db, done := openTestDB(t)
+	defer done()
+	c := connect(t, db)
+	exec(t, c, `mutation{setType(id:"product",name:"Product",fields:[{name:"sku",type:"Text"}]){id}}`)
+	exec(t, c, `mutation{setNode(id:"a",type:"Product",attrs:[{name:"sku",value:"A1",enc:"UTF8"}]){id}}`)
+	exec(t, c, `mutation{setNode(id:"b",type:"Product",attrs:[{name:"sku",value:"A1",enc:"UTF8"}]){id}}`)
+	expect(execErr(t, c, `mutation{setType(id:"product",name:"Product",fields:[{name:"sku",type:"Text",unique:true}]){id}}`).Error()).ToEqual(
+		`cannot define type 'Product': validation failed: node 'a' field 'sku': value 'A1' is already used by node 'b'; node 'b' field 'sku': value 'A1' is already used by node 'a'`)
+	exec(t, c, `mutation{setNode(id:"b",type:"Product",attrs:[{name:"sku",value:"B1",enc:"UTF8"}]){id}}`)
+	exec(t, c, `mutation{setType(id:"product",name:"Product",fields:[{name:"sku",type:"Text",unique:true}]){id}}`)
+	expect(execErr(t, c, `mutation{setNode(id:"b",type:"Product",attrs:[{name:"sku",value:"A1",enc:"UTF8"}]){id}}`)).ToNotBeNil()
+	commit(t, c)
+}
+
+func TestRestoreNode(t *testing.T) {
+	expect := testutil.Expect(t)
+	db, done := openTestDB(t)
+	defer done()
+	c := connect(t, db)
+	exec(t, c, userType)
+	exec(t, c, `mutation{setNode(id:"alice",type:"User",attrs:[{name:"username",value:"alice1",enc:"UTF8"}]){id}}`)
+	exec(t, c, `mutation{setNode(id:"bob",type:"User"){id}}`)
+	exec(t, c, `mutation{setEdge(from:"alice",to:"bob",name:"friend"){name}}`)
+	commit(t, c)
+	history := db.History("alice")
+	at := history[len(history)-1].Timestamp.Format(time.RFC3339Nano)
+	exec(t, c, `mutation{setNode(id:"alice",type:"User",attrs:[{name:"username",value:"alice2",enc:"UTF8"}]){id}}`)
+	exec(t, c, `mutation{removeEdges(from:"alice",to:"bob",name:"friend"){name}}`)
+	commit(t, c)
+	expect(exec(t, c, `mutation{restoreNode(id:"alice",at:"`+at+`"){... on User{username friends{node{id}}}}}`)).ToEqual(
+		`{"restoreNode":{"friends":[{"node":{"id":"bob"}}],"username":"alice1"}}`)
+	expect(execErr(t, c, `mutation{restoreNode(id:"alice",at:"2000-01-01T00:00:00Z"){id}}`)).ToNotBeNil()
+	commit(t, c)
+	// replay uses the revision recorded in the log
+	b, err := ioutil.ReadFile(db.cfg.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(strings.Contains(string(b), `"r":[{"type":"u","attrs":[{"name":"username","value":"alice1","enc":"UTF8"}]`)).ToEqual(true)
+	db = reopen(t, db)
+	c = connect(t, db)
+	expect(query(t, c, `{node(id:"alice"){... on User{username friends{node{id}}}}}`)).ToEqual(
+		`{"node":{"friends":[{"node":{"id":"bob"}}],"username":"alice1"}}`)
+}
+
+func TestWhereDefault(t *testing.T) {
+	expect := testutil.Expect(t)
+	db, done := openTestDB(t)
+	defer done()
+	c := connect(t, db)
+	exec(t, c, `mutation{setType(id:"task",name:"Task",fields:[
+		{name:"status",type:"Text",indexed:true,default:"open"},{name:"priority",type:"Int",default:"2"}
+	]){id}}`)
+	exec(t, c, `mutation{setNode(id:"a",type:"Task"){id}}`)
+	exec(t, c, `mutation{setNode(id:"b",type:"Task",attrs:[
+		{name:"status",value:"done",enc:"UTF8"},{name:"priority",value:"1",enc:"UTF8"}
+	]){id}}`)
+	expect(query(t, c, `{nodes(type:[Task],where:[{field:"status",value:"open"}]){id}}`)).ToEqual(`{"nodes":[{"id":"a"}]}`)
+	expect(query(t, c, `{nodes(type:[Task],where:[{field:"priority",op:"gte",value:"2"}]){id}}`)).ToEqual(`{"nodes":[{"id":"a"}]}`)
+	expect(query(t, c, `{nodes(type:[Task],sort:[priority],desc:true){id}}`)).ToEqual(`{"nodes":[{"id":"a"},{"id":"b"}]}`)
+}
+
+func TestEnumNames(t *testing.T) {
+	expect := testutil.Expect(t)
+	db, done := openTestDB(t)
+	defer done()
+	c := connect(t, db)
+	exec(t, c, `mutation{setType(id:"size",name:"SizeEnum",fields:[{name:"label",type:"Text"}]){id}}`)
+	// enum names do not reuse the schema's own types or node types
+	exec(t, c, `mutation{setType(id:"shirt",name:"Shirt",fields:[
+		{name:"valueType",type:"Enum",enumValues:["Polo","Tee"]},
+		{name:"scheme",type:"Enum",enumValues:["Plain","Striped"]},
+		{name:"size",type:"Enum",enumValues:["S","M","L"]}
+	]){id}}`)
+	exec(t, c, `mutation{setNode(id:"a",type:"Shirt",attrs:[
+		{name:"valueType",value:"Polo",enc:"UTF8"},{name:"scheme",value:"Striped",enc:"UTF8"},{name:"size",value:"M",enc:"UTF8"}
+	]){id}}`)
+	expect(query(t, c, `{nodes(type:[Shirt]){... on Shirt{valueType scheme size}}}`)).ToEqual(
+		`{"nodes":[{"scheme":"Striped","size":"M","valueType":"Polo"}]}`)
+	expect(query(t, c, `{__type(name:"ValueTypeEnum2"){kind}}`)).ToEqual(`{"__type":{"kind":"ENUM"}}`)
+	expect(query(t, c, `{__type(name:"SizeEnum"){kind}}`)).ToEqual(`{"__type":{"kind":"OBJECT"}}`)
+	for _, v := range []string{"true", "false", "null"} {
+		expect(execErr(t, c, `mutation{setType(id:"flag",name:"Flag",fields:[{name:"state",type:"Enum",enumValues:["`+v+`"]}]){id}}`).Error()).ToEqual(
+			`'` + v + `' is not a valid field enum value`)
+		expect(execErr(t, c, `mutation{changeFieldType(typeID:"size",name:"label",type:"Enum",enumValues:["`+v+`"]){failures{nodeID}}}`).Error()).ToEqual(
+			`'` + v + `' is not a valid field enum value`)
+	}
+}
+
+func TestSetTypeReservedNames(t *testing.T) {
+	expect := testutil.Expect(t)
+	db, done := openTestDB(t)
+	defer done()
+	c := connect(t, db)
+	for _, name := range []string{"Date", "DateTime", "Time", "GeoPoint", "DataTable", "Revision", "Type"} {
+		expect(execErr(t, c, `mutation{setType(id:"x",name:"`+name+`"){id}}`).Error()).ToEqual(
+			`cannot define type '` + name + `': the name is used by the schema`)
+	}
+	exec(t, c, `mutation{setType(id:"event",name:"Event",fields:[{name:"on",type:"Date"},{name:"at",type:"GeoPoint"}]){id}}`)
+	expect(query(t, c, `{__type(name:"Date"){kind}}`)).ToEqual(`{"__type":{"kind":"SCALAR"}}`)
+}
+
+func TestValidation(t *testing.T) {
+	expect := testutil.Expect(t)
+	db, done := openTestDB(t)
+	defer done()
+	c := connect(t, db)
+	exec(t, c, userType)
+	exec(t, c, `mutation{setType(id:"post",name:"Post",fields:[
+		{name:"title",type:"Text",required:true,textCharLimit:5},
+		{name:"author",type:"Edge",edgeName:"author",edgeDirection:"Out",edgeToTypeID:"u"}
+	]){id}}`)
+	exec(t, c, `mutation{setNode(id:"alice",type:"User"){id}}`)
+	// required fields are only enforced on commit
+	exec(t, c, `mutation{setNode(id:"p1",type:"Post"){id}}`)
+	expect(c.Commit().Error()).ToEqual(`validation failed: node 'p1' field 'title': value is required`)
+	expect(execErr(t, c, `mutation{setNode(id:"p1",type:"Post",attrs:[{name:"title",value:"Too long",enc:"UTF8"}]){id}}`).Error()).ToEqual(
+		`validation failed: node 'p1' field 'title': value exceeds limit of 5 characters`)
+	exec(t, c, `mutation{setNode(id:"p1",type:"Post",attrs:[{name:"title",value:"Hi",enc:"UTF8"}]){id}}`)
+	expect(execErr(t, c, `mutation{setEdge(from:"p1",to:"p1",name:"author"){name}}`).Error()).ToEqual(
+		`validation failed: node 'p1' field 'author': connected node 'p1' is not of type 'u'`)
+	exec(t, c, `mutation{setEdge(from:"p1",to:"alice",name:"author"){name}}`)
+	commit(t, c)
+}
+
+func TestEdgeCardinality(t *testing.T) {
+	expect := testutil.Expect(t)
+	db, done := openTestDB(t)
+	defer done()
+	c := connect(t, db)
+	exec(t, c, `mutation{setType(id:"person",name:"Person",fields:[
+		{name:"manager",type:"Edge",edgeName:"manager",edgeDirection:"Out",edgeCardinality:"One"},
+		{name:"mentor",type:"Edge",edgeName:"mentor",edgeDirection:"Out",edgeCardinality:"One",edgeOverflow:"Replace"}
+	]){id}}`)
+	for _, id := range []string{"a", "b", "c"} {
+		exec(t, c, `mutation{setNode(id:"`+id+`",type:"Person"){id}}`)
+	}
+	// a second edge is rejected
+	exec(t, c, `mutation{setEdge(from:"a",to:"b",name:"manager"){name}}`)
+	expect(execErr(t, c, `mutation{setEdge(from:"a",to:"c",name:"manager"){name}}`).Error()).ToEqual(
+		`validation failed: node 'a' field 'manager': has 2 connections but at most 1 are allowed`)
+	// or replaces the first
+	exec(t, c, `mutation{setEdge(from:"a",to:"b",name:"mentor"){name}}`)
+	exec(t, c, `mutation{setEdge(from:"a",to:"c",name:"mentor"){name}}`)
+	expect(query(t, c, `{node(id:"a"){... on Person{manager{node{id}} mentor{node{id}}}}}`)).ToEqual(
+		`{"node":{"manager":{"node":{"id":"b"}},"mentor":{"node":{"id":"c"}}}}`)
+	commit(t, c)
+}
+
+func TestEdgeOrder(t *testing.T) {
+	expect := testutil.Expect(t)
+	db, done := openTestDB(t)
+	defer done()
+	c := connect(t, db)
+	exec(t, c, `mutation{setType(id:"list",name:"List",fields:[
+		{name:"items",type:"Edge",edgeName:"item",edgeDirection:"Out"}
+	]){id}}`)
+	for _, id := range []string{"l", "a", "b", "c"} {
+		exec(t, c, `mutation{setNode(id:"`+id+`",type:"List"){id}}`)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		exec(t, c, `mutation{setEdge(from:"l",to:"`+id+`",name:"item"){name}}`)
+	}
+	items := `{node(id:"l"){... on List{items{node{id}}}}}`
+	expect(query(t, c, items)).ToEqual(`{"node":{"items":[{"node":{"id":"a"}},{"node":{"id":"b"}},{"node":{"id":"c"}}]}}`)
+	expect(exec(t, c, `mutation{moveEdge(from:"l",to:"c",name:"item",position:0){to{id}}}`)).ToEqual(
+		`{"moveEdge":[{"to":{"id":"c"}},{"to":{"id":"a"}},{"to":{"id":"b"}}]}`)
+	expect(exec(t, c, `mutation{reorderEdges(from:"l",name:"item",to:["b","a"]){to{id}}}`)).ToEqual(
+		`{"reorderEdges":[{"to":{"id":"b"}},{"to":{"id":"a"}},{"to":{"id":"c"}}]}`)
+	expect(execErr(t, c, `mutation{reorderEdges(from:"l",name:"item",to:["b","b"]){to{id}}}`).Error()).ToEqual(
+		`cannot reorder edges: 'b' is listed more than once`)
+	expect(execErr(t, c, `mutation{moveEdge(from:"l",to:"l",name:"item",position:0){to{id}}}`).Error()).ToEqual(
+		`cannot move edge 'item' from 'l' to 'l': not connected`)
+	commit(t, c)
+	// the order is kept when the log is replayed
+	db = reopen(t, db)
+	c = connect(t, db)
+	expect(query(t, c, items)).ToEqual(`{"node":{"items":[{"node":{"id":"b"}},{"node":{"id":"a"}},{"node":{"id":"c"}}]}}`)
+}
+
+func TestNodesWhere(t *testing.T) {
+	expect := testutil.Expect(t)
+	db, done := openTestDB(t)
+	defer done()
+	c := connect(t, db)
+	exec(t, c, `mutation{setType(id:"product",name:"Product",fields:[
+		{name:"name",type:"Text"},{name:"sku",type:"Text",indexed:true},{name:"price",type:"Int"},
+		{name:"variants",type:"Edge",edgeName:"variant",edgeDirection:"Out"}
+	]){id}}`)
+	for i, name := range []string{"Red Shirt", "Blue Shirt", "Hat"} {
+		exec(t, c, `mutation{setNode(id:"`+string(rune('a'+i))+`",type:"Product",attrs:[
+			{name:"name",value:"`+name+`",enc:"UTF8"},{name:"sku",value:"`+name[:3]+`",enc:"UTF8"},
+			{name:"price",value:"`+string(rune('3'-i))+`0",enc:"UTF8"}
+		]){id}}`)
+	}
+	exec(t, c, `mutation{setEdge(from:"a",to:"b",name:"variant"){name}}`)
+	nodes := func(args string) string {
+		return query(t, c, `{nodes(type:[Product],`+args+`){id}}`)
+	}
+	expect(nodes(`where:[{field:"sku",value:"Hat"}]`)).ToEqual(`{"nodes":[{"id":"c"}]}`)
+	expect(nodes(`where:[{field:"sku",op:"in",values:["Red","Hat"]}]`)).ToEqual(`{"nodes":[{"id":"a"},{"id":"c"}]}`)
+	expect(nodes(`where:[{field:"price",op:"lt",value:"30"}]`)).ToEqual(`{"nodes":[{"id":"b"},{"id":"c"}]}`)
+	expect(nodes(`where:[{field:"name",op:"contains",value:"shirt"},{edge:"variant",direction:"Out",exists:false}]`)).ToEqual(
+		`{"nodes":[{"id":"b"}]}`)
+	expect(nodes(`sort:[price]`)).ToEqual(`{"nodes":[{"id":"c"},{"id":"b"},{"id":"a"}]}`)
+	expect(nodes(`sort:[name],desc:true`)).ToEqual(`{"nodes":[{"id":"a"},{"id":"c"},{"id":"b"}]}`)
+	expect(resultErr(c.Query(`{nodes(type:[Product],where:[{field:"name",op:"like",value:"Hat"}]){id}}`))).ToNotBeNil()
+}
+
+func TestSearch(t *testing.T) {
+	expect := testutil.Expect(t)
+	db, done := openTestDB(t)
+	defer done()
+	c := connect(t, db)
+	exec(t, c, `mutation{setType(id:"post",name:"Post",fields:[{name:"title",type:"Text"},{name:"body",type:"RichText"}]){id}}`)
+	exec(t, c, `mutation{setType(id:"note",name:"Note",fields:[{name:"text",type:"Text"}]){id}}`)
+	exec(t, c, `mutation{setNode(id:"p1",type:"Post",attrs:[{name:"title",value:"Walking",enc:"UTF8"},{name:"body",value:"<p>hills and rivers</p>",enc:"UTF8"}]){id}}`)
+	exec(t, c, `mutation{setNode(id:"p2",type:"Post",attrs:[{name:"title",value:"Rivers",enc:"UTF8"}]){id}}`)
+	exec(t, c, `mutation{setNode(id:"n1",type:"Note",attrs:[{name:"text",value:"rivers",enc:"UTF8"}]){id}}`)
+	// only committed nodes are indexed
+	expect(query(t, c, `{search(query:"rivers"){node{id}}}`)).ToEqual(`{"search":[]}`)
+	commit(t, c)
+	expect(query(t, c, `{search(query:"hills"){node{id} field snippet}}`)).ToEqual(
+		`{"search":[{"field":"body","node":{"id":"p1"},"snippet":"\u003cem\u003ehills\u003c/em\u003e and rivers"}]}`)
+	expect(query(t, c, `{search(query:"rivers",types:[Post]){node{id}}}`)).ToEqual(
+		`{"search":[{"node":{"id":"p2"}},{"node":{"id":"p1"}}]}`)
+	expect(query(t, c, `{search(query:"rivers",first:1){node{id}}}`)).ToEqual(`{"search":[{"node":{"id":"n1"}}]}`)
+	// removed nodes are skipped before the removal is committed
+	exec(t, c, `mutation{removeNodes(id:"p2"){__typename}}`)
+	expect(query(t, c, `{search(query:"rivers",types:[Post]){node{id}}}`)).ToEqual(`{"search":[{"node":{"id":"p1"}}]}`)
+	expect(resultErr(c.Query(`{search(query:"rivers",first:0){node{id}}}`))).ToNotBeNil()
+}
+
+func TestEnumField(t *testing.T) {
+	expect := testutil.Expect(t)
+	db, done := openTestDB(t)
+	defer done()
+	c := connect(t, db)
+	exec(t, c, `mutation{setType(id:"shirt",name:"Shirt",fields:[
+		{name:"size",type:"Enum",enumValues:["S","M","L"]},
+		{name:"colours",type:"Enum",enumValues:["Red","Blue"],enumMulti:true}
+	]){id}}`)
+	exec(t, c, `mutation{setNode(id:"a",type:"Shirt",attrs:[
+		{name:"size",value:"M",enc:"UTF8"},{name:"colours",value:"[\"Blue\",\"Red\"]",enc:"JSON"}
+	]){id}}`)
+	expect(execErr(t, c, `mutation{setNode(id:"b",type:"Shirt",attrs:[{name:"size",value:"XL",enc:"UTF8"}]){id}}`).Error()).ToEqual(
+		`validation failed: node 'b' field 'size': 'XL' is not one of S, M, L`)
+	expect(execErr(t, c, `mutation{setNode(id:"b",type:"Shirt",attrs:[{name:"colours",value:"[\"Red\",\"Red\"]",enc:"JSON"}]){id}}`).Error()).ToEqual(
+		`validation failed: node 'b' field 'colours': 'Red' is chosen more than once`)
+	commit(t, c)
+	db = reopen(t, db)
+	c = connect(t, db)
+	expect(query(t, c, `{node(id:"a"){... on Shirt{size colours}}}`)).ToEqual(`{"node":{"colours":["Blue","Red"],"size":"M"}}`)
+	expect(query(t, c, `{__type(name:"SizeEnum"){kind}}`)).ToEqual(`{"__type":{"kind":"ENUM"}}`)
+}
+
+func TestTimeFields(t *testing.T) {
+	expect := testutil.Expect(t)
+	db, done := openTestDB(t)
+	defer done()
+	c := connect(t, db)
+	exec(t, c, `mutation{setType(id:"event",name:"Event",fields:[
+		{name:"day",type:"Date"},{name:"starts",type:"DateTime"},{name:"doors",type:"Time"}
+	]){id}}`)
+	exec(t, c, `mutation{setNode(id:"a",type:"Event",attrs:[
+		{name:"day",value:"2024-03-01",enc:"UTF8"},{name:"starts",value:"2024-03-01T20:00:00+02:00",enc:"UTF8"},
+		{name:"doors",value:"19:30",enc:"UTF8"}
+	]){id}}`)
+	exec(t, c, `mutation{setNode(id:"b",type:"Event",attrs:[{name:"day",value:"2023-12-25",enc:"UTF8"}]){id}}`)
+	expect(execErr(t, c, `mutation{setNode(id:"c",type:"Event",attrs:[{name:"day",value:"tomorrow",enc:"UTF8"}]){id}}`)).ToNotBeNil()
+	commit(t, c)
+	db = reopen(t, db)
+	c = connect(t, db)
+	expect(query(t, c, `{node(id:"a"){... on Event{day starts doors}}}`)).ToEqual(
+		`{"node":{"day":"2024-03-01","doors":"19:30:00","starts":"2024-03-01T18:00:00Z"}}`)
+	expect(query(t, c, `{node(id:"a"){... on Event{day(format:"2 Jan 2006") starts(tz:"+01:00")}}}`)).ToEqual(
+		`{"node":{"day":"1 Mar 2024","starts":"2024-03-01T19:00:00+01:00"}}`)
+	expect(query(t, c, `{nodes(type:[Event],where:[{field:"day",op:"lt",value:"2024-01-01"}]){id}}`)).ToEqual(`{"nodes":[{"id":"b"}]}`)
+	expect(query(t, c, `{nodes(type:[Event],sort:[day]){id}}`)).ToEqual(`{"nodes":[{"id":"b"},{"id":"a"}]}`)
+}
+
+func TestGeoPointField(t *testing.T) {
+	expect := testutil.Expect(t)
+	db, done := openTestDB(t)
+	defer done()
+	c := connect(t, db)
+	exec(t, c, `mutation{setType(id:"place",name:"Place",fields:[{name:"at",type:"GeoPoint"}]){id}}`)
+	for _, p := range [][2]string{{"london", "51.5074,-0.1278"}, {"oxford", `{\"lat\":51.752,\"lng\":-1.2577}`}, {"paris", "48.8566,2.3522"}} {
+		exec(t, c, `mutation{setNode(id:"`+p[0]+`",type:"Place",attrs:[{name:"at",value:"`+p[1]+`",enc:"UTF8"}]){id}}`)
+	}
+	expect(execErr(t, c, `mutation{setNode(id:"x",type:"Place",attrs:[{name:"at",value:"91,0",enc:"UTF8"}]){id}}`)).ToNotBeNil()
+	commit(t, c)
+	db = reopen(t, db)
+	c = connect(t, db)
+	expect(query(t, c, `{node(id:"oxford"){... on Place{at{lat lng}}}}`)).ToEqual(`{"node":{"at":{"lat":51.752,"lng":-1.2577}}}`)
+	expect(query(t, c, `{nodes(type:[Place],near:{lat:51.5,lng:-0.12,radius:100000}){id}}`)).ToEqual(
+		`{"nodes":[{"id":"london"},{"id":"oxford"}]}`)
+	expect(query(t, c, `{nodes(type:[Place],near:{lat:51.75,lng:-1.25,radius:500000},desc:true){id}}`)).ToEqual(
+		`{"nodes":[{"id":"paris"},{"id":"london"},{"id":"oxford"}]}`)
+	expect(query(t, c, `{nodes(type:[Place],within:{north:52,south:48,east:3,west:-1}){id}}`)).ToEqual(
+		`{"nodes":[{"id":"london"},{"id":"paris"}]}`)
+	expect(resultErr(c.Query(`{nodes(near:{lat:100,lng:0,radius:1}){id}}`))).ToNotBeNil()
+	var near struct {
+		Nearby []struct {
+			Node     struct{ ID string }
+			Field    string
+			Distance float64
+		}
+	}
+	json.Unmarshal([]byte(query(t, c, `{nearby(type:[Place],near:{lat:51.5074,lng:-0.1278,radius:100000}){node{id} field distance}}`)), &near)
+	expect(len(near.Nearby)).ToEqual(2)
+	expect(near.Nearby[0].Node.ID).ToEqual("london")
+	expect(near.Nearby[0].Distance < 1).ToEqual(true)
+	expect(near.Nearby[1].Node.ID).ToEqual("oxford")
+	expect(near.Nearby[1].Field).ToEqual("at")
+	// the point arrives as a Float argument so allow for rounding
+	d := graph.GeoPoint{Lat: 51.5074, Lng: -0.1278}.Distance(graph.GeoPoint{Lat: 51.752, Lng: -1.2577})
+	expect(math.Abs(near.Nearby[1].Distance-d) < 1).ToEqual(true)
+}
+
+func TestDataTableField(t *testing.T) {
+	expect := testutil.Expect(t)
+	db, done := openTestDB(t)
+	defer done()
+	c := connect(t, db)
+	exec(t, c, `mutation{setType(id:"product",name:"Product",fields:[{name:"prices",type:"DataTable",dataColumns:[
+		{name:"size",type:"Text"},{name:"qty",type:"Int"},{name:"price",type:"Float",unit:"GBP"}
+	]}]){id}}`)
+	exec(t, c, `mutation{setNode(id:"a",type:"Product",attrs:[
+		{name:"prices",value:"[{\"size\":\"S\",\"qty\":10,\"price\":1.5},[\"M\",null,2]]",enc:"JSON"}
+	]){id}}`)
+	exec(t, c, `mutation{setNode(id:"b",type:"Product",attrs:[{name:"prices",value:"size,price\nL,3.25",enc:"CSV"}]){id}}`)
+	expect(execErr(t, c, `mutation{setNode(id:"c",type:"Product",attrs:[{name:"prices",value:"[{\"qty\":\"ten\"}]",enc:"JSON"}]){id}}`)).ToNotBeNil()
+	commit(t, c)
+	db = reopen(t, db)
+	c = connect(t, db)
+	expect(query(t, c, `{node(id:"a"){... on Product{prices{columns{name type unit} rows csv}}}}`)).ToEqual(
+		`{"node":{"prices":{"columns":[{"name":"size","type":"Text","unit":null},{"name":"qty","type":"Int","unit":null},{"name":"price","type":"Float","unit":"GBP"}],` +
+			`"csv":"size,qty,price\nS,10,1.5\nM,,2\n","rows":[["S","10","1.5"],["M",null,"2"]]}}}`)
+	expect(query(t, c, `{node(id:"b"){... on Product{prices{rows}}}}`)).ToEqual(`{"node":{"prices":{"rows":[["L",null,"3.25"]]}}}`)
+}
+
+func TestTypeInterfaces(t *testing.T) {
+	expect := testutil.Expect(t)
+	db, done := openTestDB(t)
+	defer done()
+	c := connect(t, db)
+	exec(t, c, `mutation{setType(id:"content",name:"Content",abstract:true,fields:[{name:"title",type:"Text"}]){id}}`)
+	exec(t, c, `mutation{setType(id:"page",name:"Page",extends:["content"]){id}}`)
+	exec(t, c, `mutation{setNode(id:"a",type:"Page",attrs:[{name:"title",value:"Home",enc:"UTF8"}]){id}}`)
+	page := `{node(id:"a"){__typename ... on Page{title} ... on Content{title}}}`
+	expect(query(t, c, page)).ToEqual(`{"node":{"__typename":"Page","title":"Home"}}`)
+	// extending a concrete type does not rename its object
+	exec(t, c, `mutation{setType(id:"post",name:"Post",extends:["page"]){id}}`)
+	expect(query(t, c, page)).ToEqual(`{"node":{"__typename":"Page","title":"Home"}}`)
+	expect(query(t, c, `{nodes(type:[Page]){... on PageInterface{title}}}`)).ToEqual(`{"nodes":[{"title":"Home"}]}`)
+	expect(execErr(t, c, `mutation{setType(id:"x",name:"PageInterface"){id}}`).Error()).ToEqual(
+		`cannot define type 'PageInterface': the name clashes with the interface of type 'Page'`)
+	expect(execErr(t, c, `mutation{setType(id:"node",name:"Node"){id}}`).Error()).ToEqual(
+		`cannot define type 'Node': the name is used by the schema`)
+}
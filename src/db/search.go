@@ -0,0 +1,88 @@
+package db
+
+import (
+	"graph"
+	"regexp"
+	"search"
+)
+
+var markupTag = regexp.MustCompile(`<[^>]*>`)
+
+// searchFields returns the text of the Text and RichText attrs of n
+// with any markup removed
+func searchFields(n *graph.Node) []search.Field {
+	fs := []search.Field{}
+	t := n.Type()
+	if t == nil {
+		return fs
+	}
+	for _, f := range t.AllFields() {
+		if f.Type != Text && f.Type != RichText {
+			continue
+		}
+		attr := n.Attr(f.Name)
+		if attr == nil || attr.Empty() {
+			continue
+		}
+		text := attr.String()
+		if f.Type == RichText {
+			text = markupTag.ReplaceAllString(text, " ")
+		}
+		fs = append(fs, search.Field{Name: f.Name, Text: text})
+	}
+	return fs
+}
+
+// indexAll rebuilds the search and spatial indexes from the committed
+// graph
+func (db *DB) indexAll() {
+	db.indexLock.Lock()
+	defer db.indexLock.Unlock()
+	db.index = search.NewIndex()
+	db.geo = newGeoIndex()
+	for _, n := range db.g.Nodes() {
+		db.index.Put(n.ID(), searchFields(n))
+		db.geo.put(n)
+	}
+	db.geo.g = db.g
+}
+
+// updateIndex brings the search and spatial indexes up to date with
+// the changes committed since before. Redefined types cause all of
+// their nodes to be reindexed as the set of fields may have changed.
+func (db *DB) updateIndex(before *graph.Graph) {
+	db.indexLock.Lock()
+	defer db.indexLock.Unlock()
+	defer func() { db.geo.g = db.g }()
+	cs := graph.Diff(before, db.g)
+	for _, c := range cs.Nodes {
+		if c.New == nil {
+			db.index.Remove(c.ID)
+			db.geo.remove(c.ID)
+			continue
+		}
+		db.index.Put(c.ID, searchFields(c.New))
+		db.geo.put(c.New)
+	}
+	types := []*graph.Type{}
+	for _, c := range cs.Types {
+		if c.New != nil {
+			types = append(types, c.New)
+		}
+	}
+	if len(types) == 0 {
+		return
+	}
+	for _, n := range db.g.Nodes().FilterType(types...) {
+		db.index.Put(n.ID(), searchFields(n))
+		db.geo.put(n)
+	}
+}
+
+// Search finds committed nodes whose text matches query. Results for
+// which keep returns false are skipped.
+func (db *DB) Search(query string, first int, keep func(id string) bool) []*search.Result {
+	db.indexLock.RLock()
+	defer db.indexLock.RUnlock()
+	return db.index.Search(query, first, keep)
+}
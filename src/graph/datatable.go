@@ -0,0 +1,205 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DataColumn is a column of a DataTable field
+type DataColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // Text/Int/Float/Boolean
+	Unit string `json:"unit"`
+}
+
+// valueType returns the type of value held by cells of the column
+func (c *DataColumn) valueType() ValueType {
+	return (&Field{Type: c.Type}).ValueType("")
+}
+
+// DataTable is the value of a DataTable field. Each row holds a cell
+// for every column in order, missing cells are nil. It is stored as a
+// JSON list of objects keyed by column name.
+type DataTable struct {
+	Columns []*DataColumn
+	Rows    [][]interface{}
+}
+
+func validateDataColumns(f *Field) error {
+	if len(f.DataColumns) == 0 {
+		return fmt.Errorf("dataColumns are required")
+	}
+	seen := map[string]bool{}
+	for _, c := range f.DataColumns {
+		if c.Name == "" || seen[c.Name] {
+			return fmt.Errorf("dataColumns must have unique names that are not blank")
+		}
+		seen[c.Name] = true
+		switch c.Type {
+		case "Text", "Int", "Float", "Boolean":
+		default:
+			return fmt.Errorf("column '%s': '%s' is not a valid column type", c.Name, c.Type)
+		}
+	}
+	return nil
+}
+
+// ParseDataTable reads the value of the DataTable field f. Rows may be
+// objects keyed by column name or lists of cells in column order and
+// cells are converted to the type of their column.
+func (f *Field) ParseDataTable(v interface{}) (*DataTable, error) {
+	var rows []json.RawMessage
+	if err := json.Unmarshal([]byte(FormatValue(v)), &rows); err != nil {
+		return nil, fmt.Errorf("'%s' is not a list of rows", FormatValue(v))
+	}
+	dt := &DataTable{Columns: f.DataColumns, Rows: [][]interface{}{}}
+	for i, raw := range rows {
+		cells := map[string]interface{}{}
+		if err := json.Unmarshal(raw, &cells); err != nil {
+			list := []interface{}{}
+			if err := json.Unmarshal(raw, &list); err != nil {
+				return nil, fmt.Errorf("row %d is not an object or list", i+1)
+			}
+			if len(list) > len(f.DataColumns) {
+				return nil, fmt.Errorf("row %d has %d cells but there are %d columns", i+1, len(list), len(f.DataColumns))
+			}
+			for j, cell := range list {
+				cells[f.DataColumns[j].Name] = cell
+			}
+		}
+		row, err := dt.row(i, cells)
+		if err != nil {
+			return nil, err
+		}
+		dt.Rows = append(dt.Rows, row)
+	}
+	return dt, nil
+}
+
+// ParseDataTableCSV reads CSV text as the value of the DataTable field
+// f. If the first record names columns it is used as a header to pick
+// the column of each cell, otherwise cells are in column order. Empty
+// cells are left out.
+func (f *Field) ParseDataTableCSV(text string) (*DataTable, error) {
+	r := csv.NewReader(strings.NewReader(text))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %s", err)
+	}
+	names := []string{}
+	for _, c := range f.DataColumns {
+		names = append(names, c.Name)
+	}
+	if len(records) > 0 && isDataHeader(records[0], f.DataColumns) {
+		names = []string{}
+		for _, name := range records[0] {
+			for _, c := range f.DataColumns {
+				if strings.EqualFold(strings.TrimSpace(name), c.Name) {
+					name = c.Name
+				}
+			}
+			names = append(names, name)
+		}
+		records = records[1:]
+	}
+	dt := &DataTable{Columns: f.DataColumns, Rows: [][]interface{}{}}
+	for i, record := range records {
+		if len(record) > len(names) {
+			return nil, fmt.Errorf("row %d has %d cells but there are %d columns", i+1, len(record), len(names))
+		}
+		cells := map[string]interface{}{}
+		for j, cell := range record {
+			if cell = strings.TrimSpace(cell); cell != "" {
+				cells[names[j]] = cell
+			}
+		}
+		row, err := dt.row(i, cells)
+		if err != nil {
+			return nil, err
+		}
+		dt.Rows = append(dt.Rows, row)
+	}
+	return dt, nil
+}
+
+// isDataHeader reports whether every value of record names a column
+func isDataHeader(record []string, columns []*DataColumn) bool {
+	for _, name := range record {
+		found := false
+		for _, c := range columns {
+			if strings.EqualFold(strings.TrimSpace(name), c.Name) {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// row converts cells keyed by column name into a row in column order
+func (dt *DataTable) row(i int, cells map[string]interface{}) ([]interface{}, error) {
+	row := make([]interface{}, len(dt.Columns))
+	for name, cell := range cells {
+		j := -1
+		for k, c := range dt.Columns {
+			if c.Name == name {
+				j = k
+			}
+		}
+		if j < 0 {
+			return nil, fmt.Errorf("row %d: there is no column called '%s'", i+1, name)
+		}
+		if cell == nil || cell == "" {
+			continue
+		}
+		v, err := Coerce(cell, dt.Columns[j].valueType())
+		if err != nil {
+			return nil, fmt.Errorf("row %d column '%s': %s", i+1, name, err)
+		}
+		row[j] = v
+	}
+	return row, nil
+}
+
+// Value returns the stored form of the table
+func (dt *DataTable) Value() json.RawMessage {
+	rows := []map[string]interface{}{}
+	for _, row := range dt.Rows {
+		obj := map[string]interface{}{}
+		for j, cell := range row {
+			if cell != nil {
+				obj[dt.Columns[j].Name] = cell
+			}
+		}
+		rows = append(rows, obj)
+	}
+	b, _ := json.Marshal(rows)
+	return json.RawMessage(b)
+}
+
+// CSV returns the table as CSV with a header of column names
+func (dt *DataTable) CSV() string {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	header := []string{}
+	for _, c := range dt.Columns {
+		header = append(header, c.Name)
+	}
+	w.Write(header)
+	for _, row := range dt.Rows {
+		record := []string{}
+		for _, cell := range row {
+			record = append(record, FormatValue(cell))
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	return buf.String()
+}
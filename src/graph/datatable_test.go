@@ -0,0 +1,68 @@
+package graph
+
+import (
+	"testing"
+
+	"testutil"
+)
+
+var priceTable = &Field{Name: "prices", Type: "DataTable", DataColumns: []*DataColumn{
+	{Name: "size", Type: "Text"},
+	{Name: "qty", Type: "Int"},
+	{Name: "price", Type: "Float", Unit: "GBP"},
+}}
+
+func TestParseDataTable(t *testing.T) {
+	expect := testutil.Expect(t)
+	dt, err := priceTable.ParseDataTable(`[{"size":"S","qty":10,"price":1.5},["M",null,2]]`)
+	expect(err).ToEqual(nil)
+	expect(dt.Rows).ToEqual([][]interface{}{
+		{"S", int64(10), 1.5},
+		{"M", nil, float64(2)},
+	})
+	expect(string(dt.Value())).ToEqual(`[{"price":1.5,"qty":10,"size":"S"},{"price":2,"size":"M"}]`)
+	for _, s := range []string{`{}`, `[1]`, `[{"colour":"red"}]`, `[["S",1,2,3]]`, `[{"qty":"ten"}]`} {
+		_, err := priceTable.ParseDataTable(s)
+		expect(err).ToNotBeNil()
+	}
+	v, err := priceTable.Coerce(`[["S",1,2]]`, "JSON")
+	expect(err).ToEqual(nil)
+	expect(TypeOf(v)).ToEqual(JSONValue)
+}
+
+func TestParseDataTableCSV(t *testing.T) {
+	expect := testutil.Expect(t)
+	// a header picks the column of each cell
+	dt, err := priceTable.ParseDataTableCSV("Size, Price, Qty\nS, 1.50, 10\nM,2,\n")
+	expect(err).ToEqual(nil)
+	expect(dt.Rows).ToEqual([][]interface{}{
+		{"S", int64(10), 1.5},
+		{"M", nil, float64(2)},
+	})
+	expect(dt.CSV()).ToEqual("size,qty,price\nS,10,1.5\nM,,2\n")
+	// without a header cells are in column order
+	dt, err = priceTable.ParseDataTableCSV("L,5,3.25")
+	expect(err).ToEqual(nil)
+	expect(dt.Rows).ToEqual([][]interface{}{{"L", int64(5), 3.25}})
+	_, err = priceTable.ParseDataTableCSV("S,many,1")
+	expect(err).ToNotBeNil()
+	_, err = priceTable.ParseDataTableCSV("S,1,2,3")
+	expect(err).ToNotBeNil()
+	v, err := priceTable.Coerce("L,5,3.25", "CSV")
+	expect(err).ToEqual(nil)
+	expect(FormatValue(v)).ToEqual(`[{"price":3.25,"qty":5,"size":"L"}]`)
+}
+
+func TestValidateDataColumns(t *testing.T) {
+	expect := testutil.Expect(t)
+	expect(validateDataColumns(priceTable)).ToEqual(nil)
+	for _, cols := range [][]*DataColumn{
+		nil,
+		{{Name: "", Type: "Text"}},
+		{{Name: "a", Type: "Text"}, {Name: "a", Type: "Int"}},
+		{{Name: "a", Type: "Edge"}},
+	} {
+		f := &Field{Name: "t", Type: "DataTable", DataColumns: cols}
+		expect(validateDataColumns(f)).ToNotBeNil()
+	}
+}
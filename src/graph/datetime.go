@@ -0,0 +1,83 @@
+package graph
+
+import (
+	"fmt"
+	"time"
+)
+
+// Layouts of stored Date, DateTime and Time values. DateTimes are
+// stored in UTC so that stored values sort in time order as strings.
+const (
+	DateLayout     = "2006-01-02"
+	DateTimeLayout = time.RFC3339
+	TimeLayout     = "15:04:05"
+)
+
+// layouts accepted when parsing values for each temporal field type,
+// most specific first. Values without a zone are taken to be UTC.
+var timeLayouts = map[string][]string{
+	"Date": {DateLayout, time.RFC3339Nano},
+	"DateTime": {
+		time.RFC3339Nano,
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05",
+		"2006-01-02T15:04",
+		"2006-01-02 15:04",
+		DateLayout,
+	},
+	"Time": {TimeLayout, "15:04", "15:04:05.999999999"},
+}
+
+// Temporal reports whether the field holds a Date, DateTime or Time
+func (f *Field) Temporal() bool {
+	_, ok := timeLayouts[f.Type]
+	return ok
+}
+
+// ParseTime parses a value for a field of the given temporal type.
+// Dates are midnight UTC and Times are on the zero date.
+func ParseTime(fieldType string, s string) (time.Time, error) {
+	layouts, ok := timeLayouts[fieldType]
+	if !ok {
+		return time.Time{}, fmt.Errorf("'%s' is not a Date, DateTime or Time type", fieldType)
+	}
+	for _, layout := range layouts {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		switch fieldType {
+		case "Date":
+			// keep the calendar date written rather than the UTC one
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+		case "Time":
+			return time.Date(0, 1, 1, t.Hour(), t.Minute(), t.Second(), 0, time.UTC), nil
+		}
+		return t.UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("'%s' is not a valid %s", s, fieldType)
+}
+
+// FormatTime returns the stored form of t for the temporal type
+func FormatTime(fieldType string, t time.Time) string {
+	switch fieldType {
+	case "Date":
+		return t.Format(DateLayout)
+	case "Time":
+		return t.Format(TimeLayout)
+	}
+	return t.UTC().Format(DateTimeLayout)
+}
+
+// normalizeTime returns the stored form of a temporal value
+func normalizeTime(fieldType string, v interface{}) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("'%s' is not a valid %s", FormatValue(v), fieldType)
+	}
+	t, err := ParseTime(fieldType, s)
+	if err != nil {
+		return "", err
+	}
+	return FormatTime(fieldType, t), nil
+}
@@ -0,0 +1,87 @@
+package graph
+
+import (
+	"testing"
+
+	"testutil"
+)
+
+func TestFieldCoerceTime(t *testing.T) {
+	expect := testutil.Expect(t)
+	for _, c := range []struct {
+		fieldType string
+		in        string
+		out       string
+	}{
+		{"DateTime", "2024-03-10T09:30:00+02:00", "2024-03-10T07:30:00Z"},
+		{"DateTime", "2024-03-10T09:30:00.25Z", "2024-03-10T09:30:00Z"},
+		{"DateTime", "2024-03-10 09:30", "2024-03-10T09:30:00Z"},
+		{"DateTime", "2024-03-10", "2024-03-10T00:00:00Z"},
+		{"Date", "2024-03-10", "2024-03-10"},
+		{"Date", "2024-03-10T23:30:00-05:00", "2024-03-10"},
+		{"Time", "9:05", "09:05:00"},
+		{"Time", "25:00", ""},
+		{"Date", "10/03/2024", ""},
+		{"Time", "09:05", "09:05:00"},
+		{"Time", "23:59:59", "23:59:59"},
+	} {
+		f := &Field{Name: "x", Type: c.fieldType}
+		v, err := f.Coerce(c.in, "UTF8")
+		if c.out == "" {
+			expect(err).ToNotBeNil()
+			continue
+		}
+		expect(err).ToEqual(nil)
+		expect(v).ToEqual(c.out)
+	}
+}
+
+func eventGraph() *Graph {
+	g := New()
+	g = g.DefineType(Type{ID: "event", Name: "Event", Fields: Fields{
+		{Name: "title", Type: "Text"},
+		{Name: "starts", Type: "DateTime"},
+	}})
+	event := g.TypeByID("event")
+	for id, starts := range map[string]string{
+		"a": "2024-03-10T07:30:00Z",
+		"b": "2023-12-31T23:00:00Z",
+		"c": "2024-01-05T12:00:00Z",
+		"d": "",
+	} {
+		g = g.Set(NodeConfig{ID: id, Type: event, Attrs: []*Attr{
+			{Name: "starts", Value: starts},
+		}})
+	}
+	return g
+}
+
+func TestTimeRangeAndSort(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := eventGraph()
+	expect(g.Validiate()).ToEqual(nil)
+	ids := func(ns Nodes) []string {
+		out := []string{}
+		for _, n := range ns {
+			out = append(out, n.ID())
+		}
+		return out
+	}
+	// condition values are converted to UTC before comparing
+	ns, err := g.Find(nil, []*Condition{
+		{Field: "starts", Op: OpGte, Value: "2024-01-01T01:00:00+01:00"},
+		{Field: "starts", Op: OpLt, Value: "2024-03-10"},
+	})
+	expect(err).ToEqual(nil)
+	expect(ids(ns)).ToEqual([]string{"c"})
+	ns = g.Nodes()
+	ns.SortBy([]string{"starts"}, false)
+	expect(ids(ns)).ToEqual([]string{"b", "c", "a", "d"})
+	ns.SortBy([]string{"starts"}, true)
+	expect(ids(ns)).ToEqual([]string{"a", "c", "b", "d"})
+	// values must be stored in the stored layout
+	g = g.Set(NodeConfig{ID: "e", Type: g.TypeByID("event"), Attrs: []*Attr{
+		{Name: "starts", Value: "2024-03-10T09:30:00+02:00"},
+	}})
+	expect(rules(g.Validiate())).ToEqual([]string{RuleFormat})
+}
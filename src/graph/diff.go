@@ -0,0 +1,193 @@
+package graph
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Change kinds
+const (
+	Added    = "Added"
+	Removed  = "Removed"
+	Modified = "Modified"
+)
+
+// AttrChange is a single attribute that differs between two versions
+// of a node. Old is nil when the attribute was added and New is nil
+// when it was removed.
+type AttrChange struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	Old  *Attr  `json:"old"`
+	New  *Attr  `json:"new"`
+}
+
+// NodeChange describes how a node differs between two graphs
+type NodeChange struct {
+	ID    string        `json:"id"`
+	Kind  string        `json:"kind"`
+	Old   *Node         `json:"old"`
+	New   *Node         `json:"new"`
+	Attrs []*AttrChange `json:"attrs"`
+}
+
+// EdgeChange describes an edge that was connected, disconnected or
+// had its attrs changed
+type EdgeChange struct {
+	Kind string `json:"kind"`
+	Old  *Edge  `json:"old"`
+	New  *Edge  `json:"new"`
+}
+
+// TypeChange describes a type that was defined or redefined
+type TypeChange struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+	Old  *Type  `json:"old"`
+	New  *Type  `json:"new"`
+}
+
+// Changeset lists every difference between two graphs
+type Changeset struct {
+	Nodes []*NodeChange `json:"nodes"`
+	Edges []*EdgeChange `json:"edges"`
+	Types []*TypeChange `json:"types"`
+}
+
+// Empty reports whether there are no changes
+func (cs *Changeset) Empty() bool {
+	return len(cs.Nodes) == 0 && len(cs.Edges) == 0 && len(cs.Types) == 0
+}
+
+func changeKind(before, after interface{}) string {
+	if reflect.ValueOf(before).IsNil() {
+		return Added
+	}
+	if reflect.ValueOf(after).IsNil() {
+		return Removed
+	}
+	return Modified
+}
+
+// Diff returns the changes required to turn graph a into graph b.
+// Graphs that share history are compared by walking only the parts of
+// their structure that differ.
+func Diff(a, b *Graph) *Changeset {
+	cs := &Changeset{}
+	diffHamt(a.nodes, b.nodes, func(id string, av, bv interface{}) {
+		before, _ := av.(*node)
+		after, _ := bv.(*node)
+		c := &NodeChange{
+			ID:    id,
+			Kind:  changeKind(before, after),
+			Attrs: diffAttrs(before, after),
+		}
+		if before != nil {
+			c.Old = &Node{n: before, g: a}
+		}
+		if after != nil {
+			c.New = &Node{n: after, g: b}
+		}
+		if c.Kind == Modified && len(c.Attrs) == 0 && before.typeID == after.typeID {
+			return
+		}
+		cs.Nodes = append(cs.Nodes, c)
+	})
+	sort.Slice(cs.Nodes, func(i, j int) bool {
+		return cs.Nodes[i].ID < cs.Nodes[j].ID
+	})
+	diffHamt(a.edges, b.edges, func(_ string, av, bv interface{}) {
+		before, _ := av.(*edge)
+		after, _ := bv.(*edge)
+		c := &EdgeChange{
+			Kind: changeKind(before, after),
+		}
+		if before != nil {
+			c.Old = &Edge{e: before, g: a}
+		}
+		if after != nil {
+			c.New = &Edge{e: after, g: b}
+		}
+		if c.Kind == Modified && before.seq == after.seq && reflect.DeepEqual(before.attrs, after.attrs) {
+			return
+		}
+		cs.Edges = append(cs.Edges, c)
+	})
+	sort.Slice(cs.Edges, func(i, j int) bool {
+		return edgeChangeKey(cs.Edges[i]) < edgeChangeKey(cs.Edges[j])
+	})
+	for _, t := range b.types {
+		before := a.TypeByID(t.ID)
+		if before == t || reflect.DeepEqual(before, t) {
+			continue
+		}
+		cs.Types = append(cs.Types, &TypeChange{
+			ID:   t.ID,
+			Kind: changeKind(before, t),
+			Old:  before,
+			New:  t,
+		})
+	}
+	for _, t := range a.types {
+		if b.TypeByID(t.ID) == nil {
+			cs.Types = append(cs.Types, &TypeChange{
+				ID:   t.ID,
+				Kind: Removed,
+				Old:  t,
+			})
+		}
+	}
+	return cs
+}
+
+func edgeChangeKey(c *EdgeChange) string {
+	if c.New != nil {
+		return c.New.e.key()
+	}
+	return c.Old.e.key()
+}
+
+func diffAttrs(before, after *node) []*AttrChange {
+	var oldAttrs, newAttrs []*Attr
+	if before != nil {
+		oldAttrs = before.attrs
+	}
+	if after != nil {
+		newAttrs = after.attrs
+	}
+	find := func(attrs []*Attr, name string) *Attr {
+		for _, attr := range attrs {
+			if attr.Name == name {
+				return attr
+			}
+		}
+		return nil
+	}
+	changes := []*AttrChange{}
+	for _, o := range oldAttrs {
+		n := find(newAttrs, o.Name)
+		if n != nil && ValuesEqual(n.Value, o.Value) && n.Enc == o.Enc {
+			continue
+		}
+		changes = append(changes, &AttrChange{
+			Name: o.Name,
+			Kind: changeKind(o, n),
+			Old:  o,
+			New:  n,
+		})
+	}
+	for _, n := range newAttrs {
+		if find(oldAttrs, n.Name) != nil {
+			continue
+		}
+		changes = append(changes, &AttrChange{
+			Name: n.Name,
+			Kind: Added,
+			New:  n,
+		})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
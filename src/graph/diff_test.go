@@ -0,0 +1,73 @@
+package graph
+
+import (
+	"testing"
+	"testutil"
+)
+
+func TestDiff(t *testing.T) {
+	expect := testutil.Expect(t)
+	a := New()
+	a = a.DefineType(*testType)
+	a = a.Set(NodeConfig{
+		ID:   "1",
+		Type: testType,
+		Attrs: []*Attr{
+			&Attr{Name: "name", Value: "one"},
+			&Attr{Name: "gone", Value: "x"},
+		},
+	})
+	a = a.Set(NodeConfig{ID: "2", Type: testType})
+	a = a.Set(NodeConfig{ID: "3", Type: testType})
+	a = a.Connect(EdgeConfig{From: "1", To: "2", Name: "link"})
+	a = a.Connect(EdgeConfig{From: "1", To: "3", Name: "link"})
+	expect(Diff(a, a).Empty()).ToEqual(true)
+
+	b := a.Set(NodeConfig{
+		ID:   "1",
+		Type: testType,
+		Attrs: []*Attr{
+			&Attr{Name: "name", Value: "ONE"},
+			&Attr{Name: "added", Value: "y"},
+		},
+	})
+	b = b.Remove("2")
+	b = b.Set(NodeConfig{ID: "4", Type: testType})
+	b = b.Connect(EdgeConfig{From: "1", To: "4", Name: "link"})
+	b = b.Connect(EdgeConfig{
+		From:  "1",
+		To:    "3",
+		Name:  "link",
+		Attrs: []*Attr{&Attr{Name: "role", Value: "main"}},
+	})
+	b = b.DefineType(Type{ID: "other", Name: "Other"})
+
+	cs := Diff(a, b)
+	expect(len(cs.Nodes)).ToEqual(3)
+	expect(cs.Nodes[0].ID).ToEqual("1")
+	expect(cs.Nodes[0].Kind).ToEqual(Modified)
+	attrs := cs.Nodes[0].Attrs
+	expect(len(attrs)).ToEqual(3)
+	expect([]string{attrs[0].Name, attrs[0].Kind}).ToEqual([]string{"added", Added})
+	expect([]string{attrs[1].Name, attrs[1].Kind}).ToEqual([]string{"gone", Removed})
+	expect([]string{attrs[2].Name, attrs[2].Kind}).ToEqual([]string{"name", Modified})
+	expect(attrs[2].Old.Value).ToEqual("one")
+	expect(attrs[2].New.Value).ToEqual("ONE")
+	expect(cs.Nodes[1].ID).ToEqual("2")
+	expect(cs.Nodes[1].Kind).ToEqual(Removed)
+	expect(cs.Nodes[1].Old.ID()).ToEqual("2")
+	expect(cs.Nodes[2].ID).ToEqual("4")
+	expect(cs.Nodes[2].Kind).ToEqual(Added)
+
+	expect(len(cs.Edges)).ToEqual(3)
+	expect(cs.Edges[0].Kind).ToEqual(Removed)
+	expect(cs.Edges[0].Old.To().ID()).ToEqual("2")
+	expect(cs.Edges[1].Kind).ToEqual(Modified)
+	expect(cs.Edges[1].New.Attr("role").Value).ToEqual("main")
+	expect(cs.Edges[2].Kind).ToEqual(Added)
+	expect(cs.Edges[2].New.To().ID()).ToEqual("4")
+
+	expect(len(cs.Types)).ToEqual(1)
+	expect(cs.Types[0].ID).ToEqual("other")
+	expect(cs.Types[0].Kind).ToEqual(Added)
+}
@@ -0,0 +1,35 @@
+package graph
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+)
+
+// WriteDOT writes the graph in Graphviz DOT format. Nodes are labelled
+// with their name attr, or id if they do not have one, and carry their
+// type name as their class. Edges are labelled with their name.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	doc := g.Document()
+	names := map[string]string{}
+	for _, t := range doc.Types {
+		names[t.ID] = t.Name
+	}
+	bw := bufio.NewWriter(w)
+	bw.WriteString("digraph G {\n")
+	for _, n := range doc.Nodes {
+		label := n.ID
+		if attr := findAttr(n.Attrs, "name"); attr != nil && !attr.Empty() {
+			label = attr.String()
+		}
+		bw.WriteString("  " + strconv.Quote(n.ID))
+		bw.WriteString(" [label=" + strconv.Quote(label))
+		bw.WriteString(" class=" + strconv.Quote(names[n.TypeID]) + "];\n")
+	}
+	for _, e := range doc.Edges {
+		bw.WriteString("  " + strconv.Quote(e.From) + " -> " + strconv.Quote(e.To))
+		bw.WriteString(" [label=" + strconv.Quote(e.Name) + "];\n")
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
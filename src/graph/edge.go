@@ -1,13 +1,42 @@
 package graph
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
 type edge struct {
 	name     string
 	from     string
 	to       string
-	attrs    map[string]string
+	attrs    []*Attr
 	onDelete func(e *Edge) *Graph
+	seq      uint64 // rank of the edge, lists are sorted by seq
+}
+
+func (e *edge) key() string {
+	return edgeKey(e.from, e.name, e.to)
+}
+
+// other returns the id of the node at the opposite end of the edge to
+// id when following edges in the given direction
+func (e *edge) other(id string, dir string) string {
+	if e.to == id && dir != "Out" {
+		return e.from
+	}
+	return e.to
+}
+
+func edgeKey(from, name, to string) string {
+	return from + "\x00" + name + "\x00" + to
+}
+
+// sortEdges orders edges by rank, which is the order in which they
+// were connected unless they have since been reordered
+func sortEdges(es []*edge) {
+	sort.Slice(es, func(i, j int) bool {
+		return es[i].seq < es[j].seq
+	})
 }
 
 type Edges []*Edge
@@ -36,10 +65,30 @@ func (e *Edge) Name() string {
 	return e.e.name
 }
 
+// Rank returns the sort key of the edge. Edges are listed in ascending
+// rank order.
+func (e *Edge) Rank() uint64 {
+	return e.e.seq
+}
+
+func (e *Edge) Attr(key string) *Attr {
+	for _, attr := range e.e.attrs {
+		if attr.Name == key {
+			return attr
+		}
+	}
+	return nil
+}
+
+func (e *Edge) Attrs() []*Attr {
+	return e.e.attrs
+}
+
 type EdgeConfig struct {
 	Name     string
 	From     string
 	To       string
+	Attrs    []*Attr
 	OnDelete func(e *Edge) *Graph
 }
 
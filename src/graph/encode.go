@@ -0,0 +1,185 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// snapshotMagic starts every encoded graph and is followed by a single
+// version byte. The version must be bumped whenever the layout of the
+// snapshot types below changes.
+const (
+	snapshotMagic   = "GRAPHT"
+	snapshotVersion = 1
+)
+
+type snapshotHeader struct {
+	Seq   uint64
+	Types []*Type
+	Nodes int
+	Edges int
+}
+
+type snapshotAttr struct {
+	Name  string
+	Enc   string
+	Type  ValueType
+	Value string
+}
+
+type snapshotNode struct {
+	ID     string
+	TypeID string
+	Attrs  []snapshotAttr
+}
+
+type snapshotEdge struct {
+	Name  string
+	From  string
+	To    string
+	Attrs []snapshotAttr
+	Seq   uint64
+}
+
+func encodeAttrs(attrs []*Attr) []snapshotAttr {
+	sas := make([]snapshotAttr, 0, len(attrs))
+	for _, attr := range attrs {
+		sas = append(sas, snapshotAttr{
+			Name:  attr.Name,
+			Enc:   attr.Enc,
+			Type:  attr.Type(),
+			Value: attr.String(),
+		})
+	}
+	return sas
+}
+
+func decodeAttrs(sas []snapshotAttr) ([]*Attr, error) {
+	if len(sas) == 0 {
+		return nil, nil
+	}
+	attrs := make([]*Attr, 0, len(sas))
+	for _, sa := range sas {
+		v, err := Coerce(sa.Value, sa.Type)
+		if err != nil {
+			return nil, fmt.Errorf("attr '%s': %s", sa.Name, err)
+		}
+		attrs = append(attrs, &Attr{
+			Name:  sa.Name,
+			Enc:   sa.Enc,
+			Value: v,
+		})
+	}
+	return attrs, nil
+}
+
+// Encode writes a versioned binary snapshot of the whole graph to w.
+// Nodes are written in id order and edges in the order they were
+// connected. OnDelete callbacks on edges are not encoded.
+func (g *Graph) Encode(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(snapshotMagic); err != nil {
+		return err
+	}
+	if err := bw.WriteByte(snapshotVersion); err != nil {
+		return err
+	}
+	enc := gob.NewEncoder(bw)
+	err := enc.Encode(&snapshotHeader{
+		Seq:   g.seq,
+		Types: g.types,
+		Nodes: g.nodes.count(),
+		Edges: g.edges.count(),
+	})
+	if err != nil {
+		return err
+	}
+	ns := g.Nodes()
+	sort.Sort(ns)
+	for _, n := range ns {
+		err := enc.Encode(&snapshotNode{
+			ID:     n.n.id,
+			TypeID: n.n.typeID,
+			Attrs:  encodeAttrs(n.n.attrs),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, e := range g.match(EdgeMatch{}) {
+		err := enc.Encode(&snapshotEdge{
+			Name:  e.name,
+			From:  e.from,
+			To:    e.to,
+			Attrs: encodeAttrs(e.attrs),
+			Seq:   e.seq,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Decode reads a graph written by Encode
+func Decode(r io.Reader) (*Graph, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(snapshotMagic)+1)
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot header: %s", err)
+	}
+	if string(magic[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, fmt.Errorf("not a graph snapshot")
+	}
+	if v := magic[len(snapshotMagic)]; v != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", v)
+	}
+	dec := gob.NewDecoder(br)
+	var h snapshotHeader
+	if err := dec.Decode(&h); err != nil {
+		return nil, err
+	}
+	g := New()
+	g.seq = h.Seq
+	g.types = h.Types
+	g.resolveTypes()
+	for i := 0; i < h.Nodes; i++ {
+		var sn snapshotNode
+		if err := dec.Decode(&sn); err != nil {
+			return nil, err
+		}
+		attrs, err := decodeAttrs(sn.Attrs)
+		if err != nil {
+			return nil, fmt.Errorf("node '%s' %s", sn.ID, err)
+		}
+		g.putNode(&node{
+			id:     sn.ID,
+			typeID: sn.TypeID,
+			attrs:  attrs,
+		})
+	}
+	for i := 0; i < h.Edges; i++ {
+		var se snapshotEdge
+		if err := dec.Decode(&se); err != nil {
+			return nil, err
+		}
+		if g.node(se.From) == nil || g.node(se.To) == nil {
+			return nil, fmt.Errorf("edge '%s' from '%s' to '%s' references a missing node", se.Name, se.From, se.To)
+		}
+		attrs, err := decodeAttrs(se.Attrs)
+		if err != nil {
+			return nil, fmt.Errorf("edge '%s' %s", se.Name, err)
+		}
+		g.addEdge(&edge{
+			name:  se.Name,
+			from:  se.From,
+			to:    se.To,
+			attrs: attrs,
+			seq:   se.Seq,
+		})
+	}
+	return g, nil
+}
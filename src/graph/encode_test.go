@@ -0,0 +1,56 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"testutil"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := inheritanceGraph()
+	g = g.Set(NodeConfig{
+		ID:   "p1",
+		Type: g.TypeByID("post"),
+		Attrs: []*Attr{
+			&Attr{Name: "title", Value: "hello", Enc: "UTF8"},
+			&Attr{Name: "views", Value: int64(12)},
+			&Attr{Name: "rating", Value: 4.5},
+			&Attr{Name: "draft", Value: false},
+			&Attr{Name: "meta", Value: json.RawMessage(`{"a":1}`), Enc: "JSON"},
+		},
+	})
+	g = g.Set(NodeConfig{ID: "l1", Type: g.TypeByID("landing")})
+	g = g.Connect(EdgeConfig{From: "l1", To: "p1", Name: "b"})
+	g = g.Connect(EdgeConfig{
+		From:  "l1",
+		To:    "p1",
+		Name:  "a",
+		Attrs: []*Attr{&Attr{Name: "pos", Value: int64(1)}},
+	})
+	var buf bytes.Buffer
+	expect(g.Encode(&buf)).ToEqual(nil)
+	g2, err := Decode(&buf)
+	expect(err).ToEqual(nil)
+	expect(Diff(g, g2).Empty()).ToEqual(true)
+	expect(g2.Get("p1").Attr("views").Value).ToEqual(int64(12))
+	expect(g2.Get("p1").Attr("meta").Value).ToEqual(json.RawMessage(`{"a":1}`))
+	expect(fieldNames(g2.TypeByID("post").AllFields())).ToEqual([]string{"title", "slug", "body"})
+	es := g2.Get("l1").Edges(nil, "Out")
+	expect(len(es)).ToEqual(2)
+	expect(es[0].Name()).ToEqual("b")
+	expect(es[1].Attr("pos").Value).ToEqual(int64(1))
+	// later connections continue the sequence
+	g2 = g2.Connect(EdgeConfig{From: "p1", To: "l1", Name: "c"})
+	expect(g2.Edges(EdgeMatch{}).First().Name()).ToEqual("b")
+}
+
+func TestDecodeRejectsUnknownFormat(t *testing.T) {
+	expect := testutil.Expect(t)
+	_, err := Decode(bytes.NewBufferString("nonsense"))
+	expect(err).ToNotBeNil()
+	_, err = Decode(bytes.NewBufferString(snapshotMagic + "\x09"))
+	expect(err.Error()).ToEqual("unsupported snapshot version 9")
+}
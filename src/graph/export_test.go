@@ -0,0 +1,94 @@
+package graph
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"testutil"
+)
+
+// expectSameGraph checks nodes, attr values and edges match, attr
+// encodings are not compared as importers read everything as UTF8
+func expectSameGraph(expect func(vs ...interface{}) *testutil.Expection, g, g2 *Graph) {
+	expect(len(g2.Nodes())).ToEqual(len(g.Nodes()))
+	for _, n := range g.Nodes() {
+		n2 := g2.Get(n.ID())
+		expect(n2).ToNotBeNil()
+		expect(n2.Type().ID).ToEqual(n.Type().ID)
+		for _, attr := range n.Attrs() {
+			expect(n2.Attr(attr.Name).Value).ToEqual(attr.Value)
+		}
+	}
+	expect(len(g2.Edges(EdgeMatch{}))).ToEqual(len(g.Edges(EdgeMatch{})))
+	for _, e := range g.Edges(EdgeMatch{}) {
+		expect(len(g2.Edges(EdgeMatch{From: e.From().ID(), To: e.To().ID(), Name: e.Name()}))).ToEqual(1)
+	}
+}
+
+func TestGraphMLRoundTrip(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := productGraph()
+	var buf bytes.Buffer
+	expect(g.WriteGraphML(&buf)).ToEqual(nil)
+	expect(strings.Contains(buf.String(), `<key id="n.price" for="node" attr.name="price" attr.type="long">`)).ToEqual(true)
+	doc, err := g.ReadGraphML(&buf)
+	expect(err).ToEqual(nil)
+	doc.Types = g.Document().Types
+	g2, err := New().Import(doc, nil)
+	expect(err).ToEqual(nil)
+	expectSameGraph(expect, g, g2)
+	_, err = New().ReadGraphML(strings.NewReader(buf.String()))
+	expect(err).ToNotBeNil()
+
+	// attrs that share the names of the reserved keys stay attrs
+	g = g.Connect(EdgeConfig{From: "post", To: "hat", Name: "mentions", Attrs: []*Attr{
+		{Name: "label", Value: "see also", Enc: "UTF8"},
+	}})
+	buf.Reset()
+	expect(g.WriteGraphML(&buf)).ToEqual(nil)
+	doc, err = g.ReadGraphML(&buf)
+	expect(err).ToEqual(nil)
+	e := doc.Edges[len(doc.Edges)-1]
+	expect(e.Name).ToEqual("mentions")
+	expect(len(e.Attrs)).ToEqual(1)
+	expect(e.Attrs[0].Name).ToEqual("label")
+	expect(e.Attrs[0].Value).ToEqual("see also")
+}
+
+func TestWriteDOT(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := productGraph()
+	var buf bytes.Buffer
+	expect(g.WriteDOT(&buf)).ToEqual(nil)
+	out := buf.String()
+	expect(strings.HasPrefix(out, "digraph G {\n")).ToEqual(true)
+	expect(strings.Contains(out, `  "hat" [label="hat" class="Product"];`)).ToEqual(true)
+	expect(strings.Contains(out, `  "shirt" -> "shirt-red" [label="variant"];`)).ToEqual(true)
+}
+
+func TestJSONLDRoundTrip(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := productGraph()
+	var buf bytes.Buffer
+	expect(g.WriteJSONLD(&buf, "http://example.com/")).ToEqual(nil)
+	expect(strings.Contains(buf.String(), `"@type": "Product"`)).ToEqual(true)
+	doc, err := g.ReadJSONLD(&buf, "http://example.com/")
+	expect(err).ToEqual(nil)
+	doc.Types = g.Document().Types
+	g2, err := New().Import(doc, nil)
+	expect(err).ToEqual(nil)
+	expectSameGraph(expect, g, g2)
+	// terms may be written in full
+	doc, err = g.ReadJSONLD(strings.NewReader(`{
+		"@id": "cap",
+		"@type": "http://example.com/Product",
+		"http://example.com/price": 5,
+		"related": {"@id": "hat"}
+	}`), "http://example.com/")
+	expect(err).ToEqual(nil)
+	expect(doc.Nodes[0].Attrs[0].Name).ToEqual("price")
+	expect(doc.Nodes[0].Attrs[0].Value).ToEqual(int64(5))
+	expect(len(doc.Edges)).ToEqual(1)
+	expect(doc.Edges[0].Name).ToEqual("related")
+}
@@ -0,0 +1,589 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// maxComputeDepth limits how many Computed fields an expression may
+// pass through so that fields which refer to each other through edges
+// fail rather than recurse forever. Fields of a type that refer to each
+// other directly are rejected by ValidateType.
+const maxComputeDepth = 16
+
+var errComputeDepth = errors.New("computed fields nested too deeply")
+
+// expressions holds the parsed expression of each Computed field by
+// source so that it is parsed once rather than for every node
+var expressions sync.Map
+
+// Expression is the parsed form of a Computed field expression.
+//
+// Expressions are made of literals (1, 2.5, "text", 'text', true, false
+// and null), names and function calls combined with + - * / % and
+// parentheses. A name reads the field or attr of that name from the
+// node. Edge fields give the list of connected nodes and a path such as
+// author.name reads name from each of them. + joins the two sides as
+// text if either is a string. Where a single value is needed from a
+// list the first item is used.
+//
+// The functions are concat(a, ...) which joins values as text,
+// count(list), sum(list) and coalesce(a, ...) which returns the first
+// value that is not empty.
+type Expression struct {
+	src  string
+	root expr
+}
+
+// ParseExpression parses src into an Expression
+func ParseExpression(src string) (*Expression, error) {
+	toks, err := lexExpression(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{toks: toks}
+	root, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected '%s' at %d", tok.text, tok.pos)
+	}
+	return &Expression{src: src, root: root}, nil
+}
+
+func (x *Expression) String() string {
+	return x.src
+}
+
+// names returns the first part of each name or path used in x
+func (x *Expression) names() []string {
+	names := []string{}
+	var walk func(e expr)
+	walk = func(e expr) {
+		switch e := e.(type) {
+		case *pathExpr:
+			names = append(names, e.path[0])
+		case *binaryExpr:
+			walk(e.left)
+			walk(e.right)
+		case *callExpr:
+			for _, arg := range e.args {
+				walk(arg)
+			}
+		}
+	}
+	walk(x.root)
+	return names
+}
+
+// fieldExpression returns the parsed expression of the Computed field f
+func fieldExpression(f *Field) (*Expression, error) {
+	if x, ok := expressions.Load(f.Expression); ok {
+		return x.(*Expression), nil
+	}
+	x, err := ParseExpression(f.Expression)
+	if err != nil {
+		return nil, err
+	}
+	expressions.Store(f.Expression, x)
+	return x, nil
+}
+
+// Eval evaluates the expression against n. The result is nil, a stored
+// value type or a list of values.
+func (x *Expression) Eval(n *Node) (interface{}, error) {
+	return x.root.eval(n, 0)
+}
+
+// Compute evaluates the expression of the Computed field f against n
+// and converts the result to the field's ComputedType. Nil is returned
+// when the expression has no value.
+func (n *Node) Compute(f *Field) (interface{}, error) {
+	v, err := n.compute(f, 0)
+	if err == errComputeDepth {
+		return nil, fmt.Errorf("field '%s': %s", f.Name, err)
+	}
+	return v, err
+}
+
+func (n *Node) compute(f *Field, depth int) (interface{}, error) {
+	if depth > maxComputeDepth {
+		return nil, errComputeDepth
+	}
+	x, err := fieldExpression(f)
+	if err != nil {
+		return nil, fmt.Errorf("field '%s': %s", f.Name, err)
+	}
+	v, err := x.root.eval(n, depth)
+	if err == errComputeDepth {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("field '%s': %s", f.Name, err)
+	}
+	v, err = scalar(v)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	return f.Coerce(v, "")
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokName
+	tokOp
+)
+
+type exprToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func lexExpression(src string) ([]exprToken, error) {
+	toks := []exprToken{}
+	rs := []rune(src)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(rs) && (unicode.IsDigit(rs[i]) || rs[i] == '.') {
+				i++
+			}
+			toks = append(toks, exprToken{tokNumber, string(rs[start:i]), start})
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i < len(rs) && (rs[i] == '_' || unicode.IsLetter(rs[i]) || unicode.IsDigit(rs[i])) {
+				i++
+			}
+			toks = append(toks, exprToken{tokName, string(rs[start:i]), start})
+		case r == '"' || r == '\'':
+			start := i
+			var sb strings.Builder
+			for i++; i < len(rs) && rs[i] != r; i++ {
+				if rs[i] == '\\' && i+1 < len(rs) {
+					i++
+				}
+				sb.WriteRune(rs[i])
+			}
+			if i >= len(rs) {
+				return nil, fmt.Errorf("unterminated string at %d", start)
+			}
+			i++
+			toks = append(toks, exprToken{tokString, sb.String(), start})
+		case strings.ContainsRune("+-*/%().,", r):
+			toks = append(toks, exprToken{tokOp, string(r), i})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected '%c' at %d", r, i)
+		}
+	}
+	return append(toks, exprToken{tokEOF, "end of expression", len(rs)}), nil
+}
+
+type exprParser struct {
+	toks []exprToken
+	pos  int
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.toks[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.toks[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *exprParser) isOp(ops string) bool {
+	tok := p.peek()
+	return tok.kind == tokOp && strings.Contains(ops, tok.text)
+}
+
+func (p *exprParser) expect(op string) error {
+	if tok := p.next(); tok.kind != tokOp || tok.text != op {
+		return fmt.Errorf("expected '%s' at %d but found '%s'", op, tok.pos, tok.text)
+	}
+	return nil
+}
+
+func (p *exprParser) parseSum() (expr, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+-") {
+		op := p.next().text
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseProduct() (expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*/%") {
+		op := p.next().text
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (expr, error) {
+	if p.isOp("-") {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &binaryExpr{op: "-", left: &literalExpr{int64(0)}, right: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (expr, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		if i, err := strconv.ParseInt(tok.text, 10, 64); err == nil {
+			return &literalExpr{i}, nil
+		}
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s' at %d", tok.text, tok.pos)
+		}
+		return &literalExpr{f}, nil
+	case tokString:
+		return &literalExpr{tok.text}, nil
+	case tokName:
+		switch tok.text {
+		case "true":
+			return &literalExpr{true}, nil
+		case "false":
+			return &literalExpr{false}, nil
+		case "null":
+			return &literalExpr{nil}, nil
+		}
+		if p.isOp("(") {
+			return p.parseCall(tok)
+		}
+		path := []string{tok.text}
+		for p.isOp(".") {
+			p.next()
+			name := p.next()
+			if name.kind != tokName {
+				return nil, fmt.Errorf("expected name at %d but found '%s'", name.pos, name.text)
+			}
+			path = append(path, name.text)
+		}
+		return &pathExpr{path}, nil
+	case tokOp:
+		if tok.text == "(" {
+			x, err := p.parseSum()
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		}
+	}
+	return nil, fmt.Errorf("unexpected '%s' at %d", tok.text, tok.pos)
+}
+
+func (p *exprParser) parseCall(name exprToken) (expr, error) {
+	fn, ok := exprFuncs[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function '%s' at %d", name.text, name.pos)
+	}
+	p.next()
+	call := &callExpr{name: name.text, fn: fn}
+	for !p.isOp(")") {
+		if len(call.args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+	}
+	p.next()
+	return call, nil
+}
+
+type expr interface {
+	eval(n *Node, depth int) (interface{}, error)
+}
+
+type literalExpr struct {
+	v interface{}
+}
+
+func (x *literalExpr) eval(n *Node, depth int) (interface{}, error) {
+	return x.v, nil
+}
+
+type pathExpr struct {
+	path []string
+}
+
+func (x *pathExpr) eval(n *Node, depth int) (interface{}, error) {
+	v, err := readName(n, x.path[0], depth)
+	if err != nil {
+		return nil, err
+	}
+	for i, name := range x.path[1:] {
+		if v == nil {
+			return nil, nil
+		}
+		ns, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("'%s' is not an Edge field", strings.Join(x.path[:i+1], "."))
+		}
+		vs := []interface{}{}
+		for _, item := range ns {
+			other, ok := item.(*Node)
+			if !ok {
+				return nil, fmt.Errorf("'%s' is not an Edge field", strings.Join(x.path[:i+1], "."))
+			}
+			v, err := readName(other, name, depth)
+			if err != nil {
+				return nil, err
+			}
+			if list, ok := v.([]interface{}); ok {
+				vs = append(vs, list...)
+			} else if v != nil {
+				vs = append(vs, v)
+			}
+		}
+		v = vs
+	}
+	return v, nil
+}
+
+// readName returns the value of the field or attr name on n. Edge
+// fields give a list of the connected nodes.
+func readName(n *Node, name string, depth int) (interface{}, error) {
+	if t := n.Type(); t != nil {
+		if f := t.Field(name); f != nil {
+			switch f.Type {
+			case "Edge":
+				ns := []interface{}{}
+				for _, id := range n.fieldTargets(f) {
+					if other := n.g.Get(id); other != nil {
+						ns = append(ns, other)
+					}
+				}
+				return ns, nil
+			case "Computed":
+				return n.compute(f, depth+1)
+			default:
+				return n.Value(f), nil
+			}
+		}
+	}
+	attr := n.Attr(name)
+	if attr == nil || attr.Empty() {
+		return nil, nil
+	}
+	return attr.Value, nil
+}
+
+// scalar returns a single value from v, taking the first item of lists
+func scalar(v interface{}) (interface{}, error) {
+	if list, ok := v.([]interface{}); ok {
+		if len(list) == 0 {
+			return nil, nil
+		}
+		v = list[0]
+	}
+	if n, ok := v.(*Node); ok {
+		return nil, fmt.Errorf("cannot use node '%s' as a value", n.ID())
+	}
+	return v, nil
+}
+
+type binaryExpr struct {
+	op    string
+	left  expr
+	right expr
+}
+
+func (x *binaryExpr) eval(n *Node, depth int) (interface{}, error) {
+	a, err := evalScalar(x.left, n, depth)
+	if err != nil {
+		return nil, err
+	}
+	b, err := evalScalar(x.right, n, depth)
+	if err != nil {
+		return nil, err
+	}
+	if x.op == "+" {
+		_, as := a.(string)
+		_, bs := b.(string)
+		if as || bs {
+			return FormatValue(a) + FormatValue(b), nil
+		}
+	}
+	if a == nil || b == nil {
+		return nil, nil
+	}
+	ai, aInt := a.(int64)
+	bi, bInt := b.(int64)
+	if aInt && bInt && x.op != "/" {
+		switch x.op {
+		case "+":
+			return ai + bi, nil
+		case "-":
+			return ai - bi, nil
+		case "*":
+			return ai * bi, nil
+		case "%":
+			if bi == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			return ai % bi, nil
+		}
+	}
+	af, err := Coerce(a, FloatValue)
+	if err != nil || TypeOf(a) == BoolValue {
+		return nil, fmt.Errorf("cannot use '%s' in arithmetic", FormatValue(a))
+	}
+	bf, err := Coerce(b, FloatValue)
+	if err != nil || TypeOf(b) == BoolValue {
+		return nil, fmt.Errorf("cannot use '%s' in arithmetic", FormatValue(b))
+	}
+	fa, fb := af.(float64), bf.(float64)
+	switch x.op {
+	case "+":
+		return fa + fb, nil
+	case "-":
+		return fa - fb, nil
+	case "*":
+		return fa * fb, nil
+	case "/":
+		if fb == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return fa / fb, nil
+	default:
+		return nil, fmt.Errorf("'%s' needs Int values", x.op)
+	}
+}
+
+func evalScalar(x expr, n *Node, depth int) (interface{}, error) {
+	v, err := x.eval(n, depth)
+	if err != nil {
+		return nil, err
+	}
+	return scalar(v)
+}
+
+type callExpr struct {
+	name string
+	fn   func(args []interface{}) (interface{}, error)
+	args []expr
+}
+
+func (x *callExpr) eval(n *Node, depth int) (interface{}, error) {
+	args := []interface{}{}
+	for _, arg := range x.args {
+		v, err := arg.eval(n, depth)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	v, err := x.fn(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", x.name, err)
+	}
+	return v, nil
+}
+
+// values flattens args into a list of values, leaving out nil
+func values(args []interface{}) []interface{} {
+	vs := []interface{}{}
+	for _, arg := range args {
+		if list, ok := arg.([]interface{}); ok {
+			vs = append(vs, values(list)...)
+		} else if arg != nil {
+			vs = append(vs, arg)
+		}
+	}
+	return vs
+}
+
+var exprFuncs = map[string]func(args []interface{}) (interface{}, error){
+	"concat": func(args []interface{}) (interface{}, error) {
+		var sb strings.Builder
+		for _, v := range values(args) {
+			if n, ok := v.(*Node); ok {
+				return nil, fmt.Errorf("cannot use node '%s' as a value", n.ID())
+			}
+			sb.WriteString(FormatValue(v))
+		}
+		return sb.String(), nil
+	},
+	"count": func(args []interface{}) (interface{}, error) {
+		return int64(len(values(args))), nil
+	},
+	"sum": func(args []interface{}) (interface{}, error) {
+		var isum int64
+		var fsum float64
+		isFloat := false
+		for _, v := range values(args) {
+			switch v := v.(type) {
+			case int64:
+				isum += v
+			case float64:
+				fsum += v
+				isFloat = true
+			default:
+				return nil, fmt.Errorf("cannot add '%s'", FormatValue(v))
+			}
+		}
+		if isFloat {
+			return fsum + float64(isum), nil
+		}
+		return isum, nil
+	},
+	"coalesce": func(args []interface{}) (interface{}, error) {
+		for _, arg := range args {
+			v, err := scalar(arg)
+			if err != nil {
+				return nil, err
+			}
+			if v != nil && v != "" {
+				return v, nil
+			}
+		}
+		return nil, nil
+	},
+}
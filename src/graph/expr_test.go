@@ -0,0 +1,145 @@
+package graph
+
+import (
+	"testing"
+
+	"testutil"
+)
+
+func computedGraph() *Graph {
+	g := New()
+	g = g.DefineType(Type{
+		ID:   "person",
+		Name: "Person",
+		Fields: Fields{
+			{Name: "name", Type: "Text"},
+		},
+	})
+	g = g.DefineType(Type{
+		ID:   "comment",
+		Name: "Comment",
+		Fields: Fields{
+			{Name: "votes", Type: "Int"},
+		},
+	})
+	g = g.DefineType(Type{
+		ID:   "article",
+		Name: "Article",
+		Fields: Fields{
+			{Name: "title", Type: "Text"},
+			{Name: "subtitle", Type: "Text"},
+			{Name: "price", Type: "Int"},
+			{Name: "qty", Type: "Int"},
+			{Name: "author", Type: "Edge", EdgeName: "author", EdgeDirection: "Out"},
+			{Name: "comments", Type: "Edge", EdgeName: "on", EdgeDirection: "In"},
+			{Name: "fullTitle", Type: "Computed", Expression: `title + ": " + subtitle`},
+			{Name: "byline", Type: "Computed", Expression: `concat(fullTitle, " by ", author.name)`},
+			{Name: "numComments", Type: "Computed", ComputedType: "Int", Expression: "count(comments)"},
+			{Name: "total", Type: "Computed", ComputedType: "Float", Expression: "price * qty / 2"},
+			{Name: "votes", Type: "Computed", ComputedType: "Int", Expression: "sum(comments.votes) + 1"},
+			{Name: "label", Type: "Computed", Expression: "coalesce(subtitle, title)"},
+		},
+	})
+	g = g.Set(NodeConfig{ID: "bob", Type: g.TypeByID("person"), Attrs: []*Attr{
+		{Name: "name", Value: "Bob"},
+	}})
+	g = g.Set(NodeConfig{ID: "a", Type: g.TypeByID("article"), Attrs: []*Attr{
+		{Name: "title", Value: "Go"},
+		{Name: "subtitle", Value: "the good parts"},
+		{Name: "price", Value: int64(3)},
+		{Name: "qty", Value: int64(5)},
+	}})
+	g = g.Set(NodeConfig{ID: "b", Type: g.TypeByID("article"), Attrs: []*Attr{
+		{Name: "title", Value: "Empty"},
+	}})
+	g = g.Connect(EdgeConfig{From: "a", To: "bob", Name: "author"})
+	for i, id := range []string{"c1", "c2"} {
+		g = g.Set(NodeConfig{ID: id, Type: g.TypeByID("comment"), Attrs: []*Attr{
+			{Name: "votes", Value: int64(i + 2)},
+		}})
+		g = g.Connect(EdgeConfig{From: id, To: "a", Name: "on"})
+	}
+	return g
+}
+
+func TestCompute(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := computedGraph()
+	compute := func(id, name string) interface{} {
+		n := g.Get(id)
+		v, err := n.Compute(n.Type().Field(name))
+		expect(err).ToEqual(nil)
+		return v
+	}
+	expect(compute("a", "fullTitle")).ToEqual("Go: the good parts")
+	expect(compute("a", "byline")).ToEqual("Go: the good parts by Bob")
+	expect(compute("a", "numComments")).ToEqual(int64(2))
+	expect(compute("a", "total")).ToEqual(7.5)
+	expect(compute("a", "votes")).ToEqual(int64(6))
+	expect(compute("a", "label")).ToEqual("the good parts")
+	// missing values
+	expect(compute("b", "fullTitle")).ToEqual("Empty: ")
+	expect(compute("b", "byline")).ToEqual("Empty:  by ")
+	expect(compute("b", "numComments")).ToEqual(int64(0))
+	expect(compute("b", "total")).ToEqual(nil)
+	expect(compute("b", "label")).ToEqual("Empty")
+}
+
+func TestParseExpression(t *testing.T) {
+	expect := testutil.Expect(t)
+	x, err := ParseExpression(`-(1 + 2) * 3 % 4 + 'it\'s'`)
+	expect(err).ToEqual(nil)
+	v, err := x.Eval(nil)
+	expect(err).ToEqual(nil)
+	expect(v).ToEqual("-1it's")
+	for _, src := range []string{"", "1 +", "(1", "nope(1)", "a.", `"open`, "1 # 2"} {
+		_, err := ParseExpression(src)
+		expect(err).ToNotBeNil()
+	}
+}
+
+func TestComputedFieldRules(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := computedGraph()
+	// computed values cannot be stored
+	g2 := g.Set(NodeConfig{ID: "a", Type: g.TypeByID("article"), Attrs: []*Attr{
+		{Name: "fullTitle", Value: "x"},
+	}})
+	vs := DefaultValidator(g2.Get("a"))
+	expect(len(vs)).ToEqual(1)
+	expect(vs[0].Rule).ToEqual(RuleComputed)
+	// bad definitions
+	for _, f := range []*Field{
+		{Name: "x", Type: "Computed"},
+		{Name: "x", Type: "Computed", Expression: "1 +"},
+		{Name: "x", Type: "Computed", Expression: "1", ComputedType: "Edge"},
+		{Name: "x", Type: "Computed", Expression: "1", Unique: true},
+	} {
+		expect(g.ValidateType(&Type{ID: "t", Fields: Fields{f}})).ToNotBeNil()
+	}
+	// fields that refer to each other are rejected
+	for _, fs := range []Fields{
+		{{Name: "x", Type: "Computed", Expression: "x + 1"}},
+		{
+			{Name: "x", Type: "Computed", Expression: "y"},
+			{Name: "y", Type: "Computed", Expression: "concat(title, x)"},
+		},
+	} {
+		expect(g.ValidateType(&Type{ID: "loop", Fields: fs})).ToNotBeNil()
+	}
+	err := g.ValidateType(&Type{ID: "loop", Extends: []string{"article"}, Fields: Fields{
+		{Name: "x", Type: "Computed", Expression: "fullTitle + x"},
+	}})
+	expect(err.Error()).ToEqual("field 'x': expression refers to itself through x -> x")
+	// loops through edges fail when computed instead of recursing forever
+	g = g.DefineType(Type{ID: "loop", Name: "Loop", Fields: Fields{
+		{Name: "next", Type: "Edge", EdgeName: "next", EdgeDirection: "Out"},
+		{Name: "x", Type: "Computed", Expression: "next.x"},
+	}})
+	g = g.Set(NodeConfig{ID: "l1", Type: g.TypeByID("loop")})
+	g = g.Set(NodeConfig{ID: "l2", Type: g.TypeByID("loop")})
+	g = g.Connect(EdgeConfig{From: "l1", To: "l2", Name: "next"})
+	g = g.Connect(EdgeConfig{From: "l2", To: "l1", Name: "next"})
+	_, err = g.Get("l1").Compute(g.TypeByID("loop").Field("x"))
+	expect(err.Error()).ToEqual("field 'x': computed fields nested too deeply")
+}
@@ -1,5 +1,11 @@
 package graph
 
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
 type Field struct {
 	Type        string `json:"type"`
 	Name        string `json:"name"`
@@ -10,6 +16,9 @@ type Field struct {
 	Required     bool   `json:"required"`
 	Hint         string `json:"hint"`
 	Unit         string `json:"unit"`
+	Unique       bool   `json:"unique"`  // no two nodes of the declaring type or its subtypes may share a value
+	Indexed      bool   `json:"indexed"` // keep an index of values for Find
+	Default      string `json:"default"` // value for new nodes and nodes without the attr
 
 	// Text opts
 	TextMarkup    string `json:"textMarkup"`
@@ -21,6 +30,163 @@ type Field struct {
 	EdgeToTypeID  string `json:"edgeToTypeID"`
 	EdgeName      string `json:"edgeName"`
 	EdgeDirection string `json:"edgeDirection"`
+	OnDelete      string `json:"onDelete"`
+
+	// Edge cardinality opts
+	EdgeCardinality string `json:"edgeCardinality"`
+	EdgeLimit       int    `json:"edgeLimit"`
+	EdgeOverflow    string `json:"edgeOverflow"`
+
+	// Enum opts
+	EnumValues []string `json:"enumValues"`
+	EnumMulti  bool     `json:"enumMulti"` // value is a JSON list of any of EnumValues
+
+	// DataTable opts
+	DataColumns []*DataColumn `json:"dataColumns"`
+
+	// Computed opts
+	Expression   string `json:"expression"`   // see Expression
+	ComputedType string `json:"computedType"` // Text/Int/Float/Boolean the result is converted to
+}
+
+// Field OnDelete policies decide what happens to connected nodes when
+// a node owning an Edge field is removed
+const (
+	OnDeleteDisconnect = "Disconnect" // remove the edges only (default)
+	OnDeleteCascade    = "Cascade"    // remove the connected nodes too
+	OnDeleteRestrict   = "Restrict"   // refuse to remove while connected
+)
+
+// Field EdgeCardinality decides how many connections an Edge field
+// may hold and EdgeOverflow what happens when one too many is made
+const (
+	CardinalityOne  = "One"  // at most one connection
+	CardinalityMany = "Many" // up to EdgeLimit connections 0=nolimit (default)
+
+	OverflowReject  = "Reject"  // refuse the new connection (default)
+	OverflowReplace = "Replace" // disconnect the oldest connections
+)
+
+// MaxEdges returns the most connections an Edge field may hold or 0
+// if there is no limit
+func (f *Field) MaxEdges() int {
+	if f.EdgeCardinality == CardinalityOne {
+		return 1
+	}
+	return f.EdgeLimit
+}
+
+// matchesEdge reports whether e is one of the connections of the Edge
+// field f on node id
+func (f *Field) matchesEdge(id string, e *edge) bool {
+	if f.EdgeName != "" && f.EdgeName != e.name {
+		return false
+	}
+	switch f.EdgeDirection {
+	case "Out":
+		return e.from == id
+	case "In":
+		return e.to == id
+	default:
+		return e.from == id || e.to == id
+	}
+}
+
+// ValueType returns the type of value stored for the field when it is
+// encoded with enc. Text fields holding JSON keep it as raw JSON.
+func (f *Field) ValueType(enc string) ValueType {
+	fieldType := f.Type
+	if fieldType == "Computed" {
+		fieldType = f.ComputedType
+	}
+	switch fieldType {
+	case "GeoPoint", "DataTable":
+		return JSONValue
+	case "Enum":
+		if f.EnumMulti {
+			return JSONValue
+		}
+		return StringValue
+	case "Int":
+		return IntValue
+	case "Float":
+		return FloatValue
+	case "Boolean":
+		return BoolValue
+	}
+	if enc == "JSON" {
+		return JSONValue
+	}
+	return StringValue
+}
+
+// DefaultValue returns the Default of the field converted to its value
+// type or nil if it has none
+func (f *Field) DefaultValue() interface{} {
+	if f.Default == "" {
+		return nil
+	}
+	v, err := f.Coerce(f.Default, "UTF8")
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+// Coerce converts v into the value stored for the field when it is
+// encoded with enc. Date, DateTime, Time and GeoPoint values are
+// converted to their stored layout. DataTable values are read as JSON
+// rows or, when enc is CSV, as CSV text.
+func (f *Field) Coerce(v interface{}, enc string) (interface{}, error) {
+	if f.Type == "DataTable" && v != "" {
+		var dt *DataTable
+		var err error
+		if enc == "CSV" {
+			dt, err = f.ParseDataTableCSV(FormatValue(v))
+		} else {
+			dt, err = f.ParseDataTable(v)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return dt.Value(), nil
+	}
+	if f.Type == "GeoPoint" && v != "" {
+		p, err := ParseGeoPoint(v)
+		if err != nil {
+			return nil, err
+		}
+		return p.Value(), nil
+	}
+	cv, err := Coerce(v, f.ValueType(enc))
+	if err != nil || !f.Temporal() || cv == "" {
+		return cv, err
+	}
+	return normalizeTime(f.Type, cv)
+}
+
+// EnumSelection returns the values chosen by the value v of an Enum
+// field. An error is returned if any are not one of EnumValues.
+func (f *Field) EnumSelection(v interface{}) ([]string, error) {
+	sel := []string{}
+	if f.EnumMulti {
+		if err := json.Unmarshal([]byte(FormatValue(v)), &sel); err != nil {
+			return nil, fmt.Errorf("'%s' is not a list of values", FormatValue(v))
+		}
+	} else {
+		sel = append(sel, FormatValue(v))
+	}
+	seen := map[string]bool{}
+	for _, s := range sel {
+		if !stringIn(s, f.EnumValues) {
+			return nil, fmt.Errorf("'%s' is not one of %s", s, strings.Join(f.EnumValues, ", "))
+		}
+		if seen[s] {
+			return nil, fmt.Errorf("'%s' is chosen more than once", s)
+		}
+		seen[s] = true
+	}
+	return sel, nil
 }
 
 type Fields []*Field
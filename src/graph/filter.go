@@ -0,0 +1,312 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Condition operators
+const (
+	OpEq       = "eq"       // value equals
+	OpNe       = "ne"       // value does not equal
+	OpLt       = "lt"       // value is less than
+	OpGt       = "gt"       // value is greater than
+	OpLte      = "lte"      // value is less than or equal to
+	OpGte      = "gte"      // value is greater than or equal to
+	OpIn       = "in"       // value equals any of Values
+	OpContains = "contains" // value contains the text, ignoring case
+)
+
+// Condition is a single test applied to nodes by Find. If Edge is set
+// the node must have (or with Exists false must not have) an edge with
+// that name in Direction, otherwise the attr for Field is compared to
+// Value using Op.
+type Condition struct {
+	Field  string
+	Op     string
+	Value  interface{}
+	Values []interface{} // for OpIn
+
+	Edge      string
+	Direction string
+	Exists    bool
+}
+
+func (c *Condition) validate() error {
+	if c.Edge != "" {
+		if c.Direction != "" && c.Direction != "In" && c.Direction != "Out" {
+			return fmt.Errorf("edge condition direction '%s' must be In or Out", c.Direction)
+		}
+		return nil
+	}
+	if c.Field == "" {
+		return fmt.Errorf("condition requires a field or an edge")
+	}
+	switch c.Op {
+	case OpEq, OpNe, OpLt, OpGt, OpLte, OpGte, OpContains:
+		return nil
+	case OpIn:
+		if len(c.Values) == 0 {
+			return fmt.Errorf("condition on '%s': in requires a list of values", c.Field)
+		}
+		return nil
+	default:
+		return fmt.Errorf("condition on '%s': '%s' is not a valid operator", c.Field, c.Op)
+	}
+}
+
+// Match reports whether n passes the condition. Values are coerced to
+// the type of the attr being compared and never match if they cannot
+// be. Values for Date, DateTime and Time fields are converted to the
+// stored layout so they compare in time order.
+func (c *Condition) Match(n *Node) bool {
+	if c.Edge != "" {
+		return (len(n.Edges([]string{c.Edge}, c.Direction)) > 0) == c.Exists
+	}
+	// nodes without a value match on the field's default
+	attr := n.Attr(c.Field)
+	if attr == nil || attr.Empty() {
+		attr = nil
+		if t := n.Type(); t != nil {
+			if f := t.Field(c.Field); f != nil && f.Default != "" {
+				attr = &Attr{Name: c.Field, Value: n.Value(f), Enc: "UTF8"}
+			}
+		}
+		if attr == nil || attr.Empty() {
+			return c.Op == OpNe
+		}
+	}
+	coerce := func(v interface{}) (interface{}, error) {
+		if t := n.Type(); t != nil {
+			if f := t.Field(c.Field); f != nil && f.Temporal() {
+				return f.Coerce(v, attr.Enc)
+			}
+		}
+		return Coerce(v, attr.Type())
+	}
+	equals := func(v interface{}) bool {
+		cv, err := coerce(v)
+		return err == nil && ValuesEqual(attr.Value, cv)
+	}
+	switch c.Op {
+	case OpEq:
+		return equals(c.Value)
+	case OpNe:
+		return !equals(c.Value)
+	case OpIn:
+		for _, v := range c.Values {
+			if equals(v) {
+				return true
+			}
+		}
+		return false
+	case OpLt, OpGt, OpLte, OpGte:
+		cv, err := coerce(c.Value)
+		if err != nil {
+			return false
+		}
+		cmp, ok := compareValues(attr.Value, cv)
+		if !ok {
+			return false
+		}
+		switch c.Op {
+		case OpLt:
+			return cmp < 0
+		case OpGt:
+			return cmp > 0
+		case OpLte:
+			return cmp <= 0
+		}
+		return cmp >= 0
+	case OpContains:
+		return strings.Contains(strings.ToLower(attr.String()), strings.ToLower(FormatValue(c.Value)))
+	}
+	return false
+}
+
+// compareValues orders two values of the same type. The bool result is
+// false if the values cannot be ordered.
+func compareValues(a, b interface{}) (int, bool) {
+	switch a := a.(type) {
+	case int64:
+		b, ok := b.(int64)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case a < b:
+			return -1, true
+		case a > b:
+			return 1, true
+		}
+		return 0, true
+	case float64:
+		b, ok := b.(float64)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case a < b:
+			return -1, true
+		case a > b:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		b, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(a, b), true
+	}
+	return 0, false
+}
+
+// SortBy orders ns by the values of the named fields in turn and then
+// by id. Date, DateTime and Time values are stored so that they sort
+// in time order. Nodes without a value sort after those with one.
+func (ns Nodes) SortBy(names []string, desc bool) {
+	value := func(n *Node, name string) interface{} {
+		if t := n.Type(); t != nil {
+			if f := t.Field(name); f != nil {
+				return n.Value(f)
+			}
+		}
+		if attr := n.Attr(name); attr != nil && !attr.Empty() {
+			return attr.Value
+		}
+		return nil
+	}
+	sort.SliceStable(ns, func(i, j int) bool {
+		for _, name := range names {
+			a, b := value(ns[i], name), value(ns[j], name)
+			switch {
+			case a == nil && b == nil:
+				continue
+			case a == nil:
+				return false
+			case b == nil:
+				return true
+			}
+			cmp, ok := compareValues(a, b)
+			if !ok || cmp == 0 {
+				continue
+			}
+			return (cmp < 0) != desc
+		}
+		if desc {
+			return ns[i].ID() > ns[j].ID()
+		}
+		return ns[i].ID() < ns[j].ID()
+	})
+}
+
+// Find returns the nodes of any of the given types (or all nodes if
+// none are given) that pass every condition, sorted by id. Where an eq
+// or in condition is on a field that is indexed for every type being
+// searched the index is used to find candidates instead of scanning.
+func (g *Graph) Find(types []*Type, cs []*Condition) (Nodes, error) {
+	ns, ok := g.findIndexed(types, cs)
+	if !ok {
+		ns = g.Nodes().FilterType(types...)
+	}
+	found, err := ns.Filter(cs)
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(found)
+	return found, nil
+}
+
+// Filter returns the nodes of ns that pass every condition
+func (ns Nodes) Filter(cs []*Condition) (Nodes, error) {
+	for _, c := range cs {
+		if err := c.validate(); err != nil {
+			return nil, err
+		}
+	}
+	found := Nodes{}
+	for _, n := range ns {
+		matched := true
+		for _, c := range cs {
+			if !c.Match(n) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			found = append(found, n)
+		}
+	}
+	return found, nil
+}
+
+// findIndexed returns candidate nodes using the first condition that
+// can be answered from the index
+func (g *Graph) findIndexed(types []*Type, cs []*Condition) (Nodes, bool) {
+	if len(types) == 0 {
+		return nil, false
+	}
+	// nodes are indexed under their own type so include every subtype
+	searched := []*Type{}
+	seen := map[string]bool{}
+	for _, t := range types {
+		for _, st := range append([]*Type{t}, g.Subtypes(t)...) {
+			if !seen[st.ID] {
+				seen[st.ID] = true
+				searched = append(searched, st)
+			}
+		}
+	}
+	for _, c := range cs {
+		if c.Edge != "" || (c.Op != OpEq && c.Op != OpIn) {
+			continue
+		}
+		values := c.Values
+		if c.Op == OpEq {
+			values = []interface{}{c.Value}
+		}
+		ids := map[string]bool{}
+		indexed := true
+		for _, t := range searched {
+			f := t.Field(c.Field)
+			if f == nil {
+				continue
+			}
+			// nodes using the default are not in the index
+			if f.Default != "" {
+				indexed = false
+				break
+			}
+			for _, v := range values {
+				cv, err := f.Coerce(v, "")
+				if err != nil {
+					continue
+				}
+				found, ok := g.Lookup(t, c.Field, cv)
+				if !ok {
+					indexed = false
+					break
+				}
+				for _, id := range found {
+					ids[id] = true
+				}
+			}
+			if !indexed {
+				break
+			}
+		}
+		if !indexed {
+			continue
+		}
+		ns := Nodes{}
+		for id := range ids {
+			if n := g.Get(id); n != nil {
+				ns = append(ns, n)
+			}
+		}
+		return ns, true
+	}
+	return nil, false
+}
@@ -0,0 +1,105 @@
+package graph
+
+import (
+	"testing"
+
+	"testutil"
+)
+
+func filterGraph() *Graph {
+	g := New().DefineType(Type{
+		ID:   "product",
+		Name: "Product",
+		Fields: Fields{
+			{Name: "name", Type: "Text"},
+			{Name: "sku", Type: "Text", Indexed: true},
+			{Name: "price", Type: "Int"},
+		},
+	})
+	product := g.TypeByID("product")
+	for i, name := range []string{"Red Shirt", "Blue Shirt", "Hat"} {
+		g = g.Set(NodeConfig{ID: string('a' + rune(i)), Type: product, Attrs: []*Attr{
+			{Name: "name", Value: name},
+			{Name: "sku", Value: name[:3]},
+			{Name: "price", Value: int64(10 * (i + 1))},
+		}})
+	}
+	g = g.Connect(EdgeConfig{From: "a", To: "b", Name: "variant"})
+	return g
+}
+
+func foundIDs(ns Nodes) []string {
+	ids := []string{}
+	for _, n := range ns {
+		ids = append(ids, n.ID())
+	}
+	return ids
+}
+
+func TestFind(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := filterGraph()
+	product := g.TypeByID("product")
+	find := func(cs ...*Condition) []string {
+		ns, err := g.Find([]*Type{product}, cs)
+		expect(err).ToEqual(nil)
+		return foundIDs(ns)
+	}
+	expect(find(&Condition{Field: "sku", Op: OpEq, Value: "Hat"})).ToEqual([]string{"c"})
+	expect(find(&Condition{Field: "sku", Op: OpIn, Values: []interface{}{"Red", "Hat"}})).ToEqual([]string{"a", "c"})
+	expect(find(&Condition{Field: "name", Op: OpNe, Value: "Hat"})).ToEqual([]string{"a", "b"})
+	expect(find(&Condition{Field: "price", Op: OpGt, Value: "10"})).ToEqual([]string{"b", "c"})
+	expect(find(&Condition{Field: "price", Op: OpLt, Value: int64(30)})).ToEqual([]string{"a", "b"})
+	expect(find(&Condition{Field: "name", Op: OpContains, Value: "shirt"})).ToEqual([]string{"a", "b"})
+	expect(find(
+		&Condition{Field: "name", Op: OpContains, Value: "shirt"},
+		&Condition{Edge: "variant", Direction: "Out", Exists: false},
+	)).ToEqual([]string{"b"})
+	expect(find(&Condition{Edge: "variant", Exists: true})).ToEqual([]string{"a", "b"})
+	_, err := g.Find(nil, []*Condition{{Field: "name", Op: "like"}})
+	expect(err).ToNotBeNil()
+}
+
+func TestFindUsesIndex(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := filterGraph()
+	product := g.TypeByID("product")
+	cs := []*Condition{{Field: "sku", Op: OpEq, Value: "Blu"}}
+	ns, ok := g.findIndexed([]*Type{product}, cs)
+	expect(ok).ToEqual(true)
+	expect(foundIDs(ns)).ToEqual([]string{"b"})
+	_, ok = g.findIndexed([]*Type{product}, []*Condition{{Field: "name", Op: OpEq, Value: "Hat"}})
+	expect(ok).ToEqual(false)
+	// the index follows later versions of the graph
+	g2 := g.Set(NodeConfig{ID: "b", Type: product, Attrs: []*Attr{{Name: "sku", Value: "Grn"}}})
+	ns, _ = g2.findIndexed([]*Type{product}, cs)
+	expect(len(ns)).ToEqual(0)
+	ns, _ = g.findIndexed([]*Type{product}, cs)
+	expect(len(ns)).ToEqual(1)
+}
+
+func TestFindDefaults(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := New().DefineType(Type{
+		ID:   "product",
+		Name: "Product",
+		Fields: Fields{
+			{Name: "sku", Type: "Text", Indexed: true, Default: "Std"},
+			{Name: "level", Type: "Int", Default: "3"},
+		},
+	})
+	product := g.TypeByID("product")
+	g = g.Set(NodeConfig{ID: "a", Type: product})
+	g = g.Set(NodeConfig{ID: "b", Type: product, Attrs: []*Attr{{Name: "sku", Value: "Big"}, {Name: "level", Value: int64(1)}}})
+	find := func(cs ...*Condition) []string {
+		ns, err := g.Find([]*Type{product}, cs)
+		expect(err).ToEqual(nil)
+		return foundIDs(ns)
+	}
+	// nodes without a value match on the default
+	expect(find(&Condition{Field: "sku", Op: OpEq, Value: "Std"})).ToEqual([]string{"a"})
+	expect(find(&Condition{Field: "level", Op: OpGt, Value: "2"})).ToEqual([]string{"a"})
+	expect(find(&Condition{Field: "level", Op: OpNe, Value: "3"})).ToEqual([]string{"b"})
+	_, ok := g.findIndexed([]*Type{product}, []*Condition{{Field: "sku", Op: OpEq, Value: "Std"}})
+	expect(ok).ToEqual(false)
+}
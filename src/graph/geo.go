@@ -0,0 +1,141 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// earthRadius is the mean radius of the earth in metres
+const earthRadius = 6371008.8
+
+// GeoPoint is a position on the earth in degrees. It is stored as a
+// JSON object with lat and lng keys.
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// ParseGeoPoint reads a GeoPoint from a stored value or from text of
+// the form "lat,lng"
+func ParseGeoPoint(v interface{}) (GeoPoint, error) {
+	s := strings.TrimSpace(FormatValue(v))
+	p := GeoPoint{}
+	if strings.HasPrefix(s, "{") {
+		var obj map[string]*float64
+		if err := json.Unmarshal([]byte(s), &obj); err != nil || obj["lat"] == nil || obj["lng"] == nil {
+			return p, fmt.Errorf("'%s' is not a valid GeoPoint", s)
+		}
+		p.Lat, p.Lng = *obj["lat"], *obj["lng"]
+	} else {
+		parts := strings.Split(s, ",")
+		if len(parts) != 2 {
+			return p, fmt.Errorf("'%s' is not a valid GeoPoint", s)
+		}
+		lat, err1 := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		lng, err2 := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err1 != nil || err2 != nil {
+			return p, fmt.Errorf("'%s' is not a valid GeoPoint", s)
+		}
+		p.Lat, p.Lng = lat, lng
+	}
+	return p, p.Validate()
+}
+
+// Validate checks the point is within the range of lat and lng
+func (p GeoPoint) Validate() error {
+	if math.IsNaN(p.Lat) || p.Lat < -90 || p.Lat > 90 {
+		return fmt.Errorf("lat %v is not between -90 and 90", p.Lat)
+	}
+	if math.IsNaN(p.Lng) || p.Lng < -180 || p.Lng > 180 {
+		return fmt.Errorf("lng %v is not between -180 and 180", p.Lng)
+	}
+	return nil
+}
+
+// Value returns the stored form of the point
+func (p GeoPoint) Value() json.RawMessage {
+	b, _ := json.Marshal(p)
+	return json.RawMessage(b)
+}
+
+// Distance returns the great circle distance to q in metres
+func (p GeoPoint) Distance(q GeoPoint) float64 {
+	lat1, lat2 := radians(p.Lat), radians(q.Lat)
+	dLat, dLng := lat2-lat1, radians(q.Lng-p.Lng)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Around returns the bounds of the circle of radius metres around p
+func (p GeoPoint) Around(radius float64) GeoBounds {
+	d := degrees(radius / earthRadius)
+	b := GeoBounds{North: p.Lat + d, South: p.Lat - d, West: -180, East: 180}
+	if b.North >= 90 || b.South <= -90 {
+		// the circle covers a pole so includes every lng
+		b.North, b.South = math.Min(b.North, 90), math.Max(b.South, -90)
+		return b
+	}
+	sin := math.Sin(radians(d)) / math.Cos(radians(p.Lat))
+	if sin >= 1 {
+		return b
+	}
+	dLng := degrees(math.Asin(sin))
+	b.West, b.East = wrapLng(p.Lng-dLng), wrapLng(p.Lng+dLng)
+	return b
+}
+
+// GeoBounds is the area between two lines of latitude and two of
+// longitude. West is greater than East when the area crosses the
+// antimeridian.
+type GeoBounds struct {
+	North float64 `json:"north"`
+	South float64 `json:"south"`
+	East  float64 `json:"east"`
+	West  float64 `json:"west"`
+}
+
+// Validate checks the bounds are in range and north of south
+func (b GeoBounds) Validate() error {
+	for _, p := range []GeoPoint{{b.North, b.East}, {b.South, b.West}} {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+	}
+	if b.South > b.North {
+		return fmt.Errorf("south %v is north of %v", b.South, b.North)
+	}
+	return nil
+}
+
+// Contains reports whether p is inside the bounds
+func (b GeoBounds) Contains(p GeoPoint) bool {
+	if p.Lat < b.South || p.Lat > b.North {
+		return false
+	}
+	if b.West <= b.East {
+		return p.Lng >= b.West && p.Lng <= b.East
+	}
+	return p.Lng >= b.West || p.Lng <= b.East
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+func wrapLng(lng float64) float64 {
+	for lng < -180 {
+		lng += 360
+	}
+	for lng > 180 {
+		lng -= 360
+	}
+	return lng
+}
@@ -0,0 +1,47 @@
+package graph
+
+import (
+	"math"
+	"testing"
+
+	"testutil"
+)
+
+func TestParseGeoPoint(t *testing.T) {
+	expect := testutil.Expect(t)
+	p, err := ParseGeoPoint("51.5074, -0.1278")
+	expect(err).ToEqual(nil)
+	expect(p).ToEqual(GeoPoint{Lat: 51.5074, Lng: -0.1278})
+	p, err = ParseGeoPoint(p.Value())
+	expect(err).ToEqual(nil)
+	expect(string(p.Value())).ToEqual(`{"lat":51.5074,"lng":-0.1278}`)
+	for _, s := range []string{"", "51.5", "91,0", "0,181", `{"lat":1}`, "a,b"} {
+		_, err := ParseGeoPoint(s)
+		expect(err).ToNotBeNil()
+	}
+	f := &Field{Name: "at", Type: "GeoPoint"}
+	v, err := f.Coerce("48.8566,2.3522", "UTF8")
+	expect(err).ToEqual(nil)
+	expect(TypeOf(v)).ToEqual(JSONValue)
+}
+
+func TestGeoDistanceAndBounds(t *testing.T) {
+	expect := testutil.Expect(t)
+	london := GeoPoint{Lat: 51.5074, Lng: -0.1278}
+	paris := GeoPoint{Lat: 48.8566, Lng: 2.3522}
+	expect(math.Round(london.Distance(paris) / 1000)).ToEqual(float64(344))
+	expect(london.Distance(london)).ToEqual(float64(0))
+	b := london.Around(400000)
+	expect(b.Contains(paris)).ToEqual(true)
+	expect(london.Around(100000).Contains(paris)).ToEqual(false)
+	// bounds that cross the antimeridian
+	fiji := GeoPoint{Lat: -17.7, Lng: 178.1}
+	b = fiji.Around(500000)
+	expect(b.West > b.East).ToEqual(true)
+	expect(b.Contains(GeoPoint{Lat: -17.7, Lng: -179.5})).ToEqual(true)
+	expect(b.Contains(GeoPoint{Lat: -17.7, Lng: 170})).ToEqual(false)
+	// circles over a pole include every lng
+	b = GeoPoint{Lat: 89.9, Lng: 0}.Around(50000)
+	expect(b.Contains(GeoPoint{Lat: 89.95, Lng: 180})).ToEqual(true)
+	expect(GeoBounds{North: 1, South: 2}.Validate()).ToNotBeNil()
+}
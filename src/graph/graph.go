@@ -1,43 +1,58 @@
 package graph
 
-import "fmt"
-
-var DefaultValidator = func(n *Node) {
-
-}
+import (
+	"fmt"
+	"sort"
+)
 
+// Graph is an immutable set of types, nodes and edges. Every write
+// returns a new Graph that shares structure with the original.
+//
+// Nodes are held in a persistent map keyed by id and edges are held
+// in a persistent map keyed by (from,name,to) with additional
+// adjacency indexes by source, target and edge name so that lookups
+// never need to scan the whole graph.
 type Graph struct {
-	nodes []*node
-	edges []*edge
+	nodes *hamt // id => *node
+	edges *hamt // edgeKey => *edge
+	out   *hamt // from id => *hamt of edgeKey => *edge
+	in    *hamt // to id => *hamt of edgeKey => *edge
+	named *hamt // edge name => *hamt of edgeKey => *edge
+	index *hamt // typeID => *hamt of attr values, see index.go
 	types []*Type
-}
-
-func (g *Graph) Validiate() error {
-	return nil
+	seq   uint64
 }
 
 func (g *Graph) clone() *Graph {
 	return &Graph{
 		nodes: g.nodes,
 		edges: g.edges,
+		out:   g.out,
+		in:    g.in,
+		named: g.named,
+		index: g.index,
 		types: g.types,
+		seq:   g.seq,
 	}
 }
 
+func (g *Graph) node(id string) *node {
+	v, ok := g.nodes.get(id)
+	if !ok {
+		return nil
+	}
+	return v.(*node)
+}
+
 func (g *Graph) Set(v NodeConfig) *Graph {
 	g2 := g.clone()
-	g2.nodes = []*node{}
-	var old *node
-	for _, n := range g.nodes {
-		if n.id == v.ID {
-			old = n
-			continue
-		}
-		g2.nodes = append(g2.nodes, n)
-	}
+	old := g.node(v.ID)
 	if v.Type == nil && old == nil {
 		panic("cannot set node without type")
 	}
+	if v.Type != nil && v.Type.Abstract {
+		panic(fmt.Sprintf("cannot set node of abstract type '%s'", v.Type.ID))
+	}
 	n := &node{
 		id:    v.ID,
 		attrs: v.Attrs,
@@ -57,42 +72,112 @@ func (g *Graph) Set(v NodeConfig) *Graph {
 			return false
 		}
 		for _, oldAttr := range old.attrs {
-			if inNew(oldAttr.Name) {
+			if inNew(oldAttr.Name) || stringIn(oldAttr.Name, v.RemoveAttrs) {
 				continue
 			}
 			n.attrs = append(n.attrs, oldAttr)
 		}
 	}
-	g2.nodes = append(g2.nodes, n)
+	g2.putNode(n)
 	return g2
 }
 
+// RestrictError is returned when a node cannot be removed because one
+// of its Edge fields has the Restrict policy and is still connected
+type RestrictError struct {
+	NodeID string
+	Field  string
+	Target string
+}
+
+func (err *RestrictError) Error() string {
+	return fmt.Sprintf("cannot remove node '%s': field '%s' is still connected to '%s'", err.NodeID, err.Field, err.Target)
+}
+
+// Remove removes a node and its edges without applying the OnDelete
+// policies of its Edge fields. Use Delete to apply them.
 func (g *Graph) Remove(id string) *Graph {
-	g2 := g.clone()
-	g2.nodes = []*node{}
-	for _, n := range g.nodes {
-		if n.id == id {
+	if g.Get(id) == nil {
+		return g
+	}
+	return g.remove(id)
+}
+
+// Delete removes a node and its edges, applying the OnDelete policy of
+// each Edge field on the type of every node removed. It returns the
+// removed nodes starting with id followed by any that were cascaded.
+func (g *Graph) Delete(id string) (*Graph, Nodes, error) {
+	root := g.Get(id)
+	if root == nil {
+		return g, Nodes{}, nil
+	}
+	removing := map[string]bool{id: true}
+	removed := Nodes{}
+	restrictions := []*RestrictError{}
+	queue := Nodes{root}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		removed = append(removed, n)
+		t := n.Type()
+		if t == nil {
 			continue
 		}
-		g2.nodes = append(g2.nodes, n)
+		for _, f := range t.AllFields() {
+			if f.Type != "Edge" {
+				continue
+			}
+			if f.OnDelete != OnDeleteCascade && f.OnDelete != OnDeleteRestrict {
+				continue
+			}
+			for _, target := range n.fieldTargets(f) {
+				if f.OnDelete == OnDeleteRestrict {
+					restrictions = append(restrictions, &RestrictError{
+						NodeID: n.ID(),
+						Field:  f.Name,
+						Target: target,
+					})
+					continue
+				}
+				if removing[target] {
+					continue
+				}
+				if other := g.Get(target); other != nil {
+					removing[target] = true
+					queue = append(queue, other)
+				}
+			}
+		}
+	}
+	// connections to nodes that are being removed anyway are allowed
+	for _, err := range restrictions {
+		if !removing[err.Target] {
+			return g, nil, err
+		}
 	}
+	g2 := g
+	for _, n := range removed {
+		g2 = g2.remove(n.ID())
+	}
+	return g2, removed, nil
+}
+
+func (g *Graph) remove(id string) *Graph {
+	g2 := g.clone()
+	g2.dropNode(id)
 	g2 = g2.Disconnect(EdgeMatch{From: id})
 	g2 = g2.Disconnect(EdgeMatch{To: id})
 	return g2
 }
 
 func (g *Graph) Disconnect(cfg EdgeMatch) *Graph {
+	removed := g.match(cfg)
+	if len(removed) == 0 {
+		return g
+	}
 	g2 := g.clone()
-	g2.edges = []*edge{}
-	removed := []*edge{}
-	for _, e := range g.edges {
-		if (cfg.Name == "" || cfg.Name == e.name) &&
-			(cfg.From == "" || cfg.From == e.from) &&
-			(cfg.To == "" || cfg.To == e.to) {
-			removed = append(removed, e)
-			continue
-		}
-		g2.edges = append(g2.edges, e)
+	for _, e := range removed {
+		g2.removeEdge(e)
 	}
 	for _, e := range removed {
 		if e.onDelete != nil {
@@ -108,11 +193,6 @@ func (g *Graph) Connect(cfg EdgeConfig) *Graph {
 	if err := cfg.Validate(); err != nil {
 		panic(err)
 	}
-	g2.edges = g.edges
-	if i := g2.indexOfDupConnection(cfg); i != -1 {
-		g2.edges = append(g2.edges[:i], g2.edges[i+1:]...)
-
-	}
 	if cfg.Name == "" {
 		panic(fmt.Sprintf("name cannot be blank"))
 	}
@@ -122,46 +202,221 @@ func (g *Graph) Connect(cfg EdgeConfig) *Graph {
 	if g.Get(cfg.To) == nil {
 		panic(fmt.Sprintf("from node '%s' does not exist", cfg.To))
 	}
-	// check for dup
-	g2.edges = append(g2.edges, &edge{
+	e := &edge{
 		from:     cfg.From,
 		to:       cfg.To,
 		name:     cfg.Name,
+		attrs:    cfg.Attrs,
 		onDelete: cfg.OnDelete,
+	}
+	// replace any duplicate keeping its position
+	if old, ok := g.edges.get(e.key()); ok {
+		g2.removeEdge(old.(*edge))
+		e.seq = old.(*edge).seq
+	} else {
+		g2.seq++
+		e.seq = g2.seq
+	}
+	g2.addEdge(e)
+	return g2
+}
+
+// Link is like Connect but also applies the cardinality of the Edge
+// fields at both ends of the new edge. Fields with the Replace overflow
+// policy disconnect their oldest connections to make room. Fields that
+// reject overflow are left over their limit to be reported by Validate.
+func (g *Graph) Link(cfg EdgeConfig) *Graph {
+	g2 := g.Connect(cfg)
+	v, _ := g2.edges.get(edgeKey(cfg.From, cfg.Name, cfg.To))
+	e := v.(*edge)
+	ends := []string{cfg.From}
+	if cfg.To != cfg.From {
+		ends = append(ends, cfg.To)
+	}
+	for _, id := range ends {
+		n := g2.Get(id)
+		if n == nil || n.Type() == nil {
+			continue
+		}
+		t := n.Type()
+		for _, f := range t.AllFields() {
+			max := f.MaxEdges()
+			if f.Type != "Edge" || max == 0 || f.EdgeOverflow != OverflowReplace {
+				continue
+			}
+			if !f.matchesEdge(id, e) {
+				continue
+			}
+			es := g2.Get(id).FieldEdges(f)
+			over := len(es) - max
+			for _, old := range es {
+				if over <= 0 {
+					break
+				}
+				if old.e == e {
+					continue
+				}
+				g2 = g2.Disconnect(EdgeMatch{
+					From: old.e.from,
+					Name: old.e.name,
+					To:   old.e.to,
+				})
+				over--
+			}
+		}
+	}
+	return g2
+}
+
+// ReorderEdges rearranges es into the order given. The edges swap the
+// ranks they already hold between them so their position relative to
+// any other edges is unchanged.
+func (g *Graph) ReorderEdges(es Edges) *Graph {
+	current := make([]*edge, 0, len(es))
+	ranks := make([]uint64, 0, len(es))
+	seen := map[string]bool{}
+	for _, e := range es {
+		k := e.e.key()
+		v, ok := g.edges.get(k)
+		if !ok {
+			panic(fmt.Sprintf("cannot reorder edge '%s' from '%s' to '%s': not connected", e.e.name, e.e.from, e.e.to))
+		}
+		if seen[k] {
+			panic(fmt.Sprintf("cannot reorder edge '%s' from '%s' to '%s': listed twice", e.e.name, e.e.from, e.e.to))
+		}
+		seen[k] = true
+		current = append(current, v.(*edge))
+		ranks = append(ranks, v.(*edge).seq)
+	}
+	sort.Slice(ranks, func(i, j int) bool {
+		return ranks[i] < ranks[j]
 	})
+	g2 := g.clone()
+	for i, old := range current {
+		if old.seq == ranks[i] {
+			continue
+		}
+		e := *old
+		e.seq = ranks[i]
+		g2.removeEdge(old)
+		g2.addEdge(&e)
+	}
 	return g2
 }
 
-func (g *Graph) indexOfDupConnection(cfg EdgeConfig) int {
-	for i, e := range g.edges {
-		if e.from == cfg.From &&
-			e.to == cfg.To &&
-			e.name == cfg.Name {
-			return i
+// MoveEdge moves the edge matching m to the given position among the
+// edges with the same name and source node
+func (g *Graph) MoveEdge(m EdgeMatch, position int) *Graph {
+	list := g.Edges(EdgeMatch{From: m.From, Name: m.Name})
+	es := Edges{}
+	var moving *Edge
+	for _, e := range list {
+		if e.e.to == m.To {
+			moving = e
+			continue
 		}
+		es = append(es, e)
+	}
+	if moving == nil {
+		panic(fmt.Sprintf("cannot move edge '%s' from '%s' to '%s': not connected", m.Name, m.From, m.To))
+	}
+	if position < 0 {
+		position = 0
 	}
-	return -1
+	if position > len(es) {
+		position = len(es)
+	}
+	es = append(es[:position], append(Edges{moving}, es[position:]...)...)
+	return g.ReorderEdges(es)
+}
+
+// addEdge and removeEdge update the edge indexes in place so must
+// only be called on a graph returned from clone
+func (g *Graph) addEdge(e *edge) {
+	k := e.key()
+	g.edges = g.edges.set(k, e)
+	g.out = indexEdge(g.out, e.from, k, e)
+	g.in = indexEdge(g.in, e.to, k, e)
+	g.named = indexEdge(g.named, e.name, k, e)
+}
+
+func (g *Graph) removeEdge(e *edge) {
+	k := e.key()
+	g.edges = g.edges.delete(k)
+	g.out = unindexEdge(g.out, e.from, k)
+	g.in = unindexEdge(g.in, e.to, k)
+	g.named = unindexEdge(g.named, e.name, k)
+}
+
+func indexEdge(idx *hamt, id string, k string, e *edge) *hamt {
+	v, _ := idx.get(id)
+	set, _ := v.(*hamt)
+	return idx.set(id, set.set(k, e))
+}
+
+func unindexEdge(idx *hamt, id string, k string) *hamt {
+	v, ok := idx.get(id)
+	if !ok {
+		return idx
+	}
+	set := v.(*hamt).delete(k)
+	if set.count() == 0 {
+		return idx.delete(id)
+	}
+	return idx.set(id, set)
+}
+
+// match returns all edges matching m in the order they were connected
+func (g *Graph) match(m EdgeMatch) []*edge {
+	if m.From != "" && m.Name != "" && m.To != "" {
+		if v, ok := g.edges.get(edgeKey(m.From, m.Name, m.To)); ok {
+			return []*edge{v.(*edge)}
+		}
+		return nil
+	}
+	// pick the narrowest index available
+	set := g.edges
+	var v interface{}
+	if m.From != "" {
+		v, _ = g.out.get(m.From)
+		set, _ = v.(*hamt)
+	} else if m.To != "" {
+		v, _ = g.in.get(m.To)
+		set, _ = v.(*hamt)
+	} else if m.Name != "" {
+		v, _ = g.named.get(m.Name)
+		set, _ = v.(*hamt)
+	}
+	es := []*edge{}
+	set.each(func(_ string, v interface{}) bool {
+		e := v.(*edge)
+		if (m.Name == "" || m.Name == e.name) &&
+			(m.From == "" || m.From == e.from) &&
+			(m.To == "" || m.To == e.to) {
+			es = append(es, e)
+		}
+		return true
+	})
+	sortEdges(es)
+	return es
 }
 
 // Get a node from the graph by id
 func (g *Graph) Get(id string) *Node {
-	for _, n := range g.nodes {
-		if n.id == id {
-			return &Node{n: n, g: g}
-		}
+	n := g.node(id)
+	if n == nil {
+		return nil
 	}
-	return nil
+	return &Node{n: n, g: g}
 }
 
+// DefineType adds or replaces a type. The inherited fields of every
+// type that extends it are updated to match.
 func (g *Graph) DefineType(t Type) *Graph {
-	if t.ID == "" {
-		panic("type id is required")
-	}
-	for _, f := range t.Fields {
-		if f.Name == "" {
-			panic("cannot create field with blank name")
-		}
+	if err := g.ValidateType(&t); err != nil {
+		panic(err)
 	}
+	t.inherited = nil
 	g2 := g.clone()
 	g2.types = []*Type{}
 	for _, tt := range g.types {
@@ -171,6 +426,8 @@ func (g *Graph) DefineType(t Type) *Graph {
 		g2.types = append(g2.types, tt)
 	}
 	g2.types = append(g2.types, &t)
+	g2.resolveTypes()
+	g2.reindexTypes(g)
 	return g2
 }
 
@@ -196,25 +453,22 @@ func (g *Graph) Types() []*Type {
 	return g.types
 }
 
+// Nodes returns every node in the graph in no particular order
 func (g *Graph) Nodes() Nodes {
-	ns := Nodes{}
-	for _, n := range g.nodes {
+	ns := make(Nodes, 0, g.nodes.count())
+	g.nodes.each(func(_ string, v interface{}) bool {
 		ns = append(ns, &Node{
-			n: n,
+			n: v.(*node),
 			g: g,
 		})
-	}
+		return true
+	})
 	return ns
 }
 
 func (g *Graph) Edges(m EdgeMatch) Edges {
 	es := Edges{}
-	for _, e := range g.edges {
-		if (m.Name != "" && m.Name != e.name) ||
-			(m.From != "" && m.From != e.from) ||
-			(m.To != "" && m.To != e.to) {
-			continue
-		}
+	for _, e := range g.match(m) {
 		es = append(es, &Edge{
 			e: e,
 			g: g,
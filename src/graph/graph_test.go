@@ -1,12 +1,21 @@
 package graph
 
-import "testing"
-import "testutil"
+import (
+	"fmt"
+	"testing"
+	"testutil"
+)
+
+var testType = &Type{
+	ID:   "test",
+	Name: "Test",
+}
 
 func TestAddNode(t *testing.T) {
 	g := New()
 	g = g.Set(NodeConfig{
-		ID: "alice",
+		ID:   "alice",
+		Type: testType,
 	})
 	expect := testutil.Expect(t)
 	expect(g.Get("alice")).ToNotBeNil()
@@ -15,7 +24,8 @@ func TestAddNode(t *testing.T) {
 func TestAddNodeDoesntModifyOld(t *testing.T) {
 	g := New()
 	_ = g.Set(NodeConfig{
-		ID: "alice",
+		ID:   "alice",
+		Type: testType,
 	})
 	expect := testutil.Expect(t)
 	expect(g.Get("alice")).ToBeNil()
@@ -24,52 +34,58 @@ func TestAddNodeDoesntModifyOld(t *testing.T) {
 func TestSetNodeAttr(t *testing.T) {
 	g := New()
 	g = g.Set(NodeConfig{
-		ID: "1",
-		Attrs: []Attr{
-			Attr{
+		ID:   "1",
+		Type: testType,
+		Attrs: []*Attr{
+			&Attr{
 				Name:  "name",
 				Value: "alice",
 			},
 		},
 	})
 	expect := testutil.Expect(t)
-	expect(g.Get("1").Attr("name")).ToEqual("alice")
+	expect(g.Get("1").Attr("name").Value).ToEqual("alice")
 }
 
 func TestSetNodeAttrDoesNotModifyOld(t *testing.T) {
 	g := New()
 	g = g.Set(NodeConfig{
-		ID: "1",
-		Attrs: []Attr{
-			Attr{
+		ID:   "1",
+		Type: testType,
+		Attrs: []*Attr{
+			&Attr{
 				Name:  "name",
 				Value: "alice",
 			},
 		},
 	})
 	_ = g.Set(NodeConfig{
-		ID: "1",
-		Attrs: []Attr{
-			Attr{
+		ID:   "1",
+		Type: testType,
+		Attrs: []*Attr{
+			&Attr{
 				Name:  "name",
 				Value: "bob",
 			},
 		},
 	})
 	expect := testutil.Expect(t)
-	expect(g.Get("1").Attr("name")).ToEqual("alice")
+	expect(g.Get("1").Attr("name").Value).ToEqual("alice")
 }
 
 func TestHasManyConnection(t *testing.T) {
 	g := New()
 	g = g.Set(NodeConfig{
-		ID: "alice",
+		ID:   "alice",
+		Type: testType,
 	})
 	g = g.Set(NodeConfig{
-		ID: "bob",
+		ID:   "bob",
+		Type: testType,
 	})
 	g = g.Set(NodeConfig{
-		ID: "jeff",
+		ID:   "jeff",
+		Type: testType,
 	})
 	g = g.Connect(EdgeConfig{
 		From: "alice",
@@ -82,19 +98,21 @@ func TestHasManyConnection(t *testing.T) {
 		Name: "friend",
 	})
 	expect := testutil.Expect(t)
-	expect(len(g.Get("alice").Out())).ToEqual(2)
-	expect(g.Get("alice").Out()[0].Node().ID()).ToEqual("bob")
-	expect(g.Get("alice").Out()[1].Node().ID()).ToEqual("jeff")
-	expect(g.Get("bob").In()[0].Node().ID()).ToEqual("alice")
+	expect(len(g.Get("alice").Edges(nil, "Out"))).ToEqual(2)
+	expect(g.Get("alice").Edges(nil, "Out")[0].To().ID()).ToEqual("bob")
+	expect(g.Get("alice").Edges(nil, "Out")[1].To().ID()).ToEqual("jeff")
+	expect(g.Get("bob").Edges(nil, "In")[0].From().ID()).ToEqual("alice")
 }
 
 func TestDuplicateHasManyConnection(t *testing.T) {
 	g := New()
 	g = g.Set(NodeConfig{
-		ID: "1",
+		ID:   "1",
+		Type: testType,
 	})
 	g = g.Set(NodeConfig{
-		ID: "2",
+		ID:   "2",
+		Type: testType,
 	})
 	cfg := EdgeConfig{
 		From: "1",
@@ -104,16 +122,18 @@ func TestDuplicateHasManyConnection(t *testing.T) {
 	g = g.Connect(cfg)
 	g = g.Connect(cfg)
 	expect := testutil.Expect(t)
-	expect(len(g.Get("1").Out())).ToEqual(1)
+	expect(len(g.Get("1").Edges(nil, "Out"))).ToEqual(1)
 }
 
 func TestDuplicateHasOneConnectionIsIgnored(t *testing.T) {
 	g := New()
 	g = g.Set(NodeConfig{
-		ID: "1",
+		ID:   "1",
+		Type: testType,
 	})
 	g = g.Set(NodeConfig{
-		ID: "2",
+		ID:   "2",
+		Type: testType,
 	})
 	cfg := EdgeConfig{
 		From: "1",
@@ -123,19 +143,22 @@ func TestDuplicateHasOneConnectionIsIgnored(t *testing.T) {
 	g = g.Connect(cfg)
 	g = g.Connect(cfg)
 	expect := testutil.Expect(t)
-	expect(len(g.Get("1").Out())).ToEqual(1)
+	expect(len(g.Get("1").Edges(nil, "Out"))).ToEqual(1)
 }
 
 func TestHasManyConnectionDoesNotModifyOld(t *testing.T) {
 	g := New()
 	g = g.Set(NodeConfig{
-		ID: "alice",
+		ID:   "alice",
+		Type: testType,
 	})
 	g = g.Set(NodeConfig{
-		ID: "bob",
+		ID:   "bob",
+		Type: testType,
 	})
 	g = g.Set(NodeConfig{
-		ID: "jeff",
+		ID:   "jeff",
+		Type: testType,
 	})
 	g = g.Connect(EdgeConfig{
 		From: "alice",
@@ -148,26 +171,28 @@ func TestHasManyConnectionDoesNotModifyOld(t *testing.T) {
 		Name: "friend",
 	})
 	expect := testutil.Expect(t)
-	expect(len(g.Get("alice").Out())).ToEqual(1)
-	expect(g.Get("alice").Out()[0].Node().ID()).ToEqual("bob")
+	expect(len(g.Get("alice").Edges(nil, "Out"))).ToEqual(1)
+	expect(g.Get("alice").Edges(nil, "Out")[0].To().ID()).ToEqual("bob")
 }
 
 func TestHasOneConnection(t *testing.T) {
 	expect := testutil.Expect(t)
 	g := New()
 	g = g.Set(NodeConfig{
-		ID: "alice",
+		ID:   "alice",
+		Type: testType,
 	})
 	g = g.Set(NodeConfig{
-		ID: "bob",
+		ID:   "bob",
+		Type: testType,
 	})
 	g = g.Connect(EdgeConfig{
 		From: "alice",
 		To:   "bob",
 		Name: "friend",
 	})
-	expect(len(g.Get("alice").Out())).ToEqual(1)
-	expect(g.Get("alice").Out()[0].Node().ID()).ToEqual("bob")
+	expect(len(g.Get("alice").Edges(nil, "Out"))).ToEqual(1)
+	expect(g.Get("alice").Edges(nil, "Out")[0].To().ID()).ToEqual("bob")
 	// ...now connect alice -> jeff
 	g = g.Disconnect(EdgeMatch{
 		From: "alice",
@@ -175,24 +200,27 @@ func TestHasOneConnection(t *testing.T) {
 		Name: "friend",
 	})
 	g = g.Set(NodeConfig{
-		ID: "jeff",
+		ID:   "jeff",
+		Type: testType,
 	})
 	g = g.Connect(EdgeConfig{
 		From: "alice",
 		To:   "jeff",
 		Name: "friend",
 	})
-	expect(len(g.Get("alice").Out())).ToEqual(1)
-	expect(g.Get("alice").Out()[0].Node().ID()).ToEqual("jeff")
+	expect(len(g.Get("alice").Edges(nil, "Out"))).ToEqual(1)
+	expect(g.Get("alice").Edges(nil, "Out")[0].To().ID()).ToEqual("jeff")
 }
 
 func TestDisconnectOnSourceRemoved(t *testing.T) {
 	g := New()
 	g = g.Set(NodeConfig{
-		ID: "1",
+		ID:   "1",
+		Type: testType,
 	})
 	g = g.Set(NodeConfig{
-		ID: "2",
+		ID:   "2",
+		Type: testType,
 	})
 	g = g.Connect(EdgeConfig{
 		From: "1",
@@ -200,18 +228,20 @@ func TestDisconnectOnSourceRemoved(t *testing.T) {
 		Name: "link",
 	})
 	expect := testutil.Expect(t)
-	expect(len(g.edges)).ToEqual(1)
+	expect(len(g.Edges(EdgeMatch{}))).ToEqual(1)
 	g = g.Remove("1")
-	expect(len(g.edges)).ToEqual(0)
+	expect(len(g.Edges(EdgeMatch{}))).ToEqual(0)
 }
 
 func TestDisconnectOnTargetRemoved(t *testing.T) {
 	g := New()
 	g = g.Set(NodeConfig{
-		ID: "1",
+		ID:   "1",
+		Type: testType,
 	})
 	g = g.Set(NodeConfig{
-		ID: "2",
+		ID:   "2",
+		Type: testType,
 	})
 	g = g.Connect(EdgeConfig{
 		From: "1",
@@ -219,21 +249,24 @@ func TestDisconnectOnTargetRemoved(t *testing.T) {
 		Name: "link",
 	})
 	expect := testutil.Expect(t)
-	expect(len(g.edges)).ToEqual(1)
+	expect(len(g.Edges(EdgeMatch{}))).ToEqual(1)
 	g = g.Remove("2")
-	expect(len(g.edges)).ToEqual(0)
+	expect(len(g.Edges(EdgeMatch{}))).ToEqual(0)
 }
 
 func TestDisconnectSource(t *testing.T) {
 	g := New()
 	g = g.Set(NodeConfig{
-		ID: "1",
+		ID:   "1",
+		Type: testType,
 	})
 	g = g.Set(NodeConfig{
-		ID: "2",
+		ID:   "2",
+		Type: testType,
 	})
 	g = g.Set(NodeConfig{
-		ID: "3",
+		ID:   "3",
+		Type: testType,
 	})
 	g = g.Connect(EdgeConfig{
 		From: "1",
@@ -254,18 +287,20 @@ func TestDisconnectSource(t *testing.T) {
 		From: "1",
 	})
 	expect := testutil.Expect(t)
-	expect(len(g.Get("1").Out())).ToEqual(0)
-	expect(len(g.Get("2").Out())).ToEqual(1)
-	expect(g.Get("2").Out()[0].Node().ID()).ToEqual("3")
+	expect(len(g.Get("1").Edges(nil, "Out"))).ToEqual(0)
+	expect(len(g.Get("2").Edges(nil, "Out"))).ToEqual(1)
+	expect(g.Get("2").Edges(nil, "Out")[0].To().ID()).ToEqual("3")
 }
 
 func TestDisconnectByNameAndSource(t *testing.T) {
 	g := New()
 	g = g.Set(NodeConfig{
-		ID: "1",
+		ID:   "1",
+		Type: testType,
 	})
 	g = g.Set(NodeConfig{
-		ID: "2",
+		ID:   "2",
+		Type: testType,
 	})
 	g = g.Connect(EdgeConfig{
 		From: "1",
@@ -282,22 +317,24 @@ func TestDisconnectByNameAndSource(t *testing.T) {
 		Name: "NON-EXISTANT-NAME",
 	})
 	expect := testutil.Expect(t)
-	expect(len(g.Get("1").Out())).ToEqual(2)
+	expect(len(g.Get("1").Edges(nil, "Out"))).ToEqual(2)
 	g = g.Disconnect(EdgeMatch{
 		From: "1",
 		Name: "linkA",
 	})
-	expect(len(g.Get("1").Out())).ToEqual(1)
+	expect(len(g.Get("1").Edges(nil, "Out"))).ToEqual(1)
 }
 
 func TestHasOneConnectionDoesNotModifyOld(t *testing.T) {
 	expect := testutil.Expect(t)
 	g := New()
 	g = g.Set(NodeConfig{
-		ID: "alice",
+		ID:   "alice",
+		Type: testType,
 	})
 	g = g.Set(NodeConfig{
-		ID: "bob",
+		ID:   "bob",
+		Type: testType,
 	})
 	g = g.Connect(EdgeConfig{
 		From: "alice",
@@ -306,24 +343,27 @@ func TestHasOneConnectionDoesNotModifyOld(t *testing.T) {
 	})
 	// ...now connect alice -> jeff
 	g = g.Set(NodeConfig{
-		ID: "jeff",
+		ID:   "jeff",
+		Type: testType,
 	})
 	_ = g.Connect(EdgeConfig{
 		From: "alice",
 		To:   "jeff",
 		Name: "friend",
 	})
-	expect(len(g.Get("alice").Out())).ToEqual(1)
-	expect(g.Get("alice").Out()[0].Node().ID()).ToEqual("bob")
+	expect(len(g.Get("alice").Edges(nil, "Out"))).ToEqual(1)
+	expect(g.Get("alice").Edges(nil, "Out")[0].To().ID()).ToEqual("bob")
 }
 
 func TestCascadingDelete(t *testing.T) {
 	g := New()
 	g = g.Set(NodeConfig{
-		ID: "jeff",
+		ID:   "jeff",
+		Type: testType,
 	})
 	g = g.Set(NodeConfig{
-		ID: "category",
+		ID:   "category",
+		Type: testType,
 	})
 	g = g.Connect(EdgeConfig{
 		From: "category",
@@ -331,7 +371,8 @@ func TestCascadingDelete(t *testing.T) {
 		Name: "author",
 	})
 	g = g.Set(NodeConfig{
-		ID: "product1",
+		ID:   "product1",
+		Type: testType,
 	})
 	g = g.Connect(EdgeConfig{
 		From:     "category",
@@ -340,7 +381,8 @@ func TestCascadingDelete(t *testing.T) {
 		OnDelete: Cascade,
 	})
 	g = g.Set(NodeConfig{
-		ID: "product2",
+		ID:   "product2",
+		Type: testType,
 	})
 	g = g.Connect(EdgeConfig{
 		From:     "category",
@@ -361,6 +403,7 @@ func TestDefineType(t *testing.T) {
 	expect := testutil.Expect(t)
 	expect(len(g.Types())).ToEqual(0)
 	g = g.DefineType(Type{
+		ID:   "user",
 		Name: "User",
 		Fields: Fields{
 			&Field{
@@ -370,10 +413,297 @@ func TestDefineType(t *testing.T) {
 		},
 	})
 	expect(len(g.Types())).ToEqual(1)
-	expect(g.Type("User").Field("name").Type).ToEqual("text")
+	expect(g.TypeByName("User").Field("name").Type).ToEqual("text")
 	g = g.Set(NodeConfig{
 		ID:   "alice",
-		Type: "User",
+		Type: g.TypeByName("User"),
 	})
 	expect(g.Get("alice").Type().Name).ToEqual("User")
 }
+
+func TestRemoveDoesNotModifyOld(t *testing.T) {
+	g := New()
+	g = g.Set(NodeConfig{
+		ID:   "1",
+		Type: testType,
+	})
+	g = g.Set(NodeConfig{
+		ID:   "2",
+		Type: testType,
+	})
+	g = g.Connect(EdgeConfig{
+		From: "1",
+		To:   "2",
+		Name: "link",
+	})
+	_ = g.Remove("2")
+	expect := testutil.Expect(t)
+	expect(g.Get("2")).ToNotBeNil()
+	expect(len(g.Get("1").Edges(nil, "Out"))).ToEqual(1)
+	expect(len(g.Edges(EdgeMatch{Name: "link"}))).ToEqual(1)
+}
+
+func TestEdgesKeepConnectOrder(t *testing.T) {
+	g := New()
+	for i := 0; i < 50; i++ {
+		g = g.Set(NodeConfig{
+			ID:   fmt.Sprintf("n%d", i),
+			Type: testType,
+		})
+	}
+	for i := 1; i < 50; i++ {
+		g = g.Connect(EdgeConfig{
+			From: "n0",
+			To:   fmt.Sprintf("n%d", i),
+			Name: "link",
+		})
+	}
+	expect := testutil.Expect(t)
+	es := g.Get("n0").Edges(nil, "Out")
+	expect(len(es)).ToEqual(49)
+	for i, e := range es {
+		expect(e.To().ID()).ToEqual(fmt.Sprintf("n%d", i+1))
+	}
+	es = g.Edges(EdgeMatch{Name: "link"})
+	expect(es[48].To().ID()).ToEqual("n49")
+}
+
+func TestSelfEdgeIsListedOnce(t *testing.T) {
+	g := New()
+	g = g.Set(NodeConfig{
+		ID:   "1",
+		Type: testType,
+	})
+	g = g.Connect(EdgeConfig{
+		From: "1",
+		To:   "1",
+		Name: "self",
+	})
+	expect := testutil.Expect(t)
+	expect(len(g.Get("1").Edges(nil, ""))).ToEqual(1)
+	expect(len(g.Get("1").Edges(nil, "In"))).ToEqual(1)
+	expect(len(g.Get("1").Edges(nil, "Out"))).ToEqual(1)
+}
+
+const benchSize = 100000
+
+func benchGraph(b *testing.B) *Graph {
+	g := New()
+	for i := 0; i < benchSize; i++ {
+		g = g.Set(NodeConfig{
+			ID:   fmt.Sprintf("n%d", i),
+			Type: testType,
+		})
+	}
+	for i := 1; i < benchSize; i++ {
+		g = g.Connect(EdgeConfig{
+			From: fmt.Sprintf("n%d", i/10),
+			To:   fmt.Sprintf("n%d", i),
+			Name: "child",
+		})
+	}
+	return g
+}
+
+func BenchmarkGet(b *testing.B) {
+	g := benchGraph(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.Get(fmt.Sprintf("n%d", i%benchSize))
+	}
+}
+
+func BenchmarkSet(b *testing.B) {
+	g := benchGraph(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g = g.Set(NodeConfig{
+			ID:   fmt.Sprintf("n%d", i%benchSize),
+			Type: testType,
+			Attrs: []*Attr{
+				&Attr{Name: "name", Value: "x"},
+			},
+		})
+	}
+}
+
+func BenchmarkRemove(b *testing.B) {
+	g := benchGraph(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.Remove(fmt.Sprintf("n%d", i%benchSize))
+	}
+}
+
+func BenchmarkConnect(b *testing.B) {
+	g := benchGraph(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.Connect(EdgeConfig{
+			From: fmt.Sprintf("n%d", i%benchSize),
+			To:   fmt.Sprintf("n%d", (i+1)%benchSize),
+			Name: "next",
+		})
+	}
+}
+
+func BenchmarkEdges(b *testing.B) {
+	g := benchGraph(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.Edges(EdgeMatch{From: fmt.Sprintf("n%d", i%benchSize)})
+	}
+}
+
+func BenchmarkNodeEdges(b *testing.B) {
+	g := benchGraph(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.Get(fmt.Sprintf("n%d", i%benchSize)).Edges(nil, "")
+	}
+}
+
+func TestEdgeAttrs(t *testing.T) {
+	g := New()
+	g = g.Set(NodeConfig{
+		ID:   "alice",
+		Type: testType,
+	})
+	g = g.Set(NodeConfig{
+		ID:   "acme",
+		Type: testType,
+	})
+	g = g.Connect(EdgeConfig{
+		From: "alice",
+		To:   "acme",
+		Name: "employer",
+		Attrs: []*Attr{
+			&Attr{Name: "role", Value: "engineer"},
+			&Attr{Name: "since", Value: "2015"},
+		},
+	})
+	expect := testutil.Expect(t)
+	e := g.Edges(EdgeMatch{From: "alice"}).First()
+	expect(len(e.Attrs())).ToEqual(2)
+	expect(e.Attr("role").Value).ToEqual("engineer")
+	expect(e.Attr("missing")).ToBeNil()
+	// reconnecting replaces the attrs
+	g2 := g.Connect(EdgeConfig{
+		From: "alice",
+		To:   "acme",
+		Name: "employer",
+		Attrs: []*Attr{
+			&Attr{Name: "role", Value: "manager"},
+		},
+	})
+	expect(g2.Edges(EdgeMatch{From: "alice"}).First().Attr("role").Value).ToEqual("manager")
+	expect(g.Edges(EdgeMatch{From: "alice"}).First().Attr("role").Value).ToEqual("engineer")
+}
+
+func TestOnDeletePolicies(t *testing.T) {
+	expect := testutil.Expect(t)
+	gallery := &Type{
+		ID:   "gallery",
+		Name: "Gallery",
+		Fields: Fields{
+			&Field{Name: "images", Type: "Edge", EdgeName: "image", EdgeDirection: "Out", OnDelete: OnDeleteCascade},
+			&Field{Name: "owner", Type: "Edge", EdgeName: "owner", EdgeDirection: "Out", OnDelete: OnDeleteRestrict},
+		},
+	}
+	g := New()
+	g = g.DefineType(*gallery)
+	g = g.DefineType(*testType)
+	g = g.Set(NodeConfig{ID: "g1", Type: gallery})
+	g = g.Set(NodeConfig{ID: "g2", Type: gallery})
+	for _, id := range []string{"img1", "img2", "alice"} {
+		g = g.Set(NodeConfig{ID: id, Type: testType})
+	}
+	g = g.Connect(EdgeConfig{From: "g1", To: "img1", Name: "image"})
+	g = g.Connect(EdgeConfig{From: "g1", To: "img2", Name: "image"})
+	g = g.Connect(EdgeConfig{From: "g1", To: "g2", Name: "image"})
+	g = g.Connect(EdgeConfig{From: "g2", To: "alice", Name: "owner"})
+
+	// g2 is cascaded but restricts removal while connected to alice
+	_, _, err := g.Delete("g1")
+	expect(err).ToEqual(&RestrictError{NodeID: "g2", Field: "owner", Target: "alice"})
+
+	g = g.Disconnect(EdgeMatch{From: "g2", Name: "owner"})
+	g2, removed, err := g.Delete("g1")
+	expect(err).ToEqual(nil)
+	ids := []string{}
+	for _, n := range removed {
+		ids = append(ids, n.ID())
+	}
+	expect(ids).ToEqual([]string{"g1", "img1", "img2", "g2"})
+	expect(g2.Get("img1")).ToBeNil()
+	expect(g2.Get("alice")).ToNotBeNil()
+	expect(len(g2.Edges(EdgeMatch{}))).ToEqual(0)
+	expect(g.Get("img1")).ToNotBeNil()
+	// Remove ignores the policies
+	g3 := g.Connect(EdgeConfig{From: "g2", To: "alice", Name: "owner"}).Remove("g2")
+	expect(g3.Get("g2")).ToBeNil()
+	expect(g3.Get("alice")).ToNotBeNil()
+	expect(g3.Get("g1")).ToNotBeNil()
+}
+
+func TestLinkReplacesOverflow(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := New()
+	g = g.DefineType(Type{
+		ID: "person",
+		Fields: Fields{
+			{Name: "manager", Type: "Edge", EdgeName: "manager", EdgeDirection: "Out", EdgeCardinality: CardinalityOne, EdgeOverflow: OverflowReplace},
+			{Name: "reports", Type: "Edge", EdgeName: "manager", EdgeDirection: "In", EdgeLimit: 2, EdgeOverflow: OverflowReplace},
+		},
+	})
+	for _, id := range []string{"alice", "bob", "carol", "dave"} {
+		g = g.Set(NodeConfig{ID: id, Type: g.TypeByID("person")})
+	}
+	g = g.Link(EdgeConfig{From: "alice", To: "bob", Name: "manager"})
+	g2 := g.Link(EdgeConfig{From: "alice", To: "carol", Name: "manager"})
+	expect(len(g.Get("alice").Edges(nil, "Out"))).ToEqual(1)
+	expect(g2.Get("alice").Edges(nil, "Out")[0].To().ID()).ToEqual("carol")
+	// the oldest report is dropped when carol takes on a third
+	g2 = g2.Link(EdgeConfig{From: "bob", To: "carol", Name: "manager"})
+	g2 = g2.Link(EdgeConfig{From: "dave", To: "carol", Name: "manager"})
+	g2 = g2.Link(EdgeConfig{From: "carol", To: "carol", Name: "manager"})
+	ids := []string{}
+	for _, e := range g2.Get("carol").Edges(nil, "In") {
+		ids = append(ids, e.From().ID())
+	}
+	expect(ids).ToEqual([]string{"dave", "carol"})
+	expect(g2.Validiate()).ToEqual(nil)
+}
+
+func TestReorderEdges(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := New()
+	for _, id := range []string{"gallery", "a", "b", "c", "d"} {
+		g = g.Set(NodeConfig{ID: id, Type: testType})
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		g = g.Connect(EdgeConfig{From: "gallery", To: id, Name: "image"})
+	}
+	g = g.Connect(EdgeConfig{From: "gallery", To: "d", Name: "cover"})
+	order := func(g *Graph, name string) []string {
+		ids := []string{}
+		for _, e := range g.Edges(EdgeMatch{From: "gallery", Name: name}) {
+			ids = append(ids, e.To().ID())
+		}
+		return ids
+	}
+	g2 := g.MoveEdge(EdgeMatch{From: "gallery", Name: "image", To: "c"}, 0)
+	expect(order(g2, "image")).ToEqual([]string{"c", "a", "b"})
+	expect(order(g, "image")).ToEqual([]string{"a", "b", "c"})
+	g2 = g2.MoveEdge(EdgeMatch{From: "gallery", Name: "image", To: "c"}, 99)
+	expect(order(g2, "image")).ToEqual([]string{"a", "b", "c"})
+	es := g.Edges(EdgeMatch{From: "gallery", Name: "image"})
+	g2 = g.ReorderEdges(Edges{es[2], es[0], es[1]})
+	expect(order(g2, "image")).ToEqual([]string{"c", "a", "b"})
+	// other edges keep their place
+	expect(g2.Get("gallery").Edges(nil, "Out")[3].To().ID()).ToEqual("d")
+	// reconnecting an existing edge keeps its position
+	g2 = g2.Connect(EdgeConfig{From: "gallery", To: "c", Name: "image"})
+	expect(order(g2, "image")).ToEqual([]string{"c", "a", "b"})
+	expect(len(Diff(g, g2).Edges)).ToEqual(3)
+}
@@ -0,0 +1,235 @@
+package graph
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+)
+
+const graphMLNamespace = "http://graphml.graphdrawing.org/xmlns"
+
+// GraphML keys used for the type of each node and the name of each
+// edge. Attrs use keys of the form "n.<name>" and "e.<name>".
+const (
+	graphMLTypeKey = "type"
+	graphMLNameKey = "name"
+)
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr,omitempty"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr,omitempty"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr,omitempty"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// graphMLAttrType maps a value type to a GraphML attr.type
+func graphMLAttrType(t ValueType) string {
+	switch t {
+	case IntValue:
+		return "long"
+	case FloatValue:
+		return "double"
+	case BoolValue:
+		return "boolean"
+	default:
+		return "string"
+	}
+}
+
+// graphMLValueType maps a GraphML attr.type to a value type
+func graphMLValueType(attrType string) ValueType {
+	switch attrType {
+	case "int", "long":
+		return IntValue
+	case "float", "double":
+		return FloatValue
+	case "boolean":
+		return BoolValue
+	default:
+		return StringValue
+	}
+}
+
+// WriteGraphML writes the graph as a directed GraphML document. The
+// type of each node is written by name as the "@type" attr and the name
+// of each edge as its "label".
+func (g *Graph) WriteGraphML(w io.Writer) error {
+	doc := g.Document()
+	names := map[string]string{}
+	for _, t := range doc.Types {
+		names[t.ID] = t.Name
+	}
+	keys := map[string]graphMLKey{}
+	addKey := func(prefix, kind string, attr *Attr) string {
+		id := prefix + attr.Name
+		if _, ok := keys[id]; !ok {
+			keys[id] = graphMLKey{
+				ID:       id,
+				For:      kind,
+				AttrName: attr.Name,
+				AttrType: graphMLAttrType(attr.Type()),
+			}
+		}
+		return id
+	}
+	out := graphML{
+		XMLNS: graphMLNamespace,
+		Keys: []graphMLKey{
+			{ID: graphMLTypeKey, For: "node", AttrName: "@type", AttrType: "string"},
+			{ID: graphMLNameKey, For: "edge", AttrName: "label", AttrType: "string"},
+		},
+		Graph: graphMLGraph{
+			ID:          "G",
+			EdgeDefault: "directed",
+		},
+	}
+	for _, n := range doc.Nodes {
+		gn := graphMLNode{
+			ID:   n.ID,
+			Data: []graphMLData{{Key: graphMLTypeKey, Value: names[n.TypeID]}},
+		}
+		for _, attr := range n.Attrs {
+			gn.Data = append(gn.Data, graphMLData{Key: addKey("n.", "node", attr), Value: attr.String()})
+		}
+		out.Graph.Nodes = append(out.Graph.Nodes, gn)
+	}
+	for i, e := range doc.Edges {
+		ge := graphMLEdge{
+			ID:     fmt.Sprintf("e%d", i),
+			Source: e.From,
+			Target: e.To,
+			Data:   []graphMLData{{Key: graphMLNameKey, Value: e.Name}},
+		}
+		for _, attr := range e.Attrs {
+			ge.Data = append(ge.Data, graphMLData{Key: addKey("e.", "edge", attr), Value: attr.String()})
+		}
+		out.Graph.Edges = append(out.Graph.Edges, ge)
+	}
+	ids := []string{}
+	for id := range keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		out.Keys = append(out.Keys, keys[id])
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ReadGraphML reads a GraphML document into a Document that can be
+// passed to Import. Node types are looked up by name in g using the
+// data of the "type" key and edge names are taken from the "name" key,
+// as written by WriteGraphML. Other data is read as attrs using the
+// attr.type of their keys.
+func (g *Graph) ReadGraphML(r io.Reader) (*Document, error) {
+	var in graphML
+	if err := xml.NewDecoder(r).Decode(&in); err != nil {
+		return nil, err
+	}
+	keys := map[string]graphMLKey{}
+	for _, k := range in.Keys {
+		keys[k.ID] = k
+	}
+	attr := func(k graphMLKey, s string) (*Attr, error) {
+		v, err := Coerce(s, graphMLValueType(k.AttrType))
+		if err != nil {
+			return nil, fmt.Errorf("data key '%s': %s", k.ID, err)
+		}
+		return &Attr{Name: k.AttrName, Value: v, Enc: "UTF8"}, nil
+	}
+	doc := &Document{
+		Types: []*Type{},
+		Nodes: []*DocumentNode{},
+		Edges: []*DocumentEdge{},
+	}
+	for _, gn := range in.Graph.Nodes {
+		n := &DocumentNode{ID: gn.ID}
+		for _, d := range gn.Data {
+			k, ok := keys[d.Key]
+			if !ok {
+				return nil, fmt.Errorf("node '%s': data key '%s' is not declared", gn.ID, d.Key)
+			}
+			if k.ID == graphMLTypeKey {
+				t := g.TypeByName(d.Value)
+				if t == nil {
+					return nil, fmt.Errorf("node '%s': type '%s' is not defined", gn.ID, d.Value)
+				}
+				n.TypeID = t.ID
+				continue
+			}
+			a, err := attr(k, d.Value)
+			if err != nil {
+				return nil, fmt.Errorf("node '%s' %s", gn.ID, err)
+			}
+			n.Attrs = append(n.Attrs, a)
+		}
+		if n.TypeID == "" {
+			return nil, fmt.Errorf("node '%s': no @type given", gn.ID)
+		}
+		doc.Nodes = append(doc.Nodes, n)
+	}
+	for _, ge := range in.Graph.Edges {
+		e := &DocumentEdge{From: ge.Source, To: ge.Target}
+		for _, d := range ge.Data {
+			k, ok := keys[d.Key]
+			if !ok {
+				return nil, fmt.Errorf("edge '%s': data key '%s' is not declared", ge.ID, d.Key)
+			}
+			if k.ID == graphMLNameKey {
+				e.Name = d.Value
+				continue
+			}
+			a, err := attr(k, d.Value)
+			if err != nil {
+				return nil, fmt.Errorf("edge '%s' %s", ge.ID, err)
+			}
+			e.Attrs = append(e.Attrs, a)
+		}
+		if e.Name == "" {
+			return nil, fmt.Errorf("edge from '%s' to '%s': no label given", ge.Source, ge.Target)
+		}
+		doc.Edges = append(doc.Edges, e)
+	}
+	return doc, nil
+}
@@ -0,0 +1,320 @@
+package graph
+
+import (
+	"hash/fnv"
+	"math/bits"
+)
+
+// hamt is a persistent hash array mapped trie keyed by string.
+// Every write returns a new trie that shares all untouched branches
+// with the original, so old versions remain valid and unchanged.
+// A nil *hamt is an empty map.
+type hamt struct {
+	root *hamtNode
+	size int
+}
+
+const (
+	hamtBits = 5
+	hamtMask = 1<<hamtBits - 1
+)
+
+type hamtEntry struct {
+	key   string
+	value interface{}
+}
+
+// hamtLeaf holds all entries whose keys share the same full hash
+type hamtLeaf struct {
+	hash    uint32
+	entries []hamtEntry
+}
+
+type hamtNode struct {
+	bitmap   uint32
+	children []interface{} // *hamtNode or *hamtLeaf
+}
+
+func hashKey(key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32()
+}
+
+func (n *hamtNode) index(bit uint32) int {
+	return bits.OnesCount32(n.bitmap & (bit - 1))
+}
+
+func (n *hamtNode) withChild(bit uint32, child interface{}) *hamtNode {
+	i := n.index(bit)
+	n2 := &hamtNode{bitmap: n.bitmap}
+	if n.bitmap&bit != 0 {
+		n2.children = make([]interface{}, len(n.children))
+		copy(n2.children, n.children)
+		n2.children[i] = child
+		return n2
+	}
+	n2.bitmap |= bit
+	n2.children = make([]interface{}, len(n.children)+1)
+	copy(n2.children, n.children[:i])
+	n2.children[i] = child
+	copy(n2.children[i+1:], n.children[i:])
+	return n2
+}
+
+func (n *hamtNode) withoutChild(bit uint32) *hamtNode {
+	i := n.index(bit)
+	n2 := &hamtNode{
+		bitmap:   n.bitmap &^ bit,
+		children: make([]interface{}, len(n.children)-1),
+	}
+	copy(n2.children, n.children[:i])
+	copy(n2.children[i:], n.children[i+1:])
+	return n2
+}
+
+func (h *hamt) count() int {
+	if h == nil {
+		return 0
+	}
+	return h.size
+}
+
+func (h *hamt) get(key string) (interface{}, bool) {
+	if h == nil || h.root == nil {
+		return nil, false
+	}
+	hash := hashKey(key)
+	n := h.root
+	for shift := uint(0); ; shift += hamtBits {
+		bit := uint32(1) << ((hash >> shift) & hamtMask)
+		if n.bitmap&bit == 0 {
+			return nil, false
+		}
+		switch child := n.children[n.index(bit)].(type) {
+		case *hamtNode:
+			n = child
+		case *hamtLeaf:
+			if child.hash != hash {
+				return nil, false
+			}
+			for _, e := range child.entries {
+				if e.key == key {
+					return e.value, true
+				}
+			}
+			return nil, false
+		}
+	}
+}
+
+// set returns a new trie with key mapped to value
+func (h *hamt) set(key string, value interface{}) *hamt {
+	root := &hamtNode{}
+	size := 0
+	if h != nil && h.root != nil {
+		root = h.root
+		size = h.size
+	}
+	root, added := root.set(hashKey(key), 0, key, value)
+	if added {
+		size++
+	}
+	return &hamt{root: root, size: size}
+}
+
+func (n *hamtNode) set(hash uint32, shift uint, key string, value interface{}) (*hamtNode, bool) {
+	bit := uint32(1) << ((hash >> shift) & hamtMask)
+	if n.bitmap&bit == 0 {
+		return n.withChild(bit, &hamtLeaf{
+			hash:    hash,
+			entries: []hamtEntry{{key: key, value: value}},
+		}), true
+	}
+	switch child := n.children[n.index(bit)].(type) {
+	case *hamtNode:
+		child, added := child.set(hash, shift+hamtBits, key, value)
+		return n.withChild(bit, child), added
+	case *hamtLeaf:
+		if child.hash == hash {
+			leaf := &hamtLeaf{hash: hash}
+			added := true
+			for _, e := range child.entries {
+				if e.key == key {
+					added = false
+					continue
+				}
+				leaf.entries = append(leaf.entries, e)
+			}
+			leaf.entries = append(leaf.entries, hamtEntry{key: key, value: value})
+			return n.withChild(bit, leaf), added
+		}
+		// push the existing leaf down a level and retry
+		sub := (&hamtNode{}).withChild(
+			uint32(1)<<((child.hash>>(shift+hamtBits))&hamtMask),
+			child,
+		)
+		sub, added := sub.set(hash, shift+hamtBits, key, value)
+		return n.withChild(bit, sub), added
+	}
+	panic("hamt: invalid child")
+}
+
+// delete returns a new trie without key
+func (h *hamt) delete(key string) *hamt {
+	if h == nil || h.root == nil {
+		return h
+	}
+	root, removed := h.root.delete(hashKey(key), 0, key)
+	if !removed {
+		return h
+	}
+	if h.size == 1 {
+		return nil
+	}
+	return &hamt{root: root, size: h.size - 1}
+}
+
+func (n *hamtNode) delete(hash uint32, shift uint, key string) (*hamtNode, bool) {
+	bit := uint32(1) << ((hash >> shift) & hamtMask)
+	if n.bitmap&bit == 0 {
+		return n, false
+	}
+	switch child := n.children[n.index(bit)].(type) {
+	case *hamtNode:
+		child, removed := child.delete(hash, shift+hamtBits, key)
+		if !removed {
+			return n, false
+		}
+		if len(child.children) == 0 {
+			return n.withoutChild(bit), true
+		}
+		// collapse branches that only hold a single leaf
+		if len(child.children) == 1 {
+			if leaf, ok := child.children[0].(*hamtLeaf); ok {
+				return n.withChild(bit, leaf), true
+			}
+		}
+		return n.withChild(bit, child), true
+	case *hamtLeaf:
+		if child.hash != hash {
+			return n, false
+		}
+		leaf := &hamtLeaf{hash: hash}
+		for _, e := range child.entries {
+			if e.key == key {
+				continue
+			}
+			leaf.entries = append(leaf.entries, e)
+		}
+		if len(leaf.entries) == len(child.entries) {
+			return n, false
+		}
+		if len(leaf.entries) == 0 {
+			return n.withoutChild(bit), true
+		}
+		return n.withChild(bit, leaf), true
+	}
+	panic("hamt: invalid child")
+}
+
+// each calls fn for every entry until fn returns false
+func (h *hamt) each(fn func(key string, value interface{}) bool) {
+	if h == nil || h.root == nil {
+		return
+	}
+	h.root.each(fn)
+}
+
+func (n *hamtNode) each(fn func(key string, value interface{}) bool) bool {
+	for _, c := range n.children {
+		switch child := c.(type) {
+		case *hamtNode:
+			if !child.each(fn) {
+				return false
+			}
+		case *hamtLeaf:
+			for _, e := range child.entries {
+				if !fn(e.key, e.value) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// diffHamt calls fn for every key whose value differs between a and b.
+// Missing values are passed as nil. Branches shared by both tries are
+// skipped so the cost is proportional to the size of the change.
+func diffHamt(a, b *hamt, fn func(key string, av, bv interface{})) {
+	if a == b {
+		return
+	}
+	var an, bn *hamtNode
+	if a != nil {
+		an = a.root
+	}
+	if b != nil {
+		bn = b.root
+	}
+	diffChildren(an, bn, fn)
+}
+
+func diffChildren(a, b interface{}, fn func(key string, av, bv interface{})) {
+	if a == b {
+		return
+	}
+	an, aIsNode := a.(*hamtNode)
+	bn, bIsNode := b.(*hamtNode)
+	if aIsNode && bIsNode && an != nil && bn != nil {
+		for bitmap := an.bitmap | bn.bitmap; bitmap != 0; bitmap &= bitmap - 1 {
+			bit := bitmap & -bitmap
+			var ac, bc interface{}
+			if an.bitmap&bit != 0 {
+				ac = an.children[an.index(bit)]
+			}
+			if bn.bitmap&bit != 0 {
+				bc = bn.children[bn.index(bit)]
+			}
+			diffChildren(ac, bc, fn)
+		}
+		return
+	}
+	// shapes differ so compare the entries directly
+	as := map[string]interface{}{}
+	eachChild(a, func(k string, v interface{}) bool {
+		as[k] = v
+		return true
+	})
+	eachChild(b, func(k string, v interface{}) bool {
+		if av, ok := as[k]; ok {
+			delete(as, k)
+			if av == v {
+				return true
+			}
+			fn(k, av, v)
+			return true
+		}
+		fn(k, nil, v)
+		return true
+	})
+	for k, av := range as {
+		fn(k, av, nil)
+	}
+}
+
+func eachChild(c interface{}, fn func(key string, value interface{}) bool) {
+	switch child := c.(type) {
+	case *hamtNode:
+		if child != nil {
+			child.each(fn)
+		}
+	case *hamtLeaf:
+		for _, e := range child.entries {
+			if !fn(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package graph
+
+import (
+	"fmt"
+	"testing"
+	"testutil"
+)
+
+func TestHamtSetGetDelete(t *testing.T) {
+	expect := testutil.Expect(t)
+	var h *hamt
+	versions := []*hamt{}
+	for i := 0; i < 5000; i++ {
+		h = h.set(fmt.Sprintf("k%d", i), i)
+		versions = append(versions, h)
+	}
+	expect(h.count()).ToEqual(5000)
+	for i := 0; i < 5000; i++ {
+		v, ok := h.get(fmt.Sprintf("k%d", i))
+		expect(ok).ToEqual(true)
+		expect(v).ToEqual(i)
+	}
+	// older versions are unchanged
+	expect(versions[99].count()).ToEqual(100)
+	_, ok := versions[99].get("k100")
+	expect(ok).ToEqual(false)
+	for i := 0; i < 5000; i += 2 {
+		h = h.delete(fmt.Sprintf("k%d", i))
+	}
+	expect(h.count()).ToEqual(2500)
+	_, ok = h.get("k2")
+	expect(ok).ToEqual(false)
+	_, ok = h.get("k3")
+	expect(ok).ToEqual(true)
+	n := 0
+	h.each(func(_ string, _ interface{}) bool {
+		n++
+		return true
+	})
+	expect(n).ToEqual(2500)
+	expect(versions[4999].count()).ToEqual(5000)
+}
+
+func TestHamtOverwrite(t *testing.T) {
+	expect := testutil.Expect(t)
+	h := (*hamt)(nil).set("a", 1)
+	h2 := h.set("a", 2)
+	v, _ := h.get("a")
+	expect(v).ToEqual(1)
+	v, _ = h2.get("a")
+	expect(v).ToEqual(2)
+	expect(h2.count()).ToEqual(1)
+	expect(h2.delete("a").count()).ToEqual(0)
+	expect(h2.delete("missing")).ToEqual(h2)
+}
+
+func TestHamtHashCollision(t *testing.T) {
+	expect := testutil.Expect(t)
+	// these keys share the same fnv32a hash
+	a, b := "k32728", "k261234"
+	expect(hashKey(a)).ToEqual(hashKey(b))
+	h := (*hamt)(nil).set(a, 1).set(b, 2).set("c", 3)
+	v, _ := h.get(a)
+	expect(v).ToEqual(1)
+	v, _ = h.get(b)
+	expect(v).ToEqual(2)
+	h = h.delete(a)
+	_, ok := h.get(a)
+	expect(ok).ToEqual(false)
+	v, _ = h.get(b)
+	expect(v).ToEqual(2)
+	expect(h.count()).ToEqual(2)
+}
+
+func TestHamtDiff(t *testing.T) {
+	expect := testutil.Expect(t)
+	var a *hamt
+	for i := 0; i < 1000; i++ {
+		a = a.set(fmt.Sprintf("k%d", i), i)
+	}
+	b := a.set("k1", -1).delete("k2").set("new", 1)
+	changes := map[string][2]interface{}{}
+	diffHamt(a, b, func(k string, av, bv interface{}) {
+		changes[k] = [2]interface{}{av, bv}
+	})
+	expect(len(changes)).ToEqual(3)
+	expect(changes["k1"]).ToEqual([2]interface{}{1, -1})
+	expect(changes["k2"]).ToEqual([2]interface{}{2, nil})
+	expect(changes["new"]).ToEqual([2]interface{}{nil, 1})
+	n := 0
+	diffHamt(nil, a, func(k string, av, bv interface{}) {
+		n++
+	})
+	expect(n).ToEqual(1000)
+}
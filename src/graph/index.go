@@ -0,0 +1,186 @@
+package graph
+
+import "sort"
+
+// Attr values of indexed fields are held in a persistent map so that
+// nodes can be found by value without scanning the graph. The index is
+// keyed by the id of the node's type and then by field name and value:
+//
+//	typeID => *hamt of indexKey(field, value) => *hamt of node id => true
+//
+// Like the rest of the graph each version shares untouched branches
+// with the one it was derived from.
+
+func indexKey(field string, v interface{}) string {
+	return field + "\x00" + FormatValue(v)
+}
+
+// indexedFields returns the names of the fields of t that are indexed
+func (t *Type) indexedFields() []string {
+	names := []string{}
+	if t == nil {
+		return names
+	}
+	for _, f := range t.AllFields() {
+		if (f.Unique || f.Indexed) && f.Type != "Edge" {
+			names = append(names, f.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// putNode stores n, updating the index. g must not be shared.
+func (g *Graph) putNode(n *node) {
+	if old := g.node(n.id); old != nil {
+		g.unindexNode(old)
+	}
+	g.nodes = g.nodes.set(n.id, n)
+	g.indexNode(n)
+}
+
+// dropNode removes the node with the given id, updating the index. g
+// must not be shared.
+func (g *Graph) dropNode(id string) {
+	if old := g.node(id); old != nil {
+		g.unindexNode(old)
+	}
+	g.nodes = g.nodes.delete(id)
+}
+
+func (g *Graph) indexNode(n *node) {
+	g.updateIndex(n, func(ids *hamt) *hamt {
+		return ids.set(n.id, true)
+	})
+}
+
+func (g *Graph) unindexNode(n *node) {
+	g.updateIndex(n, func(ids *hamt) *hamt {
+		return ids.delete(n.id)
+	})
+}
+
+func (g *Graph) updateIndex(n *node, fn func(ids *hamt) *hamt) {
+	fields := g.TypeByID(n.typeID).indexedFields()
+	if len(fields) == 0 {
+		return
+	}
+	var byValue *hamt
+	if v, ok := g.index.get(n.typeID); ok {
+		byValue = v.(*hamt)
+	}
+	for _, name := range fields {
+		attr := findAttr(n.attrs, name)
+		if attr == nil || attr.Empty() {
+			continue
+		}
+		k := indexKey(name, attr.Value)
+		var ids *hamt
+		if v, ok := byValue.get(k); ok {
+			ids = v.(*hamt)
+		}
+		ids = fn(ids)
+		if ids.count() == 0 {
+			byValue = byValue.delete(k)
+		} else {
+			byValue = byValue.set(k, ids)
+		}
+	}
+	if byValue.count() == 0 {
+		g.index = g.index.delete(n.typeID)
+	} else {
+		g.index = g.index.set(n.typeID, byValue)
+	}
+}
+
+// reindexType rebuilds the index for every node of the type with the
+// given id. g must not be shared.
+func (g *Graph) reindexType(typeID string) {
+	g.index = g.index.delete(typeID)
+	g.nodes.each(func(_ string, v interface{}) bool {
+		if n := v.(*node); n.typeID == typeID {
+			g.indexNode(n)
+		}
+		return true
+	})
+}
+
+// reindexTypes rebuilds the index for any type whose indexed fields
+// differ from those in base. g must not be shared.
+func (g *Graph) reindexTypes(base *Graph) {
+	for _, t := range g.types {
+		if !stringsEqual(t.indexedFields(), base.TypeByID(t.ID).indexedFields()) {
+			g.reindexType(t.ID)
+		}
+	}
+}
+
+// Lookup returns the ids of the nodes of type t whose attr for the
+// named field holds v. The bool result is false if the field is not
+// indexed.
+func (g *Graph) Lookup(t *Type, field string, v interface{}) ([]string, bool) {
+	indexed := false
+	for _, name := range t.indexedFields() {
+		if name == field {
+			indexed = true
+		}
+	}
+	if !indexed {
+		return nil, false
+	}
+	ids := []string{}
+	byValue, ok := g.index.get(t.ID)
+	if !ok {
+		return ids, true
+	}
+	set, ok := byValue.(*hamt).get(indexKey(field, v))
+	if !ok {
+		return ids, true
+	}
+	set.(*hamt).each(func(id string, _ interface{}) bool {
+		ids = append(ids, id)
+		return true
+	})
+	sort.Strings(ids)
+	return ids, true
+}
+
+// uniqueScope returns the types whose nodes may not share a value of
+// the unique field name with a node of type t. Values are unique among
+// all nodes that have the field from the same declaring type, so the
+// scope is every type among t and its ancestors that declares the field
+// along with all of their subtypes.
+func (g *Graph) uniqueScope(t *Type, name string) []*Type {
+	scope := []*Type{}
+	seen := map[string]bool{}
+	add := func(ts ...*Type) {
+		for _, st := range ts {
+			if !seen[st.ID] {
+				seen[st.ID] = true
+				scope = append(scope, st)
+			}
+		}
+	}
+	for _, a := range append([]*Type{t}, g.Ancestors(t)...) {
+		if a.declares(name) {
+			add(a)
+			add(g.Subtypes(a)...)
+		}
+	}
+	if len(scope) == 0 {
+		add(t)
+	}
+	return scope
+}
+
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
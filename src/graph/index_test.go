@@ -0,0 +1,95 @@
+package graph
+
+import (
+	"testing"
+
+	"testutil"
+)
+
+func TestUniqueField(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := New().DefineType(Type{
+		ID:   "product",
+		Name: "Product",
+		Fields: Fields{
+			{Name: "sku", Type: "Text", Unique: true},
+			{Name: "name", Type: "Text"},
+		},
+	})
+	product := g.TypeByID("product")
+	g = g.Set(NodeConfig{ID: "a", Type: product, Attrs: []*Attr{{Name: "sku", Value: "A1"}}})
+	g = g.Set(NodeConfig{ID: "b", Type: product, Attrs: []*Attr{{Name: "sku", Value: "B1"}}})
+	ids, ok := g.Lookup(product, "sku", "A1")
+	expect(ok).ToEqual(true)
+	expect(ids).ToEqual([]string{"a"})
+	_, ok = g.Lookup(product, "name", "A1")
+	expect(ok).ToEqual(false)
+	expect(g.Validiate()).ToEqual(nil)
+	// a duplicate value is a violation for the node that changed
+	g2 := g.Set(NodeConfig{ID: "b", Type: product, Attrs: []*Attr{{Name: "sku", Value: "A1"}}})
+	ids, _ = g2.Lookup(product, "sku", "A1")
+	expect(ids).ToEqual([]string{"a", "b"})
+	ids, _ = g2.Lookup(product, "sku", "B1")
+	expect(ids).ToEqual([]string{})
+	err, ok := g2.ValidateChanges(g).(*ValidationError)
+	expect(ok).ToEqual(true)
+	expect(len(err.Violations)).ToEqual(1)
+	expect(err.Violations[0].NodeID).ToEqual("b")
+	expect(err.Violations[0].Rule).ToEqual(RuleUnique)
+	// older versions keep their own index
+	ids, _ = g.Lookup(product, "sku", "A1")
+	expect(ids).ToEqual([]string{"a"})
+	// removing the node frees the value
+	g3 := g.Remove("a")
+	ids, _ = g3.Lookup(product, "sku", "A1")
+	expect(ids).ToEqual([]string{})
+	// the index is built when a field becomes unique
+	g4 := g.DefineType(Type{
+		ID:     "product",
+		Name:   "Product",
+		Fields: Fields{{Name: "sku", Type: "Text"}, {Name: "name", Type: "Text", Unique: true}},
+	})
+	g4 = g4.Set(NodeConfig{ID: "c", Type: g4.TypeByID("product"), Attrs: []*Attr{{Name: "name", Value: "Hat"}}})
+	g4 = g4.DefineType(Type{
+		ID:     "product",
+		Name:   "Product",
+		Fields: Fields{{Name: "sku", Type: "Text", Unique: true}, {Name: "name", Type: "Text", Unique: true}},
+	})
+	ids, _ = g4.Lookup(g4.TypeByID("product"), "sku", "B1")
+	expect(ids).ToEqual([]string{"b"})
+	ids, _ = g4.Lookup(g4.TypeByID("product"), "name", "Hat")
+	expect(ids).ToEqual([]string{"c"})
+}
+
+func TestUniqueFieldRedefined(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := New().DefineType(Type{ID: "product", Name: "Product", Fields: Fields{{Name: "sku", Type: "Text"}}})
+	product := g.TypeByID("product")
+	g = g.Set(NodeConfig{ID: "a", Type: product, Attrs: []*Attr{{Name: "sku", Value: "A1"}}})
+	g = g.Set(NodeConfig{ID: "b", Type: product, Attrs: []*Attr{{Name: "sku", Value: "A1"}}})
+	// making a field unique checks the values nodes already hold
+	g2 := g.DefineType(Type{ID: "product", Name: "Product", Fields: Fields{{Name: "sku", Type: "Text", Unique: true}}})
+	err, ok := g2.ValidateChanges(g).(*ValidationError)
+	expect(ok).ToEqual(true)
+	expect(len(err.Violations)).ToEqual(2)
+	expect(err.Violations[0].Rule).ToEqual(RuleUnique)
+}
+
+func TestUniqueFieldSubtypes(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := New().
+		DefineType(Type{ID: "product", Name: "Product", Fields: Fields{{Name: "sku", Type: "Text", Unique: true}}}).
+		DefineType(Type{ID: "hat", Name: "Hat", Extends: []string{"product"}}).
+		DefineType(Type{ID: "shoe", Name: "Shoe", Extends: []string{"product"}})
+	g = g.Set(NodeConfig{ID: "a", Type: g.TypeByID("product"), Attrs: []*Attr{{Name: "sku", Value: "A1"}}})
+	g = g.Set(NodeConfig{ID: "h", Type: g.TypeByID("hat"), Attrs: []*Attr{{Name: "sku", Value: "H1"}}})
+	// values are unique across the declaring type and its subtypes
+	for _, v := range []string{"A1", "H1"} {
+		g2 := g.Set(NodeConfig{ID: "s", Type: g.TypeByID("shoe"), Attrs: []*Attr{{Name: "sku", Value: v}}})
+		err, ok := g2.ValidateChanges(g).(*ValidationError)
+		expect(ok).ToEqual(true)
+		expect(err.Violations[0].Rule).ToEqual(RuleUnique)
+	}
+	g2 := g.Set(NodeConfig{ID: "s", Type: g.TypeByID("shoe"), Attrs: []*Attr{{Name: "sku", Value: "S1"}}})
+	expect(g2.ValidateChanges(g)).ToEqual(nil)
+}
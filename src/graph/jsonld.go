@@ -0,0 +1,189 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// WriteJSONLD writes the graph as a JSON-LD document with every node
+// in "@graph". Terms are resolved against vocab, which should end in
+// "/" or "#". Each node's type name is its "@type", attrs become
+// properties and outbound edges become properties holding node
+// references. Edges use the name of the Edge field that holds them
+// where the type has one and the edge name otherwise. Edge attrs are
+// not written.
+func (g *Graph) WriteJSONLD(w io.Writer, vocab string) error {
+	doc := g.Document()
+	out := map[string]interface{}{
+		"@context": map[string]interface{}{
+			"@vocab": vocab,
+		},
+	}
+	nodes := []map[string]interface{}{}
+	byID := map[string]map[string]interface{}{}
+	for _, dn := range doc.Nodes {
+		t := g.TypeByID(dn.TypeID)
+		obj := map[string]interface{}{
+			"@id":   dn.ID,
+			"@type": t.Name,
+		}
+		for _, attr := range dn.Attrs {
+			if attr.Type() == JSONValue {
+				obj[attr.Name] = map[string]interface{}{"@value": attr.Value, "@type": "@json"}
+				continue
+			}
+			obj[attr.Name] = attr.Value
+		}
+		nodes = append(nodes, obj)
+		byID[dn.ID] = obj
+	}
+	for _, e := range doc.Edges {
+		obj := byID[e.From]
+		prop := e.Name
+		if f := jsonLDEdgeField(g.TypeByID(g.node(e.From).typeID), e.Name); f != nil {
+			prop = f.Name
+		}
+		refs, _ := obj[prop].([]interface{})
+		obj[prop] = append(refs, map[string]interface{}{"@id": e.To})
+	}
+	out["@graph"] = nodes
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// jsonLDEdgeField finds the Edge field of t that holds outbound edges
+// with the given name
+func jsonLDEdgeField(t *Type, edgeName string) *Field {
+	for _, f := range t.AllFields() {
+		if f.Type == "Edge" && f.EdgeName == edgeName && f.EdgeDirection != "In" {
+			return f
+		}
+	}
+	return nil
+}
+
+// ReadJSONLD reads a JSON-LD document of the form written by
+// WriteJSONLD into a Document that can be passed to Import. The
+// "@context" is not expanded, instead the vocab prefix is removed from
+// any term that starts with it. Node types are looked up by name in g.
+// Properties holding node references become edges, named by the Edge
+// field of that name if the type has one, and all others become attrs.
+func (g *Graph) ReadJSONLD(r io.Reader, vocab string) (*Document, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var in map[string]interface{}
+	if err := dec.Decode(&in); err != nil {
+		return nil, err
+	}
+	objs := []interface{}{in}
+	if graph, ok := in["@graph"].([]interface{}); ok {
+		objs = graph
+	}
+	term := func(s string) string {
+		if vocab != "" {
+			return strings.TrimPrefix(s, vocab)
+		}
+		return s
+	}
+	doc := &Document{
+		Types: []*Type{},
+		Nodes: []*DocumentNode{},
+		Edges: []*DocumentEdge{},
+	}
+	for _, v := range objs {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected node object in @graph")
+		}
+		id, _ := obj["@id"].(string)
+		if id == "" {
+			return nil, fmt.Errorf("node is missing @id")
+		}
+		typeName, _ := obj["@type"].(string)
+		t := g.TypeByName(term(typeName))
+		if t == nil {
+			return nil, fmt.Errorf("node '%s': type '%s' is not defined", id, typeName)
+		}
+		n := &DocumentNode{ID: id, TypeID: t.ID}
+		props := []string{}
+		for k := range obj {
+			if !strings.HasPrefix(k, "@") {
+				props = append(props, k)
+			}
+		}
+		sort.Strings(props)
+		for _, k := range props {
+			name := term(k)
+			if refs, ok := jsonLDRefs(obj[k]); ok {
+				edgeName := name
+				if f := t.Field(name); f != nil && f.Type == "Edge" && f.EdgeName != "" {
+					edgeName = f.EdgeName
+				}
+				for _, to := range refs {
+					doc.Edges = append(doc.Edges, &DocumentEdge{Name: edgeName, From: id, To: to})
+				}
+				continue
+			}
+			value, err := jsonLDValue(obj[k])
+			if err != nil {
+				return nil, fmt.Errorf("node '%s' property '%s': %s", id, k, err)
+			}
+			n.Attrs = append(n.Attrs, &Attr{Name: name, Value: value, Enc: "UTF8"})
+		}
+		doc.Nodes = append(doc.Nodes, n)
+	}
+	return doc, nil
+}
+
+// jsonLDRefs returns the ids of a node reference or list of them
+func jsonLDRefs(v interface{}) ([]string, bool) {
+	list, ok := v.([]interface{})
+	if !ok {
+		list = []interface{}{v}
+	}
+	ids := []string{}
+	for _, item := range list {
+		ref, ok := item.(map[string]interface{})
+		if !ok || len(ref) != 1 {
+			return nil, false
+		}
+		id, ok := ref["@id"].(string)
+		if !ok {
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, len(ids) > 0
+}
+
+// jsonLDValue converts a property value to an attr value
+func jsonLDValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string, bool:
+		return v, nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	case map[string]interface{}:
+		value, ok := v["@value"]
+		if !ok {
+			return nil, fmt.Errorf("nested objects are not supported")
+		}
+		if v["@type"] == "@json" {
+			b, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			return json.RawMessage(b), nil
+		}
+		return jsonLDValue(value)
+	default:
+		return nil, fmt.Errorf("unsupported value %v", v)
+	}
+}
@@ -0,0 +1,243 @@
+package graph
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// MergeConflict describes a change made on both sides of a merge that
+// could not be reconciled. The change from ours is always kept.
+type MergeConflict struct {
+	NodeID string     `json:"nodeID,omitempty"`
+	Attr   string     `json:"attr,omitempty"`
+	Edge   *EdgeMatch `json:"edge,omitempty"`
+	TypeID string     `json:"typeID,omitempty"`
+	Reason string     `json:"reason"`
+}
+
+func (c *MergeConflict) Error() string {
+	switch {
+	case c.Attr != "":
+		return fmt.Sprintf("node '%s' attr '%s': %s", c.NodeID, c.Attr, c.Reason)
+	case c.NodeID != "":
+		return fmt.Sprintf("node '%s': %s", c.NodeID, c.Reason)
+	case c.Edge != nil:
+		return fmt.Sprintf("edge '%s' from '%s' to '%s': %s", c.Edge.Name, c.Edge.From, c.Edge.To, c.Reason)
+	default:
+		return fmt.Sprintf("type '%s': %s", c.TypeID, c.Reason)
+	}
+}
+
+// Merge applies the changes made between base and ours on top of
+// theirs. Changes to different attrs of the same node are combined.
+// Where both sides changed the same thing differently the value from
+// ours is used and a MergeConflict is reported.
+func Merge(base, ours, theirs *Graph) (*Graph, []*MergeConflict) {
+	m := &merger{
+		g:      theirs,
+		ours:   Diff(base, ours),
+		theirs: Diff(base, theirs),
+	}
+	m.mergeTypes()
+	m.mergeNodes()
+	m.mergeEdges()
+	return m.g, m.conflicts
+}
+
+type merger struct {
+	g         *Graph
+	ours      *Changeset
+	theirs    *Changeset
+	conflicts []*MergeConflict
+}
+
+func (m *merger) conflict(c *MergeConflict) {
+	m.conflicts = append(m.conflicts, c)
+}
+
+func (m *merger) mergeTypes() {
+	theirs := map[string]*TypeChange{}
+	for _, c := range m.theirs.Types {
+		theirs[c.ID] = c
+	}
+	for _, c := range m.ours.Types {
+		if c.New == nil {
+			continue
+		}
+		if tc, ok := theirs[c.ID]; ok && !reflect.DeepEqual(tc.New, c.New) {
+			m.conflict(&MergeConflict{TypeID: c.ID, Reason: "type was also redefined by theirs"})
+		}
+		t := *c.New
+		if err := m.g.ValidateType(&t); err != nil {
+			m.conflict(&MergeConflict{TypeID: c.ID, Reason: err.Error()})
+			continue
+		}
+		m.g = m.g.DefineType(t)
+	}
+}
+
+func (m *merger) mergeNodes() {
+	theirs := map[string]*NodeChange{}
+	for _, c := range m.theirs.Nodes {
+		theirs[c.ID] = c
+	}
+	for _, c := range m.ours.Nodes {
+		tc := theirs[c.ID]
+		switch {
+		case tc == nil && c.Kind == Removed:
+			m.g = m.g.remove(c.ID)
+		case tc == nil:
+			m.putNode(c.New.n)
+		case c.Kind == Removed:
+			if tc.Kind != Removed {
+				m.conflict(&MergeConflict{NodeID: c.ID, Reason: "node was removed but modified by theirs"})
+				m.g = m.g.remove(c.ID)
+			}
+		case tc.Kind == Removed:
+			m.conflict(&MergeConflict{NodeID: c.ID, Reason: "node was modified but removed by theirs"})
+			m.putNode(c.New.n)
+		default:
+			m.putNode(m.mergeNode(c, tc))
+		}
+	}
+}
+
+// mergeNode combines two modifications of the same node attr by attr
+func (m *merger) mergeNode(c, tc *NodeChange) *node {
+	var base *node
+	if c.Old != nil {
+		base = c.Old.n
+	}
+	ours, theirs := c.New.n, tc.New.n
+	n := &node{
+		id:     c.ID,
+		typeID: theirs.typeID,
+	}
+	if base == nil || ours.typeID != base.typeID {
+		if theirs.typeID != ours.typeID && base != nil && theirs.typeID != base.typeID {
+			m.conflict(&MergeConflict{NodeID: c.ID, Reason: "type was also changed by theirs"})
+		}
+		n.typeID = ours.typeID
+	}
+	changed := map[string]*Attr{}
+	removed := map[string]bool{}
+	for _, ac := range c.Attrs {
+		theirAttr := findAttr(theirs.attrs, ac.Name)
+		var baseAttr *Attr
+		if base != nil {
+			baseAttr = findAttr(base.attrs, ac.Name)
+		}
+		if !attrsEqual(theirAttr, baseAttr) && !attrsEqual(theirAttr, ac.New) {
+			m.conflict(&MergeConflict{NodeID: c.ID, Attr: ac.Name, Reason: "attr was also changed by theirs"})
+		}
+		if ac.New == nil {
+			removed[ac.Name] = true
+		} else {
+			changed[ac.Name] = ac.New
+		}
+	}
+	for _, attr := range theirs.attrs {
+		if removed[attr.Name] {
+			continue
+		}
+		if ourAttr, ok := changed[attr.Name]; ok {
+			n.attrs = append(n.attrs, ourAttr)
+			delete(changed, attr.Name)
+			continue
+		}
+		n.attrs = append(n.attrs, attr)
+	}
+	for _, attr := range ours.attrs {
+		if _, ok := changed[attr.Name]; ok {
+			n.attrs = append(n.attrs, attr)
+		}
+	}
+	return n
+}
+
+func (m *merger) putNode(n *node) {
+	g2 := m.g.clone()
+	g2.putNode(n)
+	m.g = g2
+}
+
+func (m *merger) mergeEdges() {
+	theirs := map[string]*EdgeChange{}
+	for _, c := range m.theirs.Edges {
+		theirs[edgeChangeKey(c)] = c
+	}
+	// keep new edges in the order they were connected
+	changes := append([]*EdgeChange{}, m.ours.Edges...)
+	sort.SliceStable(changes, func(i, j int) bool {
+		return edgeChangeSeq(changes[i]) < edgeChangeSeq(changes[j])
+	})
+	for _, c := range changes {
+		k := edgeChangeKey(c)
+		tc := theirs[k]
+		e := c.Old
+		if c.New != nil {
+			e = c.New
+		}
+		match := &EdgeMatch{From: e.e.from, Name: e.e.name, To: e.e.to}
+		if tc != nil && !edgesEqual(tc, c) {
+			m.conflict(&MergeConflict{Edge: match, Reason: "edge was also changed by theirs"})
+		}
+		if c.New == nil {
+			m.g = m.g.Disconnect(*match)
+			continue
+		}
+		if m.g.node(e.e.from) == nil || m.g.node(e.e.to) == nil {
+			m.conflict(&MergeConflict{Edge: match, Reason: "connected node was removed by theirs"})
+			continue
+		}
+		ne := *c.New.e
+		g2 := m.g.clone()
+		if old, ok := m.g.edges.get(k); ok {
+			g2.removeEdge(old.(*edge))
+			if c.Kind == Added {
+				ne.seq = old.(*edge).seq
+			}
+		} else if c.Kind == Added {
+			g2.seq++
+			ne.seq = g2.seq
+		}
+		g2.addEdge(&ne)
+		m.g = g2
+	}
+}
+
+func edgeChangeSeq(c *EdgeChange) uint64 {
+	if c.New != nil {
+		return c.New.e.seq
+	}
+	return c.Old.e.seq
+}
+
+func findAttr(attrs []*Attr, name string) *Attr {
+	for _, attr := range attrs {
+		if attr.Name == name {
+			return attr
+		}
+	}
+	return nil
+}
+
+func attrsEqual(a, b *Attr) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Enc == b.Enc && ValuesEqual(a.Value, b.Value)
+}
+
+// edgesEqual reports whether both sides made the same change to an
+// edge. Edges connected on both sides are equal if their attrs match.
+func edgesEqual(a, b *EdgeChange) bool {
+	if a.New == nil || b.New == nil {
+		return a.New == b.New
+	}
+	if a.Kind == Modified && b.Kind == Modified && a.New.e.seq != b.New.e.seq {
+		return false
+	}
+	return reflect.DeepEqual(a.New.e.attrs, b.New.e.attrs)
+}
@@ -0,0 +1,84 @@
+package graph
+
+import (
+	"testing"
+
+	"testutil"
+)
+
+func mergeBase() *Graph {
+	g := New().DefineType(Type{ID: "page", Name: "Page"})
+	g = g.Set(NodeConfig{
+		ID:   "home",
+		Type: g.TypeByID("page"),
+		Attrs: []*Attr{
+			&Attr{Name: "title", Value: "Home"},
+			&Attr{Name: "body", Value: "hello"},
+		},
+	})
+	g = g.Set(NodeConfig{ID: "about", Type: g.TypeByID("page")})
+	return g
+}
+
+func attrValues(n *Node) map[string]interface{} {
+	vs := map[string]interface{}{}
+	for _, attr := range n.Attrs() {
+		vs[attr.Name] = attr.Value
+	}
+	return vs
+}
+
+func TestMergeCombinesAttrEdits(t *testing.T) {
+	expect := testutil.Expect(t)
+	base := mergeBase()
+	ours := base.Set(NodeConfig{ID: "home", Merge: true, Attrs: []*Attr{
+		&Attr{Name: "title", Value: "Welcome"},
+	}})
+	theirs := base.Set(NodeConfig{ID: "home", Merge: true, Attrs: []*Attr{
+		&Attr{Name: "body", Value: "hi there"},
+		&Attr{Name: "slug", Value: "home"},
+	}})
+	g, conflicts := Merge(base, ours, theirs)
+	expect(len(conflicts)).ToEqual(0)
+	expect(attrValues(g.Get("home"))).ToEqual(map[string]interface{}{
+		"title": "Welcome",
+		"body":  "hi there",
+		"slug":  "home",
+	})
+}
+
+func TestMergeReportsConflicts(t *testing.T) {
+	expect := testutil.Expect(t)
+	base := mergeBase()
+	ours := base.Set(NodeConfig{ID: "home", Merge: true, Attrs: []*Attr{
+		&Attr{Name: "title", Value: "Ours"},
+	}})
+	ours = ours.Set(NodeConfig{ID: "about", Attrs: []*Attr{
+		&Attr{Name: "title", Value: "About"},
+	}})
+	theirs := base.Set(NodeConfig{ID: "home", Merge: true, Attrs: []*Attr{
+		&Attr{Name: "title", Value: "Theirs"},
+	}})
+	theirs = theirs.Remove("about")
+	g, conflicts := Merge(base, ours, theirs)
+	expect(len(conflicts)).ToEqual(2)
+	expect(conflicts[0].Error()).ToEqual("node 'about': node was modified but removed by theirs")
+	expect(conflicts[1].Attr).ToEqual("title")
+	expect(g.Get("home").Attr("title").Value).ToEqual("Ours")
+	expect(g.Get("about")).ToNotBeNil()
+}
+
+func TestMergeEdges(t *testing.T) {
+	expect := testutil.Expect(t)
+	base := mergeBase()
+	base = base.Set(NodeConfig{ID: "contact", Type: base.TypeByID("page")})
+	ours := base.Connect(EdgeConfig{From: "home", To: "about", Name: "link"})
+	ours = ours.Connect(EdgeConfig{From: "home", To: "contact", Name: "link"})
+	theirs := base.Connect(EdgeConfig{From: "about", To: "home", Name: "link"})
+	theirs = theirs.Remove("contact")
+	g, conflicts := Merge(base, ours, theirs)
+	expect(len(conflicts)).ToEqual(1)
+	expect(conflicts[0].Edge.To).ToEqual("contact")
+	expect(len(g.Edges(EdgeMatch{Name: "link"}))).ToEqual(2)
+	expect(g.Edges(EdgeMatch{Name: "link"})[1].From().ID()).ToEqual("home")
+}
@@ -0,0 +1,226 @@
+package graph
+
+import "fmt"
+
+// MigrationFailure describes an attr value that could not be converted
+// to the type of its field
+type MigrationFailure struct {
+	NodeID string      `json:"nodeID"`
+	Field  string      `json:"field"`
+	Value  interface{} `json:"value"`
+	Reason string      `json:"reason"`
+}
+
+// RenameField renames a field declared by the type with the given id
+// and renames the matching attr on every node of that type or any of
+// its subtypes.
+func (g *Graph) RenameField(typeID, from, to string) (*Graph, error) {
+	t, f, err := g.migratingField(typeID, from)
+	if err != nil {
+		return g, err
+	}
+	if to == "" {
+		return g, fmt.Errorf("cannot rename field '%s': new name is blank", from)
+	}
+	if to == from {
+		return g, nil
+	}
+	if t.Field(to) != nil {
+		return g, fmt.Errorf("cannot rename field '%s': type '%s' already has a field called '%s'", from, t.ID, to)
+	}
+	for _, st := range g.Subtypes(t) {
+		if st.Field(to) != nil {
+			return g, fmt.Errorf("cannot rename field '%s': type '%s' already has a field called '%s'", from, st.ID, to)
+		}
+	}
+	f2 := *f
+	f2.Name = to
+	g2 := g.DefineType(t.withField(from, &f2))
+	if f.Type == "Edge" || f.Type == "Computed" {
+		// edges are matched by edge name and computed values are not
+		// stored so there are no attrs to move
+		return g2, nil
+	}
+	g2 = g2.migrateAttrs(t, from, func(attr *Attr) (*Attr, error) {
+		return &Attr{Name: to, Value: attr.Value, Enc: attr.Enc}, nil
+	})
+	return g2, nil
+}
+
+// FieldConversion is the type a field is changed to along with the
+// options that type needs. EnumValues and EnumMulti are used when
+// converting to Enum and DataColumns when converting to DataTable.
+type FieldConversion struct {
+	Type        string
+	EnumValues  []string
+	EnumMulti   bool
+	DataColumns []*DataColumn
+}
+
+// ChangeFieldType changes the type of a field declared by the type with
+// the given id and coerces the matching attr on every node of that type
+// or any of its subtypes. Values that cannot be coerced are removed
+// and reported. Text values that are not JSON are read as CSV when
+// converting to DataTable.
+func (g *Graph) ChangeFieldType(typeID, name string, to FieldConversion) (*Graph, []*MigrationFailure, error) {
+	t, f, err := g.migratingField(typeID, name)
+	if err != nil {
+		return g, nil, err
+	}
+	fieldType := to.Type
+	if f.Type == fieldType {
+		return g, nil, nil
+	}
+	if f.Type == "Edge" || fieldType == "Edge" {
+		return g, nil, fmt.Errorf("cannot change type of field '%s': fields cannot be converted to or from Edge", name)
+	}
+	if f.Type == "Computed" || fieldType == "Computed" {
+		return g, nil, fmt.Errorf("cannot change type of field '%s': fields cannot be converted to or from Computed", name)
+	}
+	f2 := *f
+	f2.Type = fieldType
+	f2.EnumValues, f2.EnumMulti, f2.DataColumns = nil, false, nil
+	if fieldType == "Enum" {
+		f2.EnumValues, f2.EnumMulti = to.EnumValues, to.EnumMulti
+		if err := validateEnum(&f2); err != nil {
+			return g, nil, fmt.Errorf("cannot change type of field '%s': %s", name, err)
+		}
+	}
+	if fieldType == "DataTable" {
+		f2.DataColumns = to.DataColumns
+		if err := validateDataColumns(&f2); err != nil {
+			return g, nil, fmt.Errorf("cannot change type of field '%s': %s", name, err)
+		}
+	}
+	if f2.Default != "" {
+		if err := validateDefault(&f2); err != nil {
+			return g, nil, fmt.Errorf("cannot change type of field '%s': default %s", name, err)
+		}
+	}
+	failures := []*MigrationFailure{}
+	g2 := g.DefineType(t.withField(name, &f2))
+	g2 = g2.migrateAttrs(t, name, func(attr *Attr) (*Attr, error) {
+		enc := attr.Enc
+		if fieldType == "DataTable" && enc != "JSON" {
+			enc = "CSV"
+		}
+		v, err := f2.Coerce(attr.Value, enc)
+		if err != nil {
+			return nil, err
+		}
+		if fieldType == "Enum" {
+			if _, err := f2.EnumSelection(v); err != nil {
+				return nil, err
+			}
+		}
+		if fieldType == "DataTable" {
+			enc = "JSON"
+		}
+		return &Attr{Name: name, Value: v, Enc: enc}, nil
+	}, func(n *node, attr *Attr, err error) {
+		failures = append(failures, &MigrationFailure{
+			NodeID: n.id,
+			Field:  name,
+			Value:  attr.Value,
+			Reason: err.Error(),
+		})
+	})
+	return g2, failures, nil
+}
+
+// DropField removes a field declared by the type with the given id and
+// purges the matching attr from every node of that type or any of its
+// subtypes.
+func (g *Graph) DropField(typeID, name string) (*Graph, error) {
+	t, f, err := g.migratingField(typeID, name)
+	if err != nil {
+		return g, err
+	}
+	g2 := g.DefineType(t.withField(name, nil))
+	if f.Type == "Edge" {
+		return g2, nil
+	}
+	g2 = g2.migrateAttrs(t, name, func(attr *Attr) (*Attr, error) {
+		return nil, nil
+	})
+	return g2, nil
+}
+
+// migratingField finds a field that is declared by the type itself.
+// Inherited fields must be migrated on the parent that declares them
+// and fields redeclared by a subtype are left alone.
+func (g *Graph) migratingField(typeID, name string) (*Type, *Field, error) {
+	t := g.TypeByID(typeID)
+	if t == nil {
+		return nil, nil, fmt.Errorf("cannot migrate field '%s': type '%s' is not defined", name, typeID)
+	}
+	if !t.declares(name) {
+		if t.Field(name) != nil {
+			return nil, nil, fmt.Errorf("cannot migrate field '%s': it is inherited by type '%s'", name, typeID)
+		}
+		return nil, nil, fmt.Errorf("cannot migrate field '%s': type '%s' does not define it", name, typeID)
+	}
+	for _, st := range g.Subtypes(t) {
+		if st.declares(name) {
+			return nil, nil, fmt.Errorf("cannot migrate field '%s': it is redeclared by type '%s'", name, st.ID)
+		}
+	}
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return t, f, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("cannot migrate field '%s': type '%s' does not define it", name, typeID)
+}
+
+// withField returns a copy of t with the named field replaced by f or
+// removed if f is nil
+func (t *Type) withField(name string, f *Field) Type {
+	t2 := *t
+	t2.Fields = Fields{}
+	for _, old := range t.Fields {
+		if old.Name != name {
+			t2.Fields = append(t2.Fields, old)
+		} else if f != nil {
+			t2.Fields = append(t2.Fields, f)
+		}
+	}
+	return t2
+}
+
+// migrateAttrs rewrites the named attr on every node of type t or its
+// subtypes using fn. A nil attr removes it. If fn fails the attr is
+// removed and the failure passed to each of onFail.
+func (g *Graph) migrateAttrs(t *Type, name string, fn func(*Attr) (*Attr, error), onFail ...func(*node, *Attr, error)) *Graph {
+	g2 := g
+	for _, n := range g.Nodes().FilterType(t) {
+		old := findAttr(n.n.attrs, name)
+		if old == nil {
+			continue
+		}
+		attrs := []*Attr{}
+		for _, attr := range n.n.attrs {
+			if attr != old {
+				attrs = append(attrs, attr)
+				continue
+			}
+			migrated, err := fn(attr)
+			if err != nil {
+				for _, cb := range onFail {
+					cb(n.n, attr, err)
+				}
+				continue
+			}
+			if migrated != nil {
+				attrs = append(attrs, migrated)
+			}
+		}
+		g2 = g2.clone()
+		g2.putNode(&node{
+			id:     n.n.id,
+			typeID: n.n.typeID,
+			attrs:  attrs,
+		})
+	}
+	return g2
+}
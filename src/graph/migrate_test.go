@@ -0,0 +1,90 @@
+package graph
+
+import (
+	"testing"
+
+	"testutil"
+)
+
+func migrationGraph() *Graph {
+	g := inheritanceGraph()
+	post := g.TypeByID("post")
+	g = g.Set(NodeConfig{ID: "p1", Type: post, Attrs: []*Attr{
+		&Attr{Name: "title", Value: "First"},
+		&Attr{Name: "slug", Value: "10"},
+	}})
+	g = g.Set(NodeConfig{ID: "p2", Type: post, Attrs: []*Attr{
+		&Attr{Name: "title", Value: "Second"},
+		&Attr{Name: "slug", Value: "second"},
+	}})
+	return g
+}
+
+func TestRenameField(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := migrationGraph()
+	_, err := g.RenameField("post", "title", "heading")
+	expect(err).ToNotBeNil()
+	_, err = g.RenameField("page", "title", "slug")
+	expect(err).ToNotBeNil()
+	g2, err := g.RenameField("page", "title", "heading")
+	expect(err).ToEqual(nil)
+	expect(fieldNames(g2.TypeByID("post").AllFields())).ToEqual([]string{"heading", "slug", "body"})
+	expect(g2.Get("p1").Attr("title")).ToBeNil()
+	expect(g2.Get("p1").Attr("heading").Value).ToEqual("First")
+	expect(g2.Validiate()).ToEqual(nil)
+	// the original graph is untouched
+	expect(g.Get("p1").Attr("title").Value).ToEqual("First")
+}
+
+func TestChangeFieldType(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := migrationGraph()
+	g2, failures, err := g.ChangeFieldType("page", "slug", FieldConversion{Type: "Int"})
+	expect(err).ToEqual(nil)
+	expect(g2.TypeByID("page").Field("slug").Type).ToEqual("Int")
+	expect(g2.Get("p1").Attr("slug").Value).ToEqual(int64(10))
+	expect(g2.Get("p2").Attr("slug")).ToBeNil()
+	expect(len(failures)).ToEqual(1)
+	expect(failures[0].NodeID).ToEqual("p2")
+	expect(failures[0].Value).ToEqual("second")
+	_, _, err = g.ChangeFieldType("post", "body", FieldConversion{Type: "Edge"})
+	expect(err).ToNotBeNil()
+}
+
+func TestChangeFieldTypeOptions(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := migrationGraph()
+	_, _, err := g.ChangeFieldType("page", "title", FieldConversion{Type: "Enum"})
+	expect(err).ToNotBeNil()
+	g2, failures, err := g.ChangeFieldType("page", "title", FieldConversion{Type: "Enum", EnumValues: []string{"First"}})
+	expect(err).ToEqual(nil)
+	expect(g2.TypeByID("page").Field("title").EnumValues).ToEqual([]string{"First"})
+	expect(g2.Get("p1").Attr("title").Value).ToEqual("First")
+	expect(len(failures)).ToEqual(1)
+	expect(failures[0].NodeID).ToEqual("p2")
+
+	// text is read as CSV
+	specs := []*DataColumn{{Name: "size", Type: "Text"}, {Name: "qty", Type: "Int"}}
+	_, _, err = g.ChangeFieldType("page", "title", FieldConversion{Type: "DataTable"})
+	expect(err).ToNotBeNil()
+	g2, failures, err = g.ChangeFieldType("page", "slug", FieldConversion{Type: "DataTable", DataColumns: specs})
+	expect(err).ToEqual(nil)
+	expect(len(failures)).ToEqual(0)
+	attr := g2.Get("p2").Attr("slug")
+	expect(attr.Enc).ToEqual("JSON")
+	expect(FormatValue(attr.Value)).ToEqual(`[{"size":"second"}]`)
+	expect(g2.Validiate()).ToEqual(nil)
+}
+
+func TestDropField(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := migrationGraph()
+	g2, err := g.DropField("page", "slug")
+	expect(err).ToEqual(nil)
+	expect(g2.TypeByID("post").Field("slug")).ToBeNil()
+	expect(g2.Get("p1").Attr("slug")).ToBeNil()
+	expect(g2.Get("p1").Attr("title").Value).ToEqual("First")
+	_, err = g.DropField("page", "missing")
+	expect(err).ToNotBeNil()
+}
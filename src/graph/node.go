@@ -9,10 +9,27 @@ func stringIn(needle string, haystack []string) bool {
 	return false
 }
 
+// Attr is a named value on a node or edge. Value holds one of the
+// types described by ValueType.
 type Attr struct {
-	Name  string `json:"name"`
-	Value string `json:"value"`
-	Enc   string `json:"enc"`
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+	Enc   string      `json:"enc"`
+}
+
+// String returns the value in string form
+func (attr *Attr) String() string {
+	return FormatValue(attr.Value)
+}
+
+// Type returns the type of the stored value
+func (attr *Attr) Type() ValueType {
+	return TypeOf(attr.Value)
+}
+
+// Empty reports whether the attr holds no value
+func (attr *Attr) Empty() bool {
+	return attr.Value == nil || attr.Value == ""
 }
 
 type NodeConfig struct {
@@ -20,6 +37,9 @@ type NodeConfig struct {
 	Type  *Type   `json:"type"`
 	Attrs []*Attr `json:"attrs"`
 	Merge bool    `json:"merge"`
+	// RemoveAttrs names attrs of the existing node that are not kept
+	// when merging
+	RemoveAttrs []string `json:"removeAttrs"`
 }
 
 type Nodes []*Node
@@ -31,6 +51,8 @@ func (ns Nodes) First() *Node {
 	return ns[0]
 }
 
+// FilterType returns the nodes that are of any of the given types or
+// one of their subtypes
 func (ns Nodes) FilterType(types ...*Type) Nodes {
 	if len(types) == 0 {
 		return ns
@@ -41,7 +63,7 @@ func (ns Nodes) FilterType(types ...*Type) Nodes {
 	ns2 := Nodes{}
 	for _, n := range ns {
 		for _, t := range types {
-			if n.Type() == t {
+			if n.g.IsA(n.Type(), t) {
 				ns2 = append(ns2, n)
 				break
 			}
@@ -87,29 +109,46 @@ func (n *Node) Attr(key string) *Attr {
 	return nil
 }
 
+// Value returns the value of the attr for field f or the default of f
+// when n does not have it
+func (n *Node) Value(f *Field) interface{} {
+	if attr := n.Attr(f.Name); attr != nil && !attr.Empty() {
+		return attr.Value
+	}
+	return f.DefaultValue()
+}
+
 func (n *Node) Attrs() []*Attr {
 	return n.n.attrs
 }
 
 func (n *Node) Edges(edgeNames []string, edgeDir string) Edges {
-	edges := Edges{}
-	for _, e := range n.g.edges {
-		if edgeDir == "Out" {
-			if e.from != n.n.id {
-				continue
+	es := []*edge{}
+	collect := func(idx *hamt, skipLoops bool) {
+		v, _ := idx.get(n.n.id)
+		set, _ := v.(*hamt)
+		set.each(func(_ string, v interface{}) bool {
+			e := v.(*edge)
+			if skipLoops && e.from == e.to {
+				return true
 			}
-		} else if edgeDir == "In" {
-			if e.to != n.n.id {
-				continue
+			if len(edgeNames) > 0 && !stringIn(e.name, edgeNames) {
+				return true
 			}
-		} else if e.to != n.n.id && e.from != n.n.id {
-			continue
-		}
-		if len(edgeNames) > 0 {
-			if !stringIn(e.name, edgeNames) {
-				continue
-			}
-		}
+			es = append(es, e)
+			return true
+		})
+	}
+	if edgeDir != "In" {
+		collect(n.g.out, false)
+	}
+	if edgeDir != "Out" {
+		// self referencing edges were already collected from out
+		collect(n.g.in, edgeDir != "In")
+	}
+	sortEdges(es)
+	edges := Edges{}
+	for _, e := range es {
 		edges = append(edges, &Edge{
 			e: e,
 			g: n.g,
@@ -117,3 +156,23 @@ func (n *Node) Edges(edgeNames []string, edgeDir string) Edges {
 	}
 	return edges
 }
+
+// FieldEdges returns the edges that make up the Edge field f of n in
+// the order they were connected
+func (n *Node) FieldEdges(f *Field) Edges {
+	var edgeNames []string
+	if f.EdgeName != "" {
+		edgeNames = append(edgeNames, f.EdgeName)
+	}
+	return n.Edges(edgeNames, f.EdgeDirection)
+}
+
+// fieldTargets returns the ids of the nodes connected to n through the
+// Edge field f
+func (n *Node) fieldTargets(f *Field) []string {
+	ids := []string{}
+	for _, e := range n.FieldEdges(f) {
+		ids = append(ids, e.e.other(n.n.id, f.EdgeDirection))
+	}
+	return ids
+}
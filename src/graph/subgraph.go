@@ -0,0 +1,189 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Subgraph returns a self contained graph holding every node reachable
+// from the roots using t, the edges between those nodes and the types
+// they need, including any parent types.
+func (g *Graph) Subgraph(rootIDs []string, t Traversal) *Graph {
+	sub := New()
+	for _, id := range rootIDs {
+		g.BFS(id, t, func(s *Step) bool {
+			if sub.node(s.Node.ID()) == nil {
+				sub.nodes = sub.nodes.set(s.Node.ID(), s.Node.n)
+			}
+			return true
+		})
+	}
+	needed := map[string]bool{}
+	sub.nodes.each(func(id string, v interface{}) bool {
+		n := &Node{n: v.(*node), g: g}
+		if nt := n.Type(); nt != nil {
+			needed[nt.ID] = true
+			for _, a := range g.Ancestors(nt) {
+				needed[a.ID] = true
+			}
+		}
+		for _, e := range n.Edges(nil, "Out") {
+			if sub.node(e.e.to) != nil {
+				sub.addEdge(e.e)
+			}
+		}
+		return true
+	})
+	for _, nt := range g.types {
+		if needed[nt.ID] {
+			sub.types = append(sub.types, nt)
+		}
+	}
+	sub.seq = g.seq
+	sub.reindexTypes(New())
+	return sub
+}
+
+// Document is a portable representation of a graph that can be encoded
+// as JSON and imported into another graph
+type Document struct {
+	Types []*Type         `json:"types"`
+	Nodes []*DocumentNode `json:"nodes"`
+	Edges []*DocumentEdge `json:"edges"`
+}
+
+type DocumentNode struct {
+	ID     string  `json:"id"`
+	TypeID string  `json:"typeID"`
+	Attrs  []*Attr `json:"attrs"`
+}
+
+type DocumentEdge struct {
+	Name  string  `json:"name"`
+	From  string  `json:"from"`
+	To    string  `json:"to"`
+	Attrs []*Attr `json:"attrs"`
+}
+
+// Document returns the whole graph as a Document. Types are listed
+// with parents before the types that extend them, nodes by id and
+// edges in rank order.
+func (g *Graph) Document() *Document {
+	doc := &Document{
+		Types: []*Type{},
+		Nodes: []*DocumentNode{},
+		Edges: []*DocumentEdge{},
+	}
+	added := map[string]bool{}
+	var addType func(t *Type)
+	addType = func(t *Type) {
+		if added[t.ID] {
+			return
+		}
+		added[t.ID] = true
+		for _, p := range g.Parents(t) {
+			addType(p)
+		}
+		doc.Types = append(doc.Types, t)
+	}
+	for _, t := range g.types {
+		addType(t)
+	}
+	ns := g.Nodes()
+	sort.Sort(ns)
+	for _, n := range ns {
+		doc.Nodes = append(doc.Nodes, &DocumentNode{
+			ID:     n.n.id,
+			TypeID: n.n.typeID,
+			Attrs:  n.n.attrs,
+		})
+	}
+	for _, e := range g.match(EdgeMatch{}) {
+		doc.Edges = append(doc.Edges, &DocumentEdge{
+			Name:  e.name,
+			From:  e.from,
+			To:    e.to,
+			Attrs: e.attrs,
+		})
+	}
+	return doc
+}
+
+// Import adds the contents of doc to the graph. Each node id is passed
+// through remap, which may be nil to keep the ids unchanged. Types are
+// only defined if the graph does not already have a type with the same
+// id. Attr values are coerced to the types of their fields since JSON
+// does not distinguish between them.
+func (g *Graph) Import(doc *Document, remap func(id string) string) (*Graph, error) {
+	if remap == nil {
+		remap = func(id string) string { return id }
+	}
+	g2 := g
+	for _, t := range doc.Types {
+		if g2.TypeByID(t.ID) != nil {
+			continue
+		}
+		if err := g2.ValidateType(t); err != nil {
+			return g, err
+		}
+		g2 = g2.DefineType(*t)
+	}
+	ids := map[string]string{}
+	for _, dn := range doc.Nodes {
+		id := remap(dn.ID)
+		if id == "" || g2.node(id) != nil {
+			return g, fmt.Errorf("cannot import node '%s': id '%s' is already in use", dn.ID, id)
+		}
+		t := g2.TypeByID(dn.TypeID)
+		if t == nil || t.Abstract {
+			return g, fmt.Errorf("cannot import node '%s': type '%s' is not defined or is abstract", dn.ID, dn.TypeID)
+		}
+		var attrs []*Attr
+		for _, attr := range dn.Attrs {
+			v, err := Coerce(attr.Value, importValueType(attr))
+			if f := t.Field(attr.Name); f != nil {
+				v, err = f.Coerce(attr.Value, attr.Enc)
+			}
+			if err != nil {
+				return g, fmt.Errorf("cannot import node '%s' attr '%s': %s", dn.ID, attr.Name, err)
+			}
+			attrs = append(attrs, &Attr{Name: attr.Name, Value: v, Enc: attr.Enc})
+		}
+		ids[dn.ID] = id
+		g2 = g2.Set(NodeConfig{ID: id, Type: t, Attrs: attrs})
+	}
+	for _, de := range doc.Edges {
+		from, ok := ids[de.From]
+		if !ok {
+			return g, fmt.Errorf("cannot import edge '%s': node '%s' is not in the document", de.Name, de.From)
+		}
+		to, ok := ids[de.To]
+		if !ok {
+			return g, fmt.Errorf("cannot import edge '%s': node '%s' is not in the document", de.Name, de.To)
+		}
+		var attrs []*Attr
+		for _, attr := range de.Attrs {
+			v, err := Coerce(attr.Value, importValueType(attr))
+			if err != nil {
+				return g, fmt.Errorf("cannot import edge '%s' attr '%s': %s", de.Name, attr.Name, err)
+			}
+			attrs = append(attrs, &Attr{Name: attr.Name, Value: v, Enc: attr.Enc})
+		}
+		g2 = g2.Connect(EdgeConfig{Name: de.Name, From: from, To: to, Attrs: attrs})
+	}
+	return g2, nil
+}
+
+// importValueType guesses the type of an attr value decoded from JSON
+// that does not belong to a field
+func importValueType(attr *Attr) ValueType {
+	switch attr.Value.(type) {
+	case string, float64, bool, nil:
+		if attr.Enc == "JSON" {
+			return JSONValue
+		}
+		return TypeOf(attr.Value)
+	default:
+		return JSONValue
+	}
+}
@@ -0,0 +1,75 @@
+package graph
+
+import (
+	"encoding/json"
+	"testing"
+
+	"testutil"
+)
+
+func productGraph() *Graph {
+	g := inheritanceGraph()
+	g = g.DefineType(Type{
+		ID:   "product",
+		Name: "Product",
+		Fields: Fields{
+			{Name: "name", Type: "Text"},
+			{Name: "price", Type: "Int"},
+		},
+	})
+	product := g.TypeByID("product")
+	for _, id := range []string{"shirt", "shirt-red", "shirt-blue", "hat"} {
+		g = g.Set(NodeConfig{ID: id, Type: product, Attrs: []*Attr{
+			&Attr{Name: "name", Value: id},
+			&Attr{Name: "price", Value: int64(10)},
+		}})
+	}
+	g = g.Set(NodeConfig{ID: "post", Type: g.TypeByID("post")})
+	g = g.Connect(EdgeConfig{From: "shirt", To: "shirt-red", Name: "variant"})
+	g = g.Connect(EdgeConfig{From: "shirt", To: "shirt-blue", Name: "variant"})
+	g = g.Connect(EdgeConfig{From: "shirt-red", To: "shirt-blue", Name: "sibling"})
+	g = g.Connect(EdgeConfig{From: "shirt", To: "hat", Name: "related"})
+	g = g.Connect(EdgeConfig{From: "post", To: "shirt", Name: "mentions"})
+	return g
+}
+
+func TestSubgraph(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := productGraph()
+	sub := g.Subgraph([]string{"shirt"}, Traversal{EdgeNames: []string{"variant"}, Direction: "Out"})
+	ns := sub.Nodes()
+	expect(len(ns)).ToEqual(3)
+	expect(sub.Get("hat")).ToBeNil()
+	// internal edges are kept even if they were not followed
+	expect(len(sub.Edges(EdgeMatch{}))).ToEqual(3)
+	expect(len(sub.Types())).ToEqual(1)
+	expect(sub.Get("shirt-red").Attr("price").Value).ToEqual(int64(10))
+	// types needed by the nodes bring their parents with them
+	sub = g.Subgraph([]string{"post"}, Traversal{MaxDepth: 1})
+	expect(len(sub.Types())).ToEqual(3)
+	expect(len(sub.Edges(EdgeMatch{}))).ToEqual(1)
+}
+
+func TestImportDocument(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := productGraph()
+	sub := g.Subgraph([]string{"post"}, Traversal{Direction: "Out"})
+	b, err := json.Marshal(sub.Document())
+	expect(err).ToEqual(nil)
+	doc := &Document{}
+	expect(json.Unmarshal(b, doc)).ToEqual(nil)
+	// import into an empty graph keeping ids
+	g2, err := New().Import(doc, nil)
+	expect(err).ToEqual(nil)
+	expect(Diff(sub, g2).Empty()).ToEqual(true)
+	// import into the original graph with new ids
+	_, err = g.Import(doc, nil)
+	expect(err).ToNotBeNil()
+	g3, err := g.Import(doc, func(id string) string { return "copy-" + id })
+	expect(err).ToEqual(nil)
+	expect(g3.Get("copy-shirt-red").Attr("price").Value).ToEqual(int64(10))
+	es := g3.Get("copy-shirt").Edges(nil, "Out")
+	expect(len(es)).ToEqual(3)
+	expect(es[0].To().ID()).ToEqual("copy-shirt-red")
+	expect(len(g3.Nodes())).ToEqual(len(g.Nodes()) + 5)
+}
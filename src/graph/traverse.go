@@ -0,0 +1,144 @@
+package graph
+
+// Traversal restricts which edges may be followed when walking the graph
+type Traversal struct {
+	EdgeNames []string // only follow edges with these names (all if empty)
+	Direction string   // In, Out or blank to follow edges both ways
+	MaxDepth  int      // maximum number of hops from the root 0=nolimit
+}
+
+// Step is a node reached during a traversal
+type Step struct {
+	Node   *Node
+	Edge   *Edge // edge followed to reach Node, nil for the root
+	Depth  int
+	Parent *Step // step that Edge was followed from, nil for the root
+}
+
+// Direction reports which way Edge was followed to reach Node
+func (s *Step) Direction() string {
+	if s.Edge == nil {
+		return ""
+	}
+	if s.Edge.e.to == s.Node.ID() && s.Edge.e.from == s.Parent.Node.ID() {
+		return "Out"
+	}
+	return "In"
+}
+
+// Path returns the steps leading from the root to s
+func (s *Step) Path() []*Step {
+	n := 0
+	for p := s; p != nil; p = p.Parent {
+		n++
+	}
+	path := make([]*Step, n)
+	for p := s; p != nil; p = p.Parent {
+		n--
+		path[n] = p
+	}
+	return path
+}
+
+func (t Traversal) next(s *Step) []*Step {
+	if t.MaxDepth > 0 && s.Depth >= t.MaxDepth {
+		return nil
+	}
+	id := s.Node.ID()
+	steps := []*Step{}
+	for _, e := range s.Node.Edges(t.EdgeNames, t.Direction) {
+		n := s.Node.g.Get(e.e.other(id, t.Direction))
+		if n == nil {
+			continue
+		}
+		steps = append(steps, &Step{
+			Node:   n,
+			Edge:   e,
+			Depth:  s.Depth + 1,
+			Parent: s,
+		})
+	}
+	return steps
+}
+
+// BFS visits each node reachable from id once in breadth first order,
+// starting with the root itself. The walk stops if visit returns false.
+func (g *Graph) BFS(id string, t Traversal, visit func(s *Step) bool) {
+	root := g.Get(id)
+	if root == nil {
+		return
+	}
+	seen := map[string]bool{id: true}
+	queue := []*Step{{Node: root}}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		if !visit(s) {
+			return
+		}
+		for _, next := range t.next(s) {
+			if seen[next.Node.ID()] {
+				continue
+			}
+			seen[next.Node.ID()] = true
+			queue = append(queue, next)
+		}
+	}
+}
+
+// DFS visits each node reachable from id once in depth first order,
+// starting with the root itself. The walk stops if visit returns false.
+func (g *Graph) DFS(id string, t Traversal, visit func(s *Step) bool) {
+	root := g.Get(id)
+	if root == nil {
+		return
+	}
+	seen := map[string]bool{}
+	var walk func(s *Step) bool
+	walk = func(s *Step) bool {
+		seen[s.Node.ID()] = true
+		if !visit(s) {
+			return false
+		}
+		for _, next := range t.next(s) {
+			if seen[next.Node.ID()] {
+				continue
+			}
+			if !walk(next) {
+				return false
+			}
+		}
+		return true
+	}
+	walk(&Step{Node: root})
+}
+
+// ShortestPath returns the steps of the shortest route between two
+// nodes, including both ends, or nil if to cannot be reached
+func (g *Graph) ShortestPath(from string, to string, t Traversal) []*Step {
+	var found *Step
+	g.BFS(from, t, func(s *Step) bool {
+		if s.Node.ID() == to {
+			found = s
+			return false
+		}
+		return true
+	})
+	if found == nil {
+		return nil
+	}
+	return found.Path()
+}
+
+// Reachable returns every node that can be reached from id, excluding
+// the root, in breadth first order
+func (g *Graph) Reachable(id string, t Traversal) Nodes {
+	ns := Nodes{}
+	g.BFS(id, t, func(s *Step) bool {
+		if s.Depth > 0 {
+			ns = append(ns, s.Node)
+		}
+		return true
+	})
+	return ns
+}
@@ -0,0 +1,84 @@
+package graph
+
+import (
+	"testing"
+	"testutil"
+)
+
+// home -> about -> team -> bob, with blog linked from both home and team
+func traversalGraph() *Graph {
+	g := New()
+	for _, id := range []string{"home", "about", "team", "bob", "blog"} {
+		g = g.Set(NodeConfig{
+			ID:   id,
+			Type: testType,
+		})
+	}
+	g = g.Connect(EdgeConfig{From: "home", To: "about", Name: "child"})
+	g = g.Connect(EdgeConfig{From: "about", To: "team", Name: "child"})
+	g = g.Connect(EdgeConfig{From: "team", To: "bob", Name: "member"})
+	g = g.Connect(EdgeConfig{From: "home", To: "blog", Name: "child"})
+	g = g.Connect(EdgeConfig{From: "team", To: "blog", Name: "link"})
+	return g
+}
+
+func stepIDs(steps []*Step) []string {
+	ids := []string{}
+	for _, s := range steps {
+		ids = append(ids, s.Node.ID())
+	}
+	return ids
+}
+
+func TestBFS(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := traversalGraph()
+	steps := []*Step{}
+	g.BFS("home", Traversal{Direction: "Out"}, func(s *Step) bool {
+		steps = append(steps, s)
+		return true
+	})
+	expect(stepIDs(steps)).ToEqual([]string{"home", "about", "blog", "team", "bob"})
+	expect(steps[3].Depth).ToEqual(2)
+	expect(steps[3].Direction()).ToEqual("Out")
+}
+
+func TestDFS(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := traversalGraph()
+	steps := []*Step{}
+	g.DFS("home", Traversal{Direction: "Out"}, func(s *Step) bool {
+		steps = append(steps, s)
+		return s.Node.ID() != "bob"
+	})
+	expect(stepIDs(steps)).ToEqual([]string{"home", "about", "team", "bob"})
+}
+
+func TestTraversalFilters(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := traversalGraph()
+	ids := func(ns Nodes) []string {
+		out := []string{}
+		for _, n := range ns {
+			out = append(out, n.ID())
+		}
+		return out
+	}
+	expect(ids(g.Reachable("home", Traversal{EdgeNames: []string{"child"}}))).ToEqual([]string{"about", "blog", "team"})
+	expect(ids(g.Reachable("home", Traversal{MaxDepth: 1}))).ToEqual([]string{"about", "blog"})
+	expect(ids(g.Reachable("bob", Traversal{Direction: "Out"}))).ToEqual([]string{})
+	expect(ids(g.Reachable("bob", Traversal{Direction: "In", MaxDepth: 2}))).ToEqual([]string{"team", "about"})
+}
+
+func TestShortestPath(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := traversalGraph()
+	path := g.ShortestPath("bob", "home", Traversal{})
+	expect(stepIDs(path)).ToEqual([]string{"bob", "team", "about", "home"})
+	expect(path[1].Direction()).ToEqual("In")
+	expect(path[1].Edge.Name()).ToEqual("member")
+	path = g.ShortestPath("blog", "bob", Traversal{})
+	expect(stepIDs(path)).ToEqual([]string{"blog", "team", "bob"})
+	expect(g.ShortestPath("bob", "home", Traversal{Direction: "Out"})).ToBeNil()
+	expect(g.ShortestPath("bob", "home", Traversal{MaxDepth: 2})).ToBeNil()
+}
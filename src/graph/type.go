@@ -1,17 +1,325 @@
 package graph
 
+import (
+	"fmt"
+	"strings"
+)
+
+// Type describes the fields held by a set of nodes. A type may extend
+// one or more parent types and inherits all of their fields. Abstract
+// types only exist to be extended and cannot hold nodes themselves.
 type Type struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Fields      Fields `json:"fields"`
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Extends     []string `json:"extends"` // ids of parent types
+	Abstract    bool     `json:"abstract"`
+	Fields      Fields   `json:"fields"`
+
+	inherited Fields // fields from parent types, resolved by DefineType
 }
 
-func (t *Type) Field(name string) *Field {
+// AllFields returns the inherited fields followed by the fields
+// declared by t itself
+func (t *Type) AllFields() Fields {
+	if len(t.inherited) == 0 {
+		return t.Fields
+	}
+	fs := Fields{}
+	for _, f := range t.inherited {
+		if t.declares(f.Name) {
+			continue
+		}
+		fs = append(fs, f)
+	}
+	return append(fs, t.Fields...)
+}
+
+func (t *Type) declares(name string) bool {
 	for _, f := range t.Fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Type) Field(name string) *Field {
+	for _, f := range t.AllFields() {
 		if f.Name == name {
 			return f
 		}
 	}
 	return nil
 }
+
+// ValidateType checks that t can be defined in g. Every parent must
+// already exist, must not extend t and any field redeclared from a
+// parent must keep the same field type. Computed fields must not refer
+// to themselves.
+func (g *Graph) ValidateType(t *Type) error {
+	if t.ID == "" {
+		return fmt.Errorf("type id is required")
+	}
+	for _, f := range t.Fields {
+		if f.Name == "" {
+			return fmt.Errorf("cannot create field with blank name")
+		}
+		if f.Type == "Computed" {
+			if err := validateComputed(f); err != nil {
+				return fmt.Errorf("field '%s': %s", f.Name, err)
+			}
+		}
+		if f.Type == "Enum" {
+			if err := validateEnum(f); err != nil {
+				return fmt.Errorf("field '%s': %s", f.Name, err)
+			}
+		}
+		if f.Type == "DataTable" {
+			if err := validateDataColumns(f); err != nil {
+				return fmt.Errorf("field '%s': %s", f.Name, err)
+			}
+		}
+		if f.Default != "" {
+			if err := validateDefault(f); err != nil {
+				return fmt.Errorf("field '%s': %s", f.Name, err)
+			}
+		}
+	}
+	for _, id := range t.Extends {
+		p := g.TypeByID(id)
+		if p == nil {
+			return fmt.Errorf("cannot extend type '%s': not defined", id)
+		}
+		if p.ID == t.ID || g.IsA(p, t) {
+			return fmt.Errorf("cannot extend type '%s': it already extends '%s'", p.ID, t.ID)
+		}
+		for _, pf := range p.AllFields() {
+			f := t.Field(pf.Name)
+			if f != nil && f.Type != pf.Type {
+				return fmt.Errorf("field '%s' conflicts with field inherited from '%s'", f.Name, p.ID)
+			}
+		}
+	}
+	return g.validateComputedRefs(t)
+}
+
+func validateComputed(f *Field) error {
+	switch f.ComputedType {
+	case "", "Text", "Int", "Float", "Boolean":
+	default:
+		return fmt.Errorf("'%s' is not a valid computedType", f.ComputedType)
+	}
+	if f.Required || f.Unique || f.Indexed {
+		return fmt.Errorf("computed fields cannot be required, unique or indexed")
+	}
+	if f.Expression == "" {
+		return fmt.Errorf("expression is required")
+	}
+	_, err := fieldExpression(f)
+	return err
+}
+
+// validateComputedRefs checks that no Computed field of t refers back to
+// itself through other fields of t, including those inherited from its
+// parents. The parents must already exist.
+func (g *Graph) validateComputedRefs(t *Type) error {
+	fields := map[string]*Field{}
+	computed := Fields{}
+	add := func(f *Field) {
+		if _, ok := fields[f.Name]; ok {
+			return
+		}
+		fields[f.Name] = f
+		if f.Type == "Computed" {
+			computed = append(computed, f)
+		}
+	}
+	for _, f := range t.Fields {
+		add(f)
+	}
+	for _, id := range t.Extends {
+		for _, f := range g.TypeByID(id).AllFields() {
+			add(f)
+		}
+	}
+	done := map[string]bool{}
+	var visit func(f *Field, path []string) error
+	visit = func(f *Field, path []string) error {
+		if done[f.Name] {
+			return nil
+		}
+		for _, name := range path[:len(path)-1] {
+			if name == f.Name {
+				return fmt.Errorf("field '%s': expression refers to itself through %s", f.Name, strings.Join(path, " -> "))
+			}
+		}
+		x, err := fieldExpression(f)
+		if err != nil {
+			return fmt.Errorf("field '%s': %s", f.Name, err)
+		}
+		for _, name := range x.names() {
+			if r := fields[name]; r != nil && r.Type == "Computed" {
+				if err := visit(r, append(path, name)); err != nil {
+					return err
+				}
+			}
+		}
+		done[f.Name] = true
+		return nil
+	}
+	for _, f := range computed {
+		if err := visit(f, []string{f.Name}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateDefault(f *Field) error {
+	switch {
+	case f.Type == "Edge" || f.Type == "Computed":
+		return fmt.Errorf("%s fields cannot have a default", f.Type)
+	case f.Unique:
+		return fmt.Errorf("unique fields cannot have a default")
+	}
+	v, err := f.Coerce(f.Default, "UTF8")
+	if err != nil {
+		return err
+	}
+	if f.Type == "Enum" {
+		_, err = f.EnumSelection(v)
+	}
+	return err
+}
+
+func validateEnum(f *Field) error {
+	if len(f.EnumValues) == 0 {
+		return fmt.Errorf("enumValues are required")
+	}
+	seen := map[string]bool{}
+	for _, v := range f.EnumValues {
+		if v == "" || seen[v] {
+			return fmt.Errorf("enumValues must be unique and not blank")
+		}
+		seen[v] = true
+	}
+	return nil
+}
+
+// Parents returns the types that t directly extends
+func (g *Graph) Parents(t *Type) []*Type {
+	ts := []*Type{}
+	for _, id := range t.Extends {
+		if p := g.TypeByID(id); p != nil {
+			ts = append(ts, p)
+		}
+	}
+	return ts
+}
+
+// Ancestors returns every type that t extends directly or indirectly,
+// nearest first
+func (g *Graph) Ancestors(t *Type) []*Type {
+	seen := map[string]bool{t.ID: true}
+	ts := []*Type{}
+	queue := g.Parents(t)
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if seen[p.ID] {
+			continue
+		}
+		seen[p.ID] = true
+		ts = append(ts, p)
+		queue = append(queue, g.Parents(p)...)
+	}
+	return ts
+}
+
+// Subtypes returns every type that extends t directly or indirectly
+func (g *Graph) Subtypes(t *Type) []*Type {
+	ts := []*Type{}
+	for _, other := range g.types {
+		if other.ID != t.ID && g.IsA(other, t) {
+			ts = append(ts, other)
+		}
+	}
+	return ts
+}
+
+// IsA reports whether t is the same type as parent or extends it
+func (g *Graph) IsA(t *Type, parent *Type) bool {
+	if t == nil || parent == nil {
+		return false
+	}
+	if t.ID == parent.ID {
+		return true
+	}
+	for _, a := range g.Ancestors(t) {
+		if a.ID == parent.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveTypes recalculates the inherited fields of every type,
+// copying any type whose inherited fields have changed
+func (g *Graph) resolveTypes() {
+	resolved := map[string]*Type{}
+	var resolve func(t *Type, depth int) *Type
+	resolve = func(t *Type, depth int) *Type {
+		if r, ok := resolved[t.ID]; ok {
+			return r
+		}
+		inherited := Fields{}
+		if depth <= len(g.types) { // guard against cycles
+			for _, id := range t.Extends {
+				p := g.TypeByID(id)
+				if p == nil {
+					continue
+				}
+				for _, f := range resolve(p, depth+1).AllFields() {
+					if !inherited.has(f.Name) {
+						inherited = append(inherited, f)
+					}
+				}
+			}
+		}
+		r := t
+		if !fieldsEqual(t.inherited, inherited) {
+			t2 := *t
+			t2.inherited = inherited
+			r = &t2
+		}
+		resolved[t.ID] = r
+		return r
+	}
+	types := make([]*Type, len(g.types))
+	for i, t := range g.types {
+		types[i] = resolve(t, 0)
+	}
+	g.types = types
+}
+
+func (fs Fields) has(name string) bool {
+	for _, f := range fs {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldsEqual(a, b Fields) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package graph
+
+import (
+	"testing"
+
+	"testutil"
+)
+
+func fieldNames(fs Fields) []string {
+	names := []string{}
+	for _, f := range fs {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func inheritanceGraph() *Graph {
+	return New().
+		DefineType(Type{
+			ID:       "page",
+			Name:     "Page",
+			Abstract: true,
+			Fields: Fields{
+				{Name: "title", Type: "Text", Required: true},
+				{Name: "slug", Type: "Text"},
+			},
+		}).
+		DefineType(Type{
+			ID:      "post",
+			Name:    "BlogPost",
+			Extends: []string{"page"},
+			Fields: Fields{
+				{Name: "body", Type: "RichText"},
+			},
+		}).
+		DefineType(Type{
+			ID:      "landing",
+			Name:    "LandingPage",
+			Extends: []string{"page"},
+		})
+}
+
+func TestTypeInheritsFields(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := inheritanceGraph()
+	post := g.TypeByID("post")
+	expect(fieldNames(post.AllFields())).ToEqual([]string{"title", "slug", "body"})
+	expect(post.Field("title")).ToNotBeNil()
+	expect(g.IsA(post, g.TypeByID("page"))).ToEqual(true)
+	expect(g.IsA(g.TypeByID("page"), post)).ToEqual(false)
+	expect(len(g.Subtypes(g.TypeByID("page")))).ToEqual(2)
+	// redefining the parent updates every subtype
+	g = g.DefineType(Type{
+		ID:       "page",
+		Name:     "Page",
+		Abstract: true,
+		Fields:   Fields{{Name: "title", Type: "Text"}},
+	})
+	expect(fieldNames(g.TypeByID("post").AllFields())).ToEqual([]string{"title", "body"})
+	expect(fieldNames(g.TypeByID("landing").AllFields())).ToEqual([]string{"title"})
+}
+
+func TestTypeInheritanceValidation(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := inheritanceGraph()
+	// cannot extend something that does not exist or is a subtype
+	expect(g.ValidateType(&Type{ID: "x", Extends: []string{"nope"}})).ToNotBeNil()
+	expect(g.ValidateType(&Type{ID: "page", Extends: []string{"post"}})).ToNotBeNil()
+	expect(g.ValidateType(&Type{
+		ID:      "x",
+		Extends: []string{"page"},
+		Fields:  Fields{{Name: "title", Type: "Int"}},
+	})).ToNotBeNil()
+	// inherited rules apply to subtypes and filters match subtypes
+	g = g.Set(NodeConfig{ID: "p1", Type: g.TypeByID("post")})
+	g = g.Set(NodeConfig{ID: "l1", Type: g.TypeByID("landing")})
+	expect(rules(g.Validiate())).ToEqual([]string{RuleRequired, RuleRequired})
+	expect(len(g.Nodes().FilterType(g.TypeByID("page")))).ToEqual(2)
+	expect(len(g.Nodes().FilterType(g.TypeByID("post")))).ToEqual(1)
+}
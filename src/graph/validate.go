@@ -0,0 +1,274 @@
+package graph
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Validation rules
+const (
+	RuleType          = "type"
+	RuleRequired      = "required"
+	RuleFormat        = "format"
+	RuleTextCharLimit = "textCharLimit"
+	RuleTextLineLimit = "textLineLimit"
+	RuleEdgeToType    = "edgeToType"
+	RuleEdgeLimit     = "edgeLimit"
+	RuleUnique        = "unique"
+	RuleComputed      = "computed"
+	RuleEnum          = "enum"
+)
+
+// Violation describes a single way in which a node breaks the rules
+// defined by its Type
+type Violation struct {
+	NodeID string `json:"nodeID"`
+	Field  string `json:"field"`
+	Rule   string `json:"rule"`
+	Reason string `json:"reason"`
+}
+
+func (v *Violation) String() string {
+	if v.Field == "" {
+		return fmt.Sprintf("node '%s': %s", v.NodeID, v.Reason)
+	}
+	return fmt.Sprintf("node '%s' field '%s': %s", v.NodeID, v.Field, v.Reason)
+}
+
+// ValidationError is returned when one or more nodes are invalid
+type ValidationError struct {
+	Violations []*Violation `json:"violations"`
+}
+
+func (err *ValidationError) Error() string {
+	msgs := []string{}
+	for _, v := range err.Violations {
+		msgs = append(msgs, v.String())
+	}
+	return fmt.Sprintf("validation failed: %s", strings.Join(msgs, "; "))
+}
+
+// Except returns an error containing only the violations that do not
+// match any of the given rules, or nil if there are none left
+func (err *ValidationError) Except(rules ...string) error {
+	vs := []*Violation{}
+	for _, v := range err.Violations {
+		if stringIn(v.Rule, rules) {
+			continue
+		}
+		vs = append(vs, v)
+	}
+	return newValidationError(vs)
+}
+
+func newValidationError(vs []*Violation) error {
+	if len(vs) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: vs}
+}
+
+// Validator checks a single node and returns any violations
+type Validator func(n *Node) []*Violation
+
+// DefaultValidator checks each node against the fields of its Type
+var DefaultValidator Validator = func(n *Node) []*Violation {
+	t := n.Type()
+	if t == nil {
+		return []*Violation{{
+			NodeID: n.ID(),
+			Rule:   RuleType,
+			Reason: fmt.Sprintf("type '%s' is not defined", n.n.typeID),
+		}}
+	}
+	vs := []*Violation{}
+	for _, f := range t.AllFields() {
+		if f.Type == "Edge" {
+			vs = append(vs, validateEdgeField(n, f)...)
+			continue
+		}
+		if f.Type == "Computed" {
+			if attr := n.Attr(f.Name); attr != nil && !attr.Empty() {
+				vs = append(vs, &Violation{
+					NodeID: n.ID(),
+					Field:  f.Name,
+					Rule:   RuleComputed,
+					Reason: "value is computed and cannot be set",
+				})
+			}
+			continue
+		}
+		if v := validateAttr(n, f); v != nil {
+			vs = append(vs, v)
+		}
+	}
+	return vs
+}
+
+func validateAttr(n *Node, f *Field) *Violation {
+	violation := func(rule string, reason string, args ...interface{}) *Violation {
+		return &Violation{
+			NodeID: n.ID(),
+			Field:  f.Name,
+			Rule:   rule,
+			Reason: fmt.Sprintf(reason, args...),
+		}
+	}
+	attr := n.Attr(f.Name)
+	if attr == nil || attr.Empty() {
+		// nodes without the attr read the default
+		if f.Required && f.Default == "" {
+			return violation(RuleRequired, "value is required")
+		}
+		return nil
+	}
+	if t := f.ValueType(attr.Enc); attr.Type() != t {
+		return violation(RuleFormat, "'%s' is not a valid %s", attr.String(), t)
+	}
+	if f.Temporal() {
+		if s, err := normalizeTime(f.Type, attr.Value); err != nil || s != attr.Value {
+			return violation(RuleFormat, "'%s' is not a valid %s", attr.String(), f.Type)
+		}
+	}
+	if f.Type == "GeoPoint" {
+		if _, err := ParseGeoPoint(attr.Value); err != nil {
+			return violation(RuleFormat, "%s", err)
+		}
+	}
+	if f.Type == "DataTable" {
+		if _, err := f.ParseDataTable(attr.Value); err != nil {
+			return violation(RuleFormat, "%s", err)
+		}
+	}
+	if f.Type == "Enum" {
+		if _, err := f.EnumSelection(attr.Value); err != nil {
+			return violation(RuleEnum, "%s", err)
+		}
+	}
+	if f.Unique {
+		for _, t := range n.g.uniqueScope(n.Type(), f.Name) {
+			ids, _ := n.g.Lookup(t, f.Name, attr.Value)
+			for _, id := range ids {
+				if id != n.ID() {
+					return violation(RuleUnique, "value '%s' is already used by node '%s'", attr.String(), id)
+				}
+			}
+		}
+	}
+	switch f.Type {
+	case "Text", "RichText":
+		s := attr.String()
+		if f.TextCharLimit > 0 && utf8.RuneCountInString(s) > f.TextCharLimit {
+			return violation(RuleTextCharLimit, "value exceeds limit of %d characters", f.TextCharLimit)
+		}
+		if f.TextLineLimit > 0 && strings.Count(s, "\n")+1 > f.TextLineLimit {
+			return violation(RuleTextLineLimit, "value exceeds limit of %d lines", f.TextLineLimit)
+		}
+	}
+	return nil
+}
+
+func validateEdgeField(n *Node, f *Field) []*Violation {
+	targets := n.fieldTargets(f)
+	vs := []*Violation{}
+	if f.Required && len(targets) == 0 {
+		vs = append(vs, &Violation{
+			NodeID: n.ID(),
+			Field:  f.Name,
+			Rule:   RuleRequired,
+			Reason: "at least one connection is required",
+		})
+	}
+	if max := f.MaxEdges(); max > 0 && len(targets) > max {
+		vs = append(vs, &Violation{
+			NodeID: n.ID(),
+			Field:  f.Name,
+			Rule:   RuleEdgeLimit,
+			Reason: fmt.Sprintf("has %d connections but at most %d are allowed", len(targets), max),
+		})
+	}
+	if f.EdgeToTypeID == "" {
+		return vs
+	}
+	toType := n.g.TypeByID(f.EdgeToTypeID)
+	for _, other := range targets {
+		target := n.g.Get(other)
+		if target == nil || n.g.IsA(target.Type(), toType) {
+			continue
+		}
+		vs = append(vs, &Violation{
+			NodeID: n.ID(),
+			Field:  f.Name,
+			Rule:   RuleEdgeToType,
+			Reason: fmt.Sprintf("connected node '%s' is not of type '%s'", other, f.EdgeToTypeID),
+		})
+	}
+	return vs
+}
+
+// Validiate checks every node in the graph using DefaultValidator
+func (g *Graph) Validiate() error {
+	ns := g.Nodes()
+	sort.Sort(ns)
+	vs := []*Violation{}
+	for _, n := range ns {
+		vs = append(vs, DefaultValidator(n)...)
+	}
+	return newValidationError(vs)
+}
+
+// ValidateChanges checks only the nodes that were added or modified
+// since base, along with the endpoints of any edges that were added or
+// removed. Existing nodes are not rechecked when a Type is redefined.
+func (g *Graph) ValidateChanges(base *Graph) error {
+	ids := map[string]bool{}
+	diffHamt(base.nodes, g.nodes, func(id string, _, n interface{}) {
+		if n != nil {
+			ids[id] = true
+		}
+	})
+	// redefined types may break the rules for nodes that did not change
+	redefined := map[string]bool{}
+	for _, t := range g.types {
+		if before := base.TypeByID(t.ID); before == nil || before == t || reflect.DeepEqual(before, t) {
+			continue
+		}
+		redefined[t.ID] = true
+		for _, st := range g.Subtypes(t) {
+			redefined[st.ID] = true
+		}
+	}
+	if len(redefined) > 0 {
+		g.nodes.each(func(id string, v interface{}) bool {
+			if redefined[v.(*node).typeID] {
+				ids[id] = true
+			}
+			return true
+		})
+	}
+	diffHamt(base.edges, g.edges, func(_ string, a, b interface{}) {
+		for _, v := range []interface{}{a, b} {
+			if e, ok := v.(*edge); ok {
+				ids[e.from] = true
+				ids[e.to] = true
+			}
+		}
+	})
+	sorted := []string{}
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+	vs := []*Violation{}
+	for _, id := range sorted {
+		n := g.Get(id)
+		if n == nil {
+			continue
+		}
+		vs = append(vs, DefaultValidator(n)...)
+	}
+	return newValidationError(vs)
+}
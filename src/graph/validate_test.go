@@ -0,0 +1,216 @@
+package graph
+
+import (
+	"encoding/json"
+	"testing"
+	"testutil"
+)
+
+var personType = &Type{
+	ID:   "person",
+	Name: "Person",
+	Fields: Fields{
+		&Field{Name: "name", Type: "Text", Required: true, TextCharLimit: 5},
+		&Field{Name: "bio", Type: "Text", TextLineLimit: 2},
+		&Field{Name: "age", Type: "Int"},
+		&Field{Name: "score", Type: "Float"},
+		&Field{Name: "active", Type: "Boolean"},
+		&Field{Name: "employer", Type: "Edge", EdgeName: "employer", EdgeDirection: "Out", EdgeToTypeID: "company"},
+	},
+}
+
+var companyType = &Type{
+	ID:   "company",
+	Name: "Company",
+}
+
+func validationGraph() *Graph {
+	g := New()
+	g = g.DefineType(*personType)
+	g = g.DefineType(*companyType)
+	g = g.Set(NodeConfig{
+		ID:   "alice",
+		Type: personType,
+		Attrs: []*Attr{
+			&Attr{Name: "name", Value: "alice"},
+		},
+	})
+	g = g.Set(NodeConfig{
+		ID:   "acme",
+		Type: companyType,
+	})
+	return g
+}
+
+func rules(err error) []string {
+	rs := []string{}
+	if err == nil {
+		return rs
+	}
+	for _, v := range err.(*ValidationError).Violations {
+		rs = append(rs, v.Rule)
+	}
+	return rs
+}
+
+func TestValidGraph(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := validationGraph()
+	expect(g.Validiate()).ToEqual(nil)
+}
+
+func TestValidateAttrs(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := validationGraph()
+	g = g.Set(NodeConfig{
+		ID:   "bob",
+		Type: personType,
+		Attrs: []*Attr{
+			&Attr{Name: "name", Value: "bobbyjoe"},
+			&Attr{Name: "bio", Value: "1\n2\n3"},
+			&Attr{Name: "age", Value: "old"},
+			&Attr{Name: "score", Value: 1.5},
+			&Attr{Name: "active", Value: "yes"},
+		},
+	})
+	expect(rules(g.Validiate())).ToEqual([]string{
+		RuleTextCharLimit,
+		RuleTextLineLimit,
+		RuleFormat,
+		RuleFormat,
+	})
+	g = g.Set(NodeConfig{
+		ID:   "bob",
+		Type: personType,
+	})
+	expect(rules(g.Validiate())).ToEqual([]string{RuleRequired})
+}
+
+func TestValidateEdgeToType(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := validationGraph()
+	g = g.Connect(EdgeConfig{From: "alice", To: "acme", Name: "employer"})
+	expect(g.Validiate()).ToEqual(nil)
+	g = g.Set(NodeConfig{
+		ID:   "bob",
+		Type: personType,
+		Attrs: []*Attr{
+			&Attr{Name: "name", Value: "bob"},
+		},
+	})
+	g = g.Connect(EdgeConfig{From: "alice", To: "bob", Name: "employer"})
+	err := g.Validiate()
+	expect(rules(err)).ToEqual([]string{RuleEdgeToType})
+	expect(err.(*ValidationError).Violations[0].NodeID).ToEqual("alice")
+}
+
+func TestValidateChanges(t *testing.T) {
+	expect := testutil.Expect(t)
+	base := validationGraph()
+	// an invalid node that already exists in base
+	base = base.Set(NodeConfig{
+		ID:   "legacy",
+		Type: personType,
+	})
+	g := base.Set(NodeConfig{
+		ID:   "bob",
+		Type: personType,
+		Attrs: []*Attr{
+			&Attr{Name: "age", Value: "x"},
+		},
+	})
+	err := g.ValidateChanges(base)
+	expect(rules(err)).ToEqual([]string{RuleRequired, RuleFormat})
+	expect(rules(err.(*ValidationError).Except(RuleRequired))).ToEqual([]string{RuleFormat})
+	// connecting validates both ends
+	g = base.Set(NodeConfig{
+		ID:   "bob",
+		Type: personType,
+		Attrs: []*Attr{
+			&Attr{Name: "name", Value: "bob"},
+		},
+	})
+	base = g
+	g = g.Connect(EdgeConfig{From: "alice", To: "bob", Name: "employer"})
+	expect(rules(g.ValidateChanges(base))).ToEqual([]string{RuleEdgeToType})
+}
+
+func TestValidateEdgeLimit(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := validationGraph()
+	g = g.DefineType(Type{
+		ID:   "team",
+		Name: "Team",
+		Fields: Fields{
+			{Name: "lead", Type: "Edge", EdgeName: "lead", EdgeDirection: "Out", EdgeCardinality: CardinalityOne},
+			{Name: "members", Type: "Edge", EdgeName: "member", EdgeDirection: "Out", EdgeLimit: 2},
+		},
+	})
+	g = g.Set(NodeConfig{ID: "t", Type: g.TypeByID("team")})
+	g = g.Connect(EdgeConfig{From: "t", To: "alice", Name: "lead"})
+	g = g.Connect(EdgeConfig{From: "t", To: "acme", Name: "member"})
+	g = g.Connect(EdgeConfig{From: "t", To: "alice", Name: "member"})
+	expect(g.Validiate()).ToEqual(nil)
+	g = g.Connect(EdgeConfig{From: "t", To: "acme", Name: "lead"})
+	g = g.Connect(EdgeConfig{From: "t", To: "t", Name: "member"})
+	expect(rules(g.Validiate())).ToEqual([]string{RuleEdgeLimit, RuleEdgeLimit})
+}
+
+func TestFieldDefault(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := validationGraph()
+	withDefaults := *personType
+	withDefaults.Fields = append(Fields{}, personType.Fields...)
+	withDefaults.Fields[0] = &Field{Name: "name", Type: "Text", Required: true, Default: "anon"}
+	withDefaults.Fields = append(withDefaults.Fields, &Field{Name: "level", Type: "Int", Default: "3"})
+	expect(g.ValidateType(&withDefaults)).ToEqual(nil)
+	g = g.DefineType(withDefaults)
+	g = g.Set(NodeConfig{ID: "bob", Type: g.TypeByID("person")})
+	// a default satisfies required
+	expect(g.Validiate()).ToEqual(nil)
+	// nodes without the attr read the default
+	person := g.TypeByID("person")
+	expect(g.Get("alice").Value(person.Field("level"))).ToEqual(int64(3))
+	expect(g.Get("alice").Value(person.Field("name"))).ToEqual("alice")
+	expect(g.Get("bob").Value(person.Field("name"))).ToEqual("anon")
+	// defaults must suit the field
+	for _, f := range []*Field{
+		{Name: "x", Type: "Int", Default: "three"},
+		{Name: "x", Type: "Text", Unique: true, Default: "a"},
+		{Name: "x", Type: "Edge", Default: "a"},
+	} {
+		expect(g.ValidateType(&Type{ID: "t", Fields: Fields{f}})).ToNotBeNil()
+	}
+	_, _, err := g.ChangeFieldType("person", "name", FieldConversion{Type: "Int"})
+	expect(err).ToNotBeNil()
+}
+
+func TestValidateEnum(t *testing.T) {
+	expect := testutil.Expect(t)
+	g := New()
+	g = g.DefineType(Type{ID: "shirt", Name: "Shirt", Fields: Fields{
+		{Name: "size", Type: "Enum", EnumValues: []string{"S", "M", "L"}, Default: "M"},
+		{Name: "colours", Type: "Enum", EnumValues: []string{"red", "blue"}, EnumMulti: true},
+	}})
+	shirt := g.TypeByID("shirt")
+	g = g.Set(NodeConfig{ID: "a", Type: shirt, Attrs: []*Attr{
+		{Name: "size", Value: "L"},
+		{Name: "colours", Value: json.RawMessage(`["red","blue"]`), Enc: "JSON"},
+	}})
+	expect(g.Validiate()).ToEqual(nil)
+	sel, err := shirt.Field("colours").EnumSelection(g.Get("a").Attr("colours").Value)
+	expect(err).ToEqual(nil)
+	expect(sel).ToEqual([]string{"red", "blue"})
+	g = g.Set(NodeConfig{ID: "a", Type: shirt, Attrs: []*Attr{
+		{Name: "size", Value: "XL"},
+		{Name: "colours", Value: json.RawMessage(`["red","red"]`), Enc: "JSON"},
+	}})
+	expect(rules(g.Validiate())).ToEqual([]string{RuleEnum, RuleEnum})
+	for _, f := range []*Field{
+		{Name: "x", Type: "Enum"},
+		{Name: "x", Type: "Enum", EnumValues: []string{"a", "a"}},
+		{Name: "x", Type: "Enum", EnumValues: []string{"a"}, Default: "b"},
+	} {
+		expect(g.ValidateType(&Type{ID: "t", Fields: Fields{f}})).ToNotBeNil()
+	}
+}
@@ -1,3 +1,144 @@
 package graph
 
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ValueType is the type of value stored in an Attr. Attr values are
+// held as string, int64, float64, bool or json.RawMessage.
 type ValueType string
+
+const (
+	StringValue ValueType = "String"
+	IntValue    ValueType = "Int"
+	FloatValue  ValueType = "Float"
+	BoolValue   ValueType = "Boolean"
+	JSONValue   ValueType = "JSON"
+)
+
+// TypeOf returns the ValueType of a stored value
+func TypeOf(v interface{}) ValueType {
+	switch v.(type) {
+	case int64:
+		return IntValue
+	case float64:
+		return FloatValue
+	case bool:
+		return BoolValue
+	case json.RawMessage:
+		return JSONValue
+	default:
+		return StringValue
+	}
+}
+
+// FormatValue returns the string form of a stored value
+func FormatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case json.RawMessage:
+		return string(v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// ValuesEqual reports whether two stored values are the same
+func ValuesEqual(a, b interface{}) bool {
+	if TypeOf(a) != TypeOf(b) {
+		return false
+	}
+	return FormatValue(a) == FormatValue(b)
+}
+
+// Coerce converts v into a value of type t, parsing strings where
+// needed. Empty strings are left as they are to represent no value.
+func Coerce(v interface{}, t ValueType) (interface{}, error) {
+	if s, ok := v.(string); ok && s == "" {
+		return s, nil
+	}
+	fail := func() (interface{}, error) {
+		return nil, fmt.Errorf("'%s' is not a valid %s", FormatValue(v), t)
+	}
+	switch t {
+	case IntValue:
+		switch v := v.(type) {
+		case int64:
+			return v, nil
+		case int:
+			return int64(v), nil
+		case float64:
+			if v != math.Trunc(v) {
+				return fail()
+			}
+			return int64(v), nil
+		case string:
+			i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err != nil {
+				return fail()
+			}
+			return i, nil
+		}
+	case FloatValue:
+		switch v := v.(type) {
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		case int:
+			return float64(v), nil
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return fail()
+			}
+			return f, nil
+		}
+	case BoolValue:
+		switch v := v.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return fail()
+			}
+			return b, nil
+		}
+	case JSONValue:
+		switch v := v.(type) {
+		case json.RawMessage:
+			if !json.Valid(v) {
+				return fail()
+			}
+			return v, nil
+		case string:
+			if !json.Valid([]byte(v)) {
+				return fail()
+			}
+			return json.RawMessage(v), nil
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return fail()
+			}
+			return json.RawMessage(b), nil
+		}
+	case StringValue:
+		return FormatValue(v), nil
+	}
+	return fail()
+}
@@ -0,0 +1,50 @@
+package graph
+
+import (
+	"encoding/json"
+	"testing"
+
+	"testutil"
+)
+
+func TestCoerce(t *testing.T) {
+	expect := testutil.Expect(t)
+	coerce := func(v interface{}, vt ValueType) interface{} {
+		out, err := Coerce(v, vt)
+		if err != nil {
+			return err.Error()
+		}
+		return out
+	}
+	expect(coerce("42", IntValue)).ToEqual(int64(42))
+	expect(coerce(float64(42), IntValue)).ToEqual(int64(42))
+	expect(coerce(4.2, IntValue)).ToEqual("'4.2' is not a valid Int")
+	expect(coerce("1.5", FloatValue)).ToEqual(1.5)
+	expect(coerce(int64(2), FloatValue)).ToEqual(float64(2))
+	expect(coerce("true", BoolValue)).ToEqual(true)
+	expect(coerce("yes", BoolValue)).ToEqual("'yes' is not a valid Boolean")
+	expect(coerce(`{"a":1}`, JSONValue)).ToEqual(json.RawMessage(`{"a":1}`))
+	expect(coerce(`{"a":`, JSONValue)).ToEqual(`'{"a":' is not a valid JSON`)
+	expect(coerce(int64(7), StringValue)).ToEqual("7")
+	// empty strings mean no value whatever the type
+	expect(coerce("", IntValue)).ToEqual("")
+}
+
+func TestTypedAttrs(t *testing.T) {
+	expect := testutil.Expect(t)
+	attr := &Attr{Name: "age", Value: int64(30)}
+	expect(attr.Type()).ToEqual(IntValue)
+	expect(attr.String()).ToEqual("30")
+	expect((&Attr{Value: 0.5}).String()).ToEqual("0.5")
+	expect((&Attr{Value: false}).String()).ToEqual("false")
+	expect((&Attr{Value: ""}).Empty()).ToEqual(true)
+	expect((&Attr{Value: false}).Empty()).ToEqual(false)
+	expect(ValuesEqual(int64(1), int64(1))).ToEqual(true)
+	expect(ValuesEqual(int64(1), "1")).ToEqual(false)
+	g := New().DefineType(Type{ID: "t", Fields: []*Field{{Name: "age", Type: "Int"}}})
+	g = g.Set(NodeConfig{ID: "a", Type: g.TypeByID("t"), Attrs: []*Attr{attr}})
+	g2 := g.Set(NodeConfig{ID: "a", Attrs: []*Attr{{Name: "age", Value: int64(30)}}})
+	expect(Diff(g, g2).Empty()).ToEqual(true)
+	g2 = g.Set(NodeConfig{ID: "a", Attrs: []*Attr{{Name: "age", Value: "30"}}})
+	expect(len(Diff(g, g2).Nodes)).ToEqual(1)
+}
@@ -36,9 +36,13 @@ func (c *Client) Send(msg *WireMsg) error {
 }
 
 func (c *Client) OnConflict(conflict *db.Conflict) {
+	msg := "Some of your unpublished changes were lost due to conflicts caused by another user"
+	if conflict.Merge != nil {
+		msg = "Your unpublished changes overwrote a change made by another user"
+	}
 	c.Send(&WireMsg{
 		Type:  "error",
-		Error: "Some of your unpublished changes were lost due to conflicts caused by another user",
+		Error: fmt.Sprintf("%s: %s", msg, conflict.Error()),
 	})
 }
 
@@ -71,10 +71,10 @@ func fetchImage(c echo.Context) error {
 	if attr == nil {
 		return fmt.Errorf("no attr")
 	}
-	if attr.Value == "" {
+	if attr.Empty() {
 		return fmt.Errorf("no data")
 	}
-	r, err := db.NewImageDataReader(attr.Value)
+	r, err := db.NewImageDataReader(attr.String())
 	if err != nil {
 		return err
 	}
@@ -346,6 +346,63 @@ export default class Connection {
 		});
 	}
 
+	// example moveEdge({from:"gallery", name:"image", to:"img1", position:0})
+	moveEdge(args, returning){
+		if( !returning ){
+			returning = `
+				from {id}
+				to {id}
+				name
+			`;
+		}
+		return this.mutation({
+			input: {
+				to: 'String!',
+				from: 'String!',
+				name: 'String!',
+				position: 'Int!',
+			},
+			query: `
+				edges:moveEdge(${this.toPlaceholders(args)}) {
+					${returning}
+				}
+			`,
+			params: args
+		})
+		.then((data) => {
+			this.markDirty();
+			return data.edges;
+		});
+	}
+
+	// example reorderEdges({from:"gallery", name:"image", to:["img2","img1"]})
+	reorderEdges(args, returning){
+		if( !returning ){
+			returning = `
+				from {id}
+				to {id}
+				name
+			`;
+		}
+		return this.mutation({
+			input: {
+				to: '[String]!',
+				from: 'String!',
+				name: 'String!',
+			},
+			query: `
+				edges:reorderEdges(${this.toPlaceholders(args)}) {
+					${returning}
+				}
+			`,
+			params: args
+		})
+		.then((data) => {
+			this.markDirty();
+			return data.edges;
+		});
+	}
+
 	commit(){
 		return this.send({
 			type: COMMIT,
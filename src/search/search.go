@@ -0,0 +1,284 @@
+// Package search is a small in-memory full-text index. Documents are
+// made of named text fields which are tokenised, lower cased and
+// stemmed. Queries are ranked with BM25 and each result carries a
+// snippet of the best matching field with the matched words
+// highlighted.
+package search
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// BM25 ranking parameters
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// snippetWords is the number of words either side of the first match
+// included in a snippet
+const snippetWords = 8
+
+// Field is a named piece of text belonging to a document
+type Field struct {
+	Name string
+	Text string
+}
+
+// Token is a stemmed term and the byte offsets of the word it was made
+// from in the original text
+type Token struct {
+	Term  string
+	Start int
+	End   int
+}
+
+// Result is a document that matched a query
+type Result struct {
+	ID      string
+	Score   float64
+	Field   string // name of the field the snippet was taken from
+	Snippet string // html escaped text with matches wrapped in <em>
+}
+
+type doc struct {
+	fields []Field
+	terms  map[string]int // term => frequency
+	length int
+}
+
+// Index is an inverted index of documents by id. It is not safe for
+// concurrent use.
+type Index struct {
+	docs     map[string]*doc
+	postings map[string]map[string]int // term => doc id => frequency
+	length   int                       // total length of all docs
+}
+
+func NewIndex() *Index {
+	return &Index{
+		docs:     map[string]*doc{},
+		postings: map[string]map[string]int{},
+	}
+}
+
+// Len returns the number of documents in the index
+func (ix *Index) Len() int {
+	return len(ix.docs)
+}
+
+// Put adds the document with the given id, replacing any existing
+// document with the same id
+func (ix *Index) Put(id string, fields []Field) {
+	ix.Remove(id)
+	d := &doc{
+		fields: fields,
+		terms:  map[string]int{},
+	}
+	for _, f := range fields {
+		for _, tok := range Tokenize(f.Text) {
+			d.terms[tok.Term]++
+			d.length++
+		}
+	}
+	if d.length == 0 {
+		return
+	}
+	for term, n := range d.terms {
+		ps, ok := ix.postings[term]
+		if !ok {
+			ps = map[string]int{}
+			ix.postings[term] = ps
+		}
+		ps[id] = n
+	}
+	ix.docs[id] = d
+	ix.length += d.length
+}
+
+// Remove deletes the document with the given id if it exists
+func (ix *Index) Remove(id string) {
+	d, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+	for term := range d.terms {
+		delete(ix.postings[term], id)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+	ix.length -= d.length
+	delete(ix.docs, id)
+}
+
+// Search returns up to limit documents containing any of the words in
+// query ordered by score. Documents for which keep returns false are
+// skipped, keep may be nil. A limit of 0 or less returns every match.
+func (ix *Index) Search(query string, limit int, keep func(id string) bool) []*Result {
+	terms := []string{}
+	seen := map[string]bool{}
+	for _, tok := range Tokenize(query) {
+		if !seen[tok.Term] {
+			seen[tok.Term] = true
+			terms = append(terms, tok.Term)
+		}
+	}
+	if len(terms) == 0 || len(ix.docs) == 0 {
+		return []*Result{}
+	}
+	n := float64(len(ix.docs))
+	avg := float64(ix.length) / n
+	scores := map[string]float64{}
+	for _, term := range terms {
+		ps := ix.postings[term]
+		df := float64(len(ps))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id, tf := range ps {
+			if keep != nil && !keep(id) {
+				continue
+			}
+			norm := k1 * (1 - b + b*float64(ix.docs[id].length)/avg)
+			scores[id] += idf * float64(tf) * (k1 + 1) / (float64(tf) + norm)
+		}
+	}
+	results := []*Result{}
+	for id, score := range scores {
+		results = append(results, &Result{ID: id, Score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	for _, r := range results {
+		r.Field, r.Snippet = ix.docs[r.ID].snippet(seen)
+	}
+	return results
+}
+
+// snippet picks the field with the most matching words and returns a
+// window of text around the first match
+func (d *doc) snippet(terms map[string]bool) (string, string) {
+	best, bestToks, bestHits := -1, []Token(nil), 0
+	for i, f := range d.fields {
+		toks := Tokenize(f.Text)
+		hits := 0
+		for _, tok := range toks {
+			if terms[tok.Term] {
+				hits++
+			}
+		}
+		if hits > bestHits {
+			best, bestToks, bestHits = i, toks, hits
+		}
+	}
+	if best < 0 {
+		return "", ""
+	}
+	text := d.fields[best].Text
+	first := 0
+	for i, tok := range bestToks {
+		if terms[tok.Term] {
+			first = i
+			break
+		}
+	}
+	from := first - snippetWords
+	if from < 0 {
+		from = 0
+	}
+	to := first + snippetWords
+	if to >= len(bestToks) {
+		to = len(bestToks) - 1
+	}
+	var sb strings.Builder
+	if from > 0 {
+		sb.WriteString("…")
+	}
+	pos := bestToks[from].Start
+	for _, tok := range bestToks[from : to+1] {
+		sb.WriteString(html.EscapeString(text[pos:tok.Start]))
+		word := html.EscapeString(text[tok.Start:tok.End])
+		if terms[tok.Term] {
+			word = "<em>" + word + "</em>"
+		}
+		sb.WriteString(word)
+		pos = tok.End
+	}
+	if to < len(bestToks)-1 {
+		sb.WriteString("…")
+	}
+	return d.fields[best].Name, sb.String()
+}
+
+// Tokenize splits text into words made of letters and digits and
+// returns the stemmed, lower cased term for each
+func Tokenize(text string) []Token {
+	toks := []Token{}
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		toks = append(toks, Token{
+			Term:  Stem(strings.ToLower(text[start:end])),
+			Start: start,
+			End:   end,
+		})
+		start = -1
+	}
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(text))
+	return toks
+}
+
+// Stem removes common English suffixes so that different forms of a
+// word share a term. It is a light stemmer covering plurals, -ed,
+// -ing and -ly rather than a full Porter implementation.
+func Stem(word string) string {
+	if len(word) <= 3 {
+		return word
+	}
+	switch {
+	case strings.HasSuffix(word, "sses"):
+		word = word[:len(word)-2]
+	case strings.HasSuffix(word, "ies"):
+		word = word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "ss"), strings.HasSuffix(word, "us"):
+	case strings.HasSuffix(word, "s"):
+		word = word[:len(word)-1]
+	}
+	for _, suffix := range []string{"ingly", "edly", "ing", "ed", "ly"} {
+		stem := strings.TrimSuffix(word, suffix)
+		if stem == word || len(stem) < 3 || !hasVowel(stem) {
+			continue
+		}
+		// hopping => hop, but not falling => fal
+		if n := len(stem); stem[n-1] == stem[n-2] && !strings.ContainsRune("lsz", rune(stem[n-1])) {
+			stem = stem[:n-1]
+		}
+		return stem
+	}
+	return word
+}
+
+func hasVowel(s string) bool {
+	return strings.ContainsAny(s, "aeiouy")
+}
@@ -0,0 +1,70 @@
+package search
+
+import (
+	"testing"
+
+	"testutil"
+)
+
+func TestStem(t *testing.T) {
+	expect := testutil.Expect(t)
+	for word, stem := range map[string]string{
+		"shirts":   "shirt",
+		"stories":  "story",
+		"classes":  "class",
+		"running":  "run",
+		"falling":  "fall",
+		"jumped":   "jump",
+		"quickly":  "quick",
+		"bus":      "bus",
+		"red":      "red",
+		"thinking": "think",
+	} {
+		expect(Stem(word)).ToEqual(stem)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	expect := testutil.Expect(t)
+	toks := Tokenize("Red shirts, 100% cotton!")
+	terms := []string{}
+	for _, tok := range toks {
+		terms = append(terms, tok.Term)
+	}
+	expect(terms).ToEqual([]string{"red", "shirt", "100", "cotton"})
+	expect(toks[1].Start).ToEqual(4)
+	expect(toks[1].End).ToEqual(10)
+}
+
+func TestSearch(t *testing.T) {
+	expect := testutil.Expect(t)
+	ix := NewIndex()
+	ix.Put("a", []Field{{Name: "title", Text: "Red Shirt"}, {Name: "body", Text: "A shirt for running in the rain"}})
+	ix.Put("b", []Field{{Name: "title", Text: "Blue Shirt"}})
+	ix.Put("c", []Field{{Name: "title", Text: "Running shoes"}, {Name: "body", Text: "Shoes for <runners> & walkers who run every single day of the week"}})
+	expect(ix.Len()).ToEqual(3)
+	rs := ix.Search("shirts", 0, nil)
+	expect(len(rs)).ToEqual(2)
+	// shorter documents rank higher for the same term
+	expect(rs[0].ID).ToEqual("b")
+	expect(rs[0].Snippet).ToEqual("Blue <em>Shirt</em>")
+	rs = ix.Search("run", 1, nil)
+	expect(len(rs)).ToEqual(1)
+	expect(rs[0].ID).ToEqual("c")
+	expect(rs[0].Field).ToEqual("title")
+	expect(rs[0].Snippet).ToEqual("<em>Running</em> shoes")
+	rs = ix.Search("walkers", 0, nil)
+	expect(rs[0].Field).ToEqual("body")
+	expect(rs[0].Snippet).ToEqual("Shoes for &lt;runners&gt; &amp; <em>walkers</em> who run every single day of the week")
+	rs = ix.Search("weeks", 0, nil)
+	expect(rs[0].Snippet).ToEqual("…walkers who run every single day of the <em>week</em>")
+	rs = ix.Search("red running", 0, func(id string) bool { return id != "c" })
+	expect(len(rs)).ToEqual(1)
+	expect(rs[0].ID).ToEqual("a")
+	// replacing and removing documents updates the postings
+	ix.Put("b", []Field{{Name: "title", Text: "Blue Hat"}})
+	expect(len(ix.Search("shirt", 0, nil))).ToEqual(1)
+	ix.Remove("a")
+	expect(len(ix.Search("shirt", 0, nil))).ToEqual(0)
+	expect(ix.Len()).ToEqual(2)
+}